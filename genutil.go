@@ -21,6 +21,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -31,18 +32,30 @@ type bslice []byte
 
 // GzFile is used to write to regular or gz file, removing existing compression variant first
 type GzFile struct {
-	fo   *os.File
-	ww   *bufio.Writer
-	wwgz *gzip.Writer
+	fname    string
+	fo       *os.File
+	ww       *bufio.Writer
+	wwgz     *gzip.Writer
+	wwenc    io.WriteCloser // set by OpenGzFileEncrypted, between wwgz and ww
+	keepopen bool           // set for stdout, which is flushed but not closed
+	tees     []GzFile       // set by OpenGzFileTee, copies written alongside
+	wwsum    *Sha256Writer  // set by OpenGzFileSha256, between ww and fo
 }
 
 func (us GzFile) Write(pp []byte) (nn int, err error) {
 	switch {
 	case us.wwgz != nil:
 		nn, err = us.wwgz.Write(pp)
+	case us.wwenc != nil:
+		nn, err = us.wwenc.Write(pp)
 	case us.ww != nil:
 		nn, err = us.ww.Write(pp)
 	}
+	for _, tee := range us.tees {
+		if _, terr := tee.Write(pp); err == nil {
+			err = terr
+		}
+	}
 	return
 }
 
@@ -51,9 +64,16 @@ func (us GzFile) WriteString(ss string) (nn int, err error) {
 	switch {
 	case us.wwgz != nil:
 		nn, err = us.wwgz.Write([]byte(ss))
+	case us.wwenc != nil:
+		nn, err = io.WriteString(us.wwenc, ss)
 	case us.ww != nil:
 		nn, err = us.ww.WriteString(ss)
 	}
+	for _, tee := range us.tees {
+		if _, terr := tee.WriteString(ss); err == nil {
+			err = terr
+		}
+	}
 	return
 }
 
@@ -64,26 +84,49 @@ func (us GzFile) Close() {
 		us.wwgz.Flush()
 		us.wwgz.Close()
 	}
+	if us.wwenc != nil {
+		us.wwenc.Close()
+	}
 	if us.ww != nil {
 		us.ww.Flush()
-		us.fo.Close()
+		if !us.keepopen {
+			if us.wwsum != nil {
+				if err := us.wwsum.Close(); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+				}
+			} else {
+				us.fo.Close()
+			}
+			auditFile("create", us.fname, -1)
+		}
+	}
+	for _, tee := range us.tees {
+		tee.Close()
 	}
 }
 
 // OpenGzFile Opens a file for buffered writing, optionally using gzip compression
+// The name "-" writes to stdout, and an existing named pipe is written to rather than removed
 func OpenGzFile(_fname string) GzFile {
 	self := new(GzFile)
 	var err error
 
 	switch {
+	case _fname == "-":
+		self.fo, self.keepopen = os.Stdout, true
+		self.ww = bufio.NewWriter(self.fo)
+		return (*self)
 	case strings.HasPrefix(_fname, "/dev/"):
+	case PathIsFifo(_fname):
 	default:
+		checkFreeSpaceOrPanic(_fname) // before the old file and its variants are removed
 		ofname, ofcode := WritableFilename(_fname)
 		if false {
 			fmt.Println("Removed existing file: %s, ofcode=%d\n", ofname, ofcode)
 		}
 	}
 
+	self.fname = _fname
 	self.fo, err = os.Create(_fname)
 	if err != nil {
 		panic(err)
@@ -114,6 +157,9 @@ var (
 	}
 )
 
+// sepmapMu guards sepmap, which RegisterSeparator extends
+var sepmapMu sync.RWMutex
+
 // ================================================================================
 
 // Hostname retrieves hostname
@@ -717,7 +763,7 @@ func StrIsInt(_str string) bool {
 
 // StrAbs returns the abs value of a string, as string
 func StrAbs(_num string) string {
-	return fmt.Sprintf("%f", math.Abs(StrToFloat(_num)))
+	return sprintfF(math.Abs(StrToFloat(_num)))
 }
 
 // ToBool converts string to bool, with default
@@ -811,13 +857,15 @@ func TwoStrToFloat(_bsl1, _bsl2 string) (float64, float64) {
 	return f1, f2
 }
 
-// ToFloat converts string to float, without default
+// ToFloat converts string to float, without default. Plain decimals are parsed without allocating.
 func ToFloat(_bsl []byte) float64 {
 	if len(_bsl) <= 0 {
 		return 0.0
 	}
-	f, _ := strconv.ParseFloat(string(_bsl), 64)
-	return f
+	if f, ok := parseFloatFast(_bsl); ok {
+		return f
+	}
+	return bytesToFloatSlow(_bsl)
 }
 
 // StrMultFloat returns the result as a float, of multiplying a string and a float
@@ -826,7 +874,7 @@ func StrMultFloat(_bsl1 string, _num float64) string {
 	if len(_bsl1) > 0 {
 		f1, _ = strconv.ParseFloat(_bsl1, 64)
 	}
-	return fmt.Sprintf("%f", f1*_num)
+	return sprintfF(f1 * _num)
 }
 
 // StrAddFloat is shorthand
@@ -835,7 +883,7 @@ func StrAddFloat(_bsl1 string, _num float64) string {
 	if len(_bsl1) > 0 {
 		f1, _ = strconv.ParseFloat(_bsl1, 64)
 	}
-	return fmt.Sprintf("%f", f1+_num)
+	return sprintfF(f1 + _num)
 }
 
 // StrAddInt is shorthand
@@ -862,7 +910,7 @@ func StrDivFloat(_bsl1 string, _num float64) string {
 	if len(_bsl1) > 0 {
 		f1, _ = strconv.ParseFloat(_bsl1, 64)
 	}
-	return fmt.Sprintf("%f", f1/_num)
+	return sprintfF(f1 / _num)
 }
 
 // StrAbsDivFloat is shorthand
@@ -871,16 +919,16 @@ func StrAbsDivFloat(_bsl1 string, _num float64) string {
 	if len(_bsl1) > 0 {
 		f1, _ = strconv.ParseFloat(_bsl1, 64)
 	}
-	return fmt.Sprintf("%f", math.Abs(f1/_num))
+	return sprintfF(math.Abs(f1 / _num))
 }
 
-// StrInvert is shorthand
+// StrInvert is shorthand, a zero or empty input gives "+Inf", see StrInvertRate for a safe variant
 func StrInvert(_bsl1 string) string {
 	f1 := 0.0
 	if len(_bsl1) > 0 {
 		f1, _ = strconv.ParseFloat(_bsl1, 64)
 	}
-	return fmt.Sprintf("%f", math.Abs(1.0/f1))
+	return sprintfF(math.Abs(1.0 / f1))
 }
 
 // StrNegate is shorthand
@@ -889,7 +937,7 @@ func StrNegate(_bsl1 string) string {
 	if len(_bsl1) > 0 {
 		f1, _ = strconv.ParseFloat(_bsl1, 64)
 	}
-	return fmt.Sprintf("%f", -f1)
+	return sprintfF(-f1)
 }
 
 // StrSignAsFloat is shorthand
@@ -904,7 +952,7 @@ func StrSignAsFloat(_bsl1 string, _snum float64) string {
 	case false:
 		f1 = math.Abs(f1)
 	}
-	return fmt.Sprintf("%f", f1)
+	return sprintfF(f1)
 }
 
 // StrIntsAdd is shorthand
@@ -930,7 +978,7 @@ func StrFloatsAdd(_bsl1, _bsl2 string) string {
 	}
 	f1, _ := strconv.ParseFloat(_bsl1, 64)
 	f2, _ := strconv.ParseFloat(_bsl2, 64)
-	return fmt.Sprintf("%f", f1+f2)
+	return sprintfF(f1 + f2)
 }
 
 // StrFloatsDiff is shorthand
@@ -943,7 +991,7 @@ func StrFloatsDiff(_bsl1, _bsl2 string) string {
 	}
 	f1, _ := strconv.ParseFloat(_bsl1, 64)
 	f2, _ := strconv.ParseFloat(_bsl2, 64)
-	return fmt.Sprintf("%f", f1-f2)
+	return sprintfF(f1 - f2)
 }
 
 // StrFloatsAbsDiff is shorthand
@@ -956,7 +1004,7 @@ func StrFloatsAbsDiff(_bsl1, _bsl2 string) string {
 	}
 	f1, _ := strconv.ParseFloat(_bsl1, 64)
 	f2, _ := strconv.ParseFloat(_bsl2, 64)
-	return fmt.Sprintf("%f", math.Abs(f1-f2))
+	return sprintfF(math.Abs(f1 - f2))
 }
 
 // StrFloatsMult is shorthand
@@ -969,7 +1017,7 @@ func StrFloatsMult(_bsl1, _bsl2 string) string {
 	}
 	f1, _ := strconv.ParseFloat(_bsl1, 64)
 	f2, _ := strconv.ParseFloat(_bsl2, 64)
-	return fmt.Sprintf("%f", f1*f2)
+	return sprintfF(f1 * f2)
 }
 
 // StrFloatsMult3Zero returns 0 if any items are missing
@@ -986,7 +1034,7 @@ func StrFloatsMult3Zero(_bsl1, _bsl2, _bsl3 string) string {
 	f1, _ := strconv.ParseFloat(_bsl1, 64)
 	f2, _ := strconv.ParseFloat(_bsl2, 64)
 	f3, _ := strconv.ParseFloat(_bsl3, 64)
-	return fmt.Sprintf("%f", f1*f2*f3)
+	return sprintfF(f1 * f2 * f3)
 }
 
 // StrFloatsDiv is shorthand
@@ -1002,7 +1050,7 @@ func StrFloatsDiv(_bsl1, _bsl2, _def string) string {
 		return _def
 	}
 	f1, _ := strconv.ParseFloat(_bsl1, 64)
-	return fmt.Sprintf("%f", f1/f2)
+	return sprintfF(f1 / f2)
 }
 
 // StrFloatsAplusBminusC is shorthand
@@ -1017,7 +1065,7 @@ func StrFloatsAplusBminusC(_bsl1, _bsl2, _bsl3 string) string {
 	if len(_bsl3) > 0 {
 		c, _ = strconv.ParseFloat(_bsl3, 64)
 	}
-	return fmt.Sprintf("%f", a+b-c)
+	return sprintfF(a + b - c)
 }
 
 // StrFloatsAplusminusBminusC is shorthand
@@ -1032,7 +1080,7 @@ func StrFloatsAplusminusBminusC(_bsl1 string, _plusminus int64, _bsl2, _bsl3 str
 	if len(_bsl3) > 0 {
 		c, _ = strconv.ParseFloat(_bsl3, 64)
 	}
-	return fmt.Sprintf("%f", a+float64(_plusminus)*(b-c))
+	return sprintfF(a + float64(_plusminus)*(b-c))
 }
 
 // SliceFloatsAdd adds slice elements of the slice
@@ -1075,7 +1123,7 @@ func IsYYYYMMDD(_str string) bool {
 }
 
 // StryyyymmddLTEQ returns true if firstdate <= seconddate
-// use AddCalDate if you want to compare offsetted dates
+// use AddCalDate if you want to compare offsetted dates, and CompareYYYYMMDD in bulk, as bad dates are printed here
 func StryyyymmddLTEQ(_dt1, _dt2 string) bool {
 	len1, len2 := len(_dt1), len(_dt2)
 	switch {
@@ -1084,15 +1132,7 @@ func StryyyymmddLTEQ(_dt1, _dt2 string) bool {
 	case len1 == 8 && len2 < 8:
 		return false
 	case len1 == 8 && len2 == 8:
-		for ii := 0; ii < 8; ii++ {
-			switch {
-			case _dt1[ii] > _dt2[ii]:
-				return false
-			case _dt1[ii] < _dt2[ii]:
-				return true
-			}
-		}
-		return true
+		return _dt1 <= _dt2
 	}
 	pc, file, line, ok := runtime.Caller(1)
 	fmt.Println("genutil.StryyyymmddLTEQ : bad dates dt1=", _dt1, " dt2=", _dt2, " callerFile=", file, " callerLine=", line, " pc=", pc, " ok=", ok)
@@ -1109,18 +1149,7 @@ func StryyyymmddLT(_dt1, _dt2 string) bool {
 	case len1 == 8 && len2 < 8:
 		return false
 	case len1 == 8 && len2 == 8:
-		if _dt1 == _dt2 {
-			return false
-		}
-		for ii := 0; ii < 8; ii++ {
-			switch {
-			case _dt1[ii] > _dt2[ii]:
-				return false
-			case _dt1[ii] < _dt2[ii]:
-				return true
-			}
-		}
-		return true
+		return _dt1 < _dt2
 	}
 	pc, file, line, ok := runtime.Caller(1)
 	fmt.Println("genutil.StryyyymmddLT : bad dates dt1=", _dt1, " dt2=", _dt2, " callerFile=", file, " callerLine=", line, " pc=", pc, " ok=", ok)
@@ -1207,20 +1236,14 @@ func YYYY_MM_DD_HH_MM_SS2yyyymmdd_hhmmss(_bsl []byte) (int64, int64) {
 }
 
 // YYYY_MM_DD_HH_MM_SS_mmm_zz2yyyymmdd_hhmmss_mmm_zz converts "2020-01-09 16:45:07.mmm-zz" format dates to (YYYYMMDD, HHMMSS, mmm, zz) string pair
-// Here zz is timezone from pgsql in hours from GMT
+// Here zz is timezone from pgsql in hours from GMT. Any form ParsePgTimestamp accepts will do; unparseable input gives (19010101, -1, -1, -1)
 func YYYY_MM_DD_HH_MM_SS_mmm_zz2yyyymmdd_hhmmss_mmm_zz(_bsl []byte) (int64, int64, int64, int64) {
-	if len(_bsl) < 26 {
+	tt, err := ParsePgTimestamp(string(_bsl))
+	if err != nil {
 		return 19010101, -1, -1, -1
 	}
-	yyyy := ToInt(string(_bsl[0:4]), 1901)
-	mm := ToInt(string(_bsl[5:7]), 0)
-	dd := ToInt(string(_bsl[8:10]), 0)
-	hh := ToInt(string(_bsl[11:13]), 1901)
-	MM := ToInt(string(_bsl[14:16]), 0)
-	ss := ToInt(string(_bsl[17:19]), 0)
-	mmm := ToInt(string(_bsl[21:23]), 0)
-	zz := ToInt(string(_bsl[23:25]), 0) // could be signed
-	return yyyy*10000 + mm*100 + dd, hh*10000 + MM*100 + ss, mmm, zz
+	_, offset := tt.Zone()
+	return int64(Time2YYYYMMDD(tt)), int64(tt.Hour()*10000 + tt.Minute()*100 + tt.Second()), int64(tt.Nanosecond() / 1e6), int64(offset / 3600)
 }
 
 // Hhmmss2Seconds converts to (possibly fractional) seconds
@@ -1568,16 +1591,68 @@ func Date2YYYYMMDD(_today, _dt string) string {
 	return ""
 }
 
+// FilenameExpandUser expands a leading ~ or ~user, returning the name unchanged if the user is unknown.
+// $VARs are left alone, see ExpandPath.
 func FilenameExpandUser(_fname string) string {
-	switch {
-	case len(_fname) < 2:
-		return _fname
-	case _fname[:2] != "~/":
-		return _fname
+	if expanded, err := expandTilde(_fname); err == nil {
+		return expanded
+	}
+	return _fname
+}
+
+// ExpandPath expands a leading ~ or ~user and then $VAR or ${VAR} from the environment,
+// returning an error for an unknown user or an unset variable
+func ExpandPath(_fname string) (string, error) {
+	expanded, err := expandTilde(_fname)
+	if err != nil {
+		return "", err
 	}
-	usr, _ := user.Current()
-	dir := usr.HomeDir
-	return strings.Replace(_fname, "~/", dir+"/", 1)
+	unset := ""
+	expanded = os.Expand(expanded, func(_name string) string {
+		val, ok := os.LookupEnv(_name)
+		if !ok && unset == "" {
+			unset = _name
+		}
+		return val
+	})
+	if unset != "" {
+		return "", fmt.Errorf("genutil.ExpandPath: variable(%s) is not set in path(%s)", unset, _fname)
+	}
+	return expanded, nil
+}
+
+var (
+	homeDirCache   = map[string]string{}
+	homeDirCacheMu sync.Mutex
+)
+
+// expandTilde replaces ~ or ~user at the start of the name by the home dir, caching the lookups
+func expandTilde(_fname string) (string, error) {
+	if !strings.HasPrefix(_fname, "~") {
+		return _fname, nil
+	}
+	name, rest := _fname[1:], ""
+	if idx := strings.Index(name, "/"); idx >= 0 {
+		name, rest = name[:idx], name[idx:]
+	}
+	homeDirCacheMu.Lock()
+	defer homeDirCacheMu.Unlock()
+	dir, ok := homeDirCache[name]
+	if !ok {
+		var usr *user.User
+		var err error
+		if name == "" {
+			usr, err = user.Current()
+		} else {
+			usr, err = user.Lookup(name)
+		}
+		if err != nil {
+			return "", fmt.Errorf("genutil.ExpandPath: unknown user(%s) in path(%s)", name, _fname)
+		}
+		dir = usr.HomeDir
+		homeDirCache[name] = dir
+	}
+	return dir + rest, nil
 }
 
 // PathExists returns whether the given file or directory exists or not
@@ -1604,6 +1679,15 @@ func PathIsDir(_path string) bool {
 	return false
 }
 
+// PathIsFifo checks if path is a named pipe
+func PathIsFifo(_path string) bool {
+	stat, err := os.Stat(_path)
+	if err != nil {
+		return false
+	}
+	return (stat.Mode() & os.ModeNamedPipe) != 0
+}
+
 // PathOK is shorthand
 func PathOK(_path string) bool {
 	_, err := os.Stat(_path)
@@ -1720,11 +1804,22 @@ func AllDirs(_dir string) (paths, dirs []string) {
 
 // ReadableFilename returns information for subsequent reading of the specified file
 // If not found, it looks for compression variants of the file
+// The name "-" denotes stdin (ofcode 12)
 func ReadableFilename(_fname string) (ofname string, ofcmd *exec.Cmd, ofcode int) {
 	ofname = "/dev/null"
 	// ofcmd = nil
 	ofcode = 0
 
+	// ================================================================================
+	// "-" is stdin, read as is
+	// ================================================================================
+	if _fname == "-" {
+		ofname = "/dev/stdin"
+		ofcmd = exec.Command("/bin/cat")
+		ofcode = 12
+		return
+	}
+
 	// ================================================================================
 	// First extract the file exactly as the user specified it
 	// ================================================================================
@@ -1817,18 +1912,21 @@ func ReadableFilename(_fname string) (ofname string, ofcmd *exec.Cmd, ofcode int
 
 // PathRemoveOrPanic panics if it fails to remove a directory
 func PathRemoveOrPanic(_fname string) bool {
-	err := os.Remove(_fname)
-	if err != nil {
+	if err := auditedRemove(_fname); err != nil {
 		panic(err)
 	}
 	return true
 }
 
 // WritableFilename returns information for subsequent writing of the specified file
-// Any compression variants of the file are removed.
+// Any compression variants of the file are removed (or backed up, see SetRemoveMode).
+// Nothing is removed for "-" (stdout) or if the file is a named pipe.
 func WritableFilename(_fname string) (ofname string, ofcode int) {
 	ofname = "/dev/null"
 	ofcode = 0
+	if _fname == "-" || PathIsFifo(_fname) {
+		return
+	}
 
 	// ================================================================================
 	// First remove any file exactly as the user specified it
@@ -1836,19 +1934,19 @@ func WritableFilename(_fname string) (ofname string, ofcode int) {
 	fok := PathOK(_fname)
 	switch {
 	case strings.HasSuffix(_fname, ".xz") && fok:
-		ofname, _, ofcode = _fname, PathRemoveOrPanic(_fname), 1
+		ofname, _, ofcode = _fname, PathDiscardOrPanic(_fname), 1
 		return
 	case strings.HasSuffix(_fname, ".gz") && fok:
-		ofname, _, ofcode = _fname, PathRemoveOrPanic(_fname), 2
+		ofname, _, ofcode = _fname, PathDiscardOrPanic(_fname), 2
 		return
 	case strings.HasSuffix(_fname, ".bz2") && fok:
-		ofname, _, ofcode = _fname, PathRemoveOrPanic(_fname), 3
+		ofname, _, ofcode = _fname, PathDiscardOrPanic(_fname), 3
 		return
 	case strings.HasSuffix(_fname, ".zip") && fok:
-		ofname, _, ofcode = _fname, PathRemoveOrPanic(_fname), 4
+		ofname, _, ofcode = _fname, PathDiscardOrPanic(_fname), 4
 		return
 	case fok:
-		ofname, _, ofcode = _fname, PathRemoveOrPanic(_fname), 6
+		ofname, _, ofcode = _fname, PathDiscardOrPanic(_fname), 6
 		return
 	}
 
@@ -1871,19 +1969,19 @@ func WritableFilename(_fname string) (ofname string, ofcode int) {
 
 	switch {
 	case PathOK(tmpf + ".xz"):
-		ofname, _, ofcode = tmpf+".xz", PathRemoveOrPanic(tmpf+".xz"), 7
+		ofname, _, ofcode = tmpf+".xz", PathDiscardOrPanic(tmpf+".xz"), 7
 		return
 	case PathOK(tmpf + ".gz"):
-		ofname, _, ofcode = tmpf+".gz", PathRemoveOrPanic(tmpf+".gz"), 8
+		ofname, _, ofcode = tmpf+".gz", PathDiscardOrPanic(tmpf+".gz"), 8
 		return
 	case PathOK(tmpf + ".bz2"):
-		ofname, _, ofcode = tmpf+".bz2", PathRemoveOrPanic(tmpf+".bz2"), 9
+		ofname, _, ofcode = tmpf+".bz2", PathDiscardOrPanic(tmpf+".bz2"), 9
 		return
 	case PathOK(tmpf + ".zip"):
-		ofname, _, ofcode = tmpf+".zip", PathRemoveOrPanic(tmpf+".zip"), 10
+		ofname, _, ofcode = tmpf+".zip", PathDiscardOrPanic(tmpf+".zip"), 10
 		return
 	case PathOK(tmpf):
-		ofname, _, ofcode = tmpf, PathRemoveOrPanic(tmpf), 11
+		ofname, _, ofcode = tmpf, PathDiscardOrPanic(tmpf), 11
 		return
 	}
 	return
@@ -1927,6 +2025,7 @@ func CompressionBasename(_fname string) string {
 }
 
 // RemoveCompressionVariants removes all compression variants of the specified filename, optionally preserving the base filename
+// Files are backed up rather than removed if so configured with SetRemoveMode
 func RemoveCompressionVariants(_fname string, _keepbase bool) {
 	fbase := CompressionBasename(_fname)
 	for _, ext := range []string{"", ".xz", ".gz", ".bz2", ".zip", ".ZIP"} {
@@ -1935,10 +2034,10 @@ func RemoveCompressionVariants(_fname string, _keepbase bool) {
 		}
 		ff := fbase + ext
 		fok := PathOK(ff)
-		if !fok {
+		if !fok || PathIsFifo(ff) {
 			continue
 		}
-		PathRemoveOrPanic(ff)
+		PathDiscardOrPanic(ff)
 	}
 }
 
@@ -2008,6 +2107,8 @@ func OpenAny(_fname string) *bufio.Reader {
 		// defer fi.Close()
 		r := bufio.NewReaderSize(fi, 20*4096)
 		return r
+	case 12:
+		return bufio.NewReaderSize(os.Stdin, 20*4096)
 	default:
 	}
 	return nil
@@ -2052,6 +2153,9 @@ func OpenAnyIO(_fname string) *io.Reader {
 		// defer fi.Close()
 		r := io.Reader(fi)
 		return &r
+	case 12:
+		r := io.Reader(os.Stdin)
+		return &r
 	default:
 	}
 	return nil
@@ -2059,7 +2163,42 @@ func OpenAnyIO(_fname string) *io.Reader {
 
 // OpenAnyErr returns buffered reader for the content of the specified file, or available compression variant
 // It is more error conscious than OpenAny()
+// Nothing is closed when the caller is done, use OpenAnyReadCloser to release the file or decompressor.
 func OpenAnyErr(_fname string) (*bufio.Reader, error) {
+	rc, err := OpenAnyReadCloser(_fname)
+	if err != nil {
+		return nil, err
+	}
+	return rc.Reader, nil
+}
+
+// AnyReadCloser is the buffered content given by OpenAnyReadCloser, with a Close releasing what is behind it
+type AnyReadCloser struct {
+	*bufio.Reader
+	file io.Closer // the file or pipe read, nil for stdin
+	cmd  *exec.Cmd // the decompressor or script writing the pipe, if any
+}
+
+// Close closes the file or pipe, and kills and waits for the command so it is neither left blocked on a full pipe
+// nor a zombie. It is safe to call more than once.
+func (us *AnyReadCloser) Close() error {
+	var err error
+	if us.cmd != nil {
+		if us.cmd.Process != nil {
+			us.cmd.Process.Kill()
+		}
+		us.cmd.Wait() // closes the pipe too; a killed or failed command is expected here
+		us.cmd, us.file = nil, nil
+	}
+	if us.file != nil {
+		err = us.file.Close()
+		us.file = nil
+	}
+	return err
+}
+
+// OpenAnyReadCloser is OpenAnyErr returning a reader the caller must Close, usually with a defer
+func OpenAnyReadCloser(_fname string) (*AnyReadCloser, error) {
 	ofname, ofcmd, ofcode := ReadableFilename(_fname)
 	if ofcmd == nil {
 		return nil, errors.New("os.exec.Command returned nil pointer")
@@ -2074,61 +2213,79 @@ func OpenAnyErr(_fname string) (*bufio.Reader, error) {
 		if err != nil {
 			return nil, err
 		}
-		// defer fi.Close()
-		r := bufio.NewReaderSize(fi, 20*4096)
-		return r, nil
+		return &AnyReadCloser{Reader: bufio.NewReaderSize(fi, 20*4096), file: fi, cmd: ofcmd}, nil
 	case 2, 8:
 		fi, err := os.Open(ofname)
 		if err != nil {
 			return nil, err
 		}
-		// defer fi.Close()
 		gzr, err := gzip.NewReader(fi)
 		if err != nil {
 			fi.Close()
 			return nil, err
 		}
-		r := bufio.NewReaderSize(gzr, 20*4096)
-		return r, nil
+		return &AnyReadCloser{Reader: bufio.NewReaderSize(gzr, 20*4096), file: fi}, nil
 	case 3, 9:
 		fi, err := os.Open(ofname)
 		if err != nil {
 			return nil, err
 		}
-		// defer fi.Close()
 		bzr := bzip2.NewReader(fi)
-		r := bufio.NewReaderSize(bzr, 20*4096)
-		return r, nil
+		return &AnyReadCloser{Reader: bufio.NewReaderSize(bzr, 20*4096), file: fi}, nil
 	case 6, 11:
 		fi, err := os.Open(ofname)
 		if err != nil {
 			return nil, err
 		}
-		// defer fi.Close()
-		r := bufio.NewReaderSize(fi, 20*4096)
-		return r, nil
+		return &AnyReadCloser{Reader: bufio.NewReaderSize(fi, 20*4096), file: fi}, nil
+	case 12:
+		return &AnyReadCloser{Reader: bufio.NewReaderSize(os.Stdin, 20*4096)}, nil
 	default:
 	}
 	return nil, fmt.Errorf("OpenAnyErr : unknown ofcode = %d", ofcode)
 }
 
-// WriteStringToFile is shorthand
-func WriteStringToFile(_str, _fname string) {
+// readableRawSize returns the size of the file OpenAnyReadCloser would read, and whether it is read as is,
+// so the size is that of the content
+func readableRawSize(_fname string) (int64, bool) {
+	ofname, _, ofcode := ReadableFilename(_fname)
+	stat, err := os.Stat(ofname)
+	if err != nil || !stat.Mode().IsRegular() {
+		return -1, false
+	}
+	return stat.Size(), ofcode == 6 || ofcode == 11
+}
+
+// createOrStdout returns stdout for "-", else the created file, and whether the caller should close it
+func createOrStdout(_fname string) (*os.File, bool) {
+	if _fname == "-" {
+		return os.Stdout, false
+	}
+	checkFreeSpaceOrPanic(_fname)
 	fo, err := os.Create(_fname)
 	if err != nil {
 		panic(err)
 	}
-	defer fo.Close()
+	return fo, true
+}
+
+// WriteStringToFile is shorthand, "-" writes to stdout
+func WriteStringToFile(_str, _fname string) {
+	fo, doclose := createOrStdout(_fname)
+	if doclose {
+		defer auditFile("create", _fname, int64(len(_str)))
+		defer fo.Close()
+	}
 	io.WriteString(fo, _str)
 }
 
-// WriteStringToGzipFile is shorthand
+// WriteStringToGzipFile is shorthand, "-" writes to stdout
 func WriteStringToGzipFile(_str, _fname string) {
-	fo, err := os.Create(_fname)
-	if err != nil {
-		panic(err)
+	fo, doclose := createOrStdout(_fname)
+	if doclose {
+		defer auditFile("create", _fname, -1)
+		defer fo.Close()
 	}
-	defer fo.Close()
 	ww0 := bufio.NewWriter(fo)
 	defer ww0.Flush()
 	ww := gzip.NewWriter(ww0)
@@ -2191,6 +2348,65 @@ func NewInt64BoolMap() map[int64]bool {
 	return aset
 }
 
+// NewInt64BoolMapFromCsv returns a map where each integer element of the supplied string is set true
+func NewInt64BoolMapFromCsv(_csv, _sep string) map[int64]bool {
+	aset := make(map[int64]bool)
+	UpdateInt64BoolMapFromCsv(&aset, _csv, _sep)
+	return aset
+}
+
+// UpdateInt64BoolMapFromCsv updates the map setting integer elements of the string to true, non-integers are skipped
+func UpdateInt64BoolMapFromCsv(_aset *map[int64]bool, _csv, _sep string) {
+	parts := strings.Split(_csv, _sep)
+	for _, part := range parts {
+		num, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err == nil {
+			(*_aset)[num] = true
+		}
+	}
+}
+
+// UpdateInt64BoolMap updates the map, setting elements of the slice to true
+func UpdateInt64BoolMap(_aset *map[int64]bool, _keys []int64) {
+	for _, key := range _keys {
+		(*_aset)[key] = true
+	}
+}
+
+// KeysInt64BoolMap is shorthand
+func KeysInt64BoolMap(_aset *map[int64]bool) []int64 {
+	keys := []int64{}
+	for kk := range *_aset {
+		keys = append(keys, kk)
+	}
+	return keys
+}
+
+// NewSet returns a set of any comparable type, filled with the supplied keys
+func NewSet[K comparable](_keys ...K) map[K]bool {
+	aset := make(map[K]bool, len(_keys))
+	for _, key := range _keys {
+		aset[key] = true
+	}
+	return aset
+}
+
+// UpdateSet updates the set, setting elements of the slice to true
+func UpdateSet[K comparable](_aset map[K]bool, _keys []K) {
+	for _, key := range _keys {
+		_aset[key] = true
+	}
+}
+
+// KeysOf returns the keys of any map, in no particular order
+func KeysOf[K comparable, V any](_mp map[K]V) []K {
+	keys := make([]K, 0, len(_mp))
+	for kk := range _mp {
+		keys = append(keys, kk)
+	}
+	return keys
+}
+
 // FileList returns files in dir
 func FileList(_dname string) []string {
 	flist, _ := ioutil.ReadDir(_dname)
@@ -2306,18 +2522,16 @@ func FileInfoSysStr(_sys interface{}, _sep string) string {
 	return str
 }
 
-// FileInfo formats file info into readable form
+// FileInfo formats file info into readable form, see FileStat for the typed fields
 func FileInfo(_fname, _sep string, _fullinfo bool) string {
-	stat, err := os.Stat(_fname)
+	fs, err := FileStat(_fname)
 	if os.IsNotExist(err) {
 		return fmt.Sprintf("fname=%s%sstatus=notexists", _fname, _sep)
 	}
-	str := fmt.Sprintf("fname=%s%ssize=%d%smode=%s%smodtime=%s",
-		_fname, _sep, stat.Size(), _sep, stat.Mode().String(), _sep, stat.ModTime().Format("Mon 20060102 15:04:05 MST"))
-	if _fullinfo {
-		str += fmt.Sprintf("%sname=%s%sisdir=%t%s%s", _sep, stat.Name(), _sep, stat.IsDir(), _sep, FileInfoSysStr(stat.Sys(), _sep))
+	if err != nil {
+		return fmt.Sprintf("fname=%s%sstatus=%v", _fname, _sep, err)
 	}
-	return str
+	return fs.Format(_sep, _fullinfo)
 }
 
 // FileSize returns -1 if file not found
@@ -2382,7 +2596,8 @@ func BashExecOrDie(_verbose bool, _cmd, _dir string) string {
 	return string(buf) + "\n" + string(bufe)
 }
 
-// ExecCommandOrDie executes the given command and panics on any kind of failure
+// ExecCommandOrDie executes the given command and panics on any kind of failure.
+// The command is split into words by ShellSplit, so quoted args may contain spaces.
 func ExecCommandOrDie(_verbose bool, _cmd string) {
 	if _verbose {
 		fmt.Println("ExecCommandOrDie:info cmd is: (" + _cmd + ")")
@@ -2390,15 +2605,15 @@ func ExecCommandOrDie(_verbose bool, _cmd string) {
 	if len(_cmd) < 0 {
 		panic("genutil.ExecCommandOrDie: empty command")
 	}
-	parts := strings.Split(_cmd, " ")
-	if len(parts) < 1 {
+	parts, err := ShellSplit(_cmd)
+	if err != nil || len(parts) < 1 {
 		panic("genutil.ExecCommandOrDie: bad command (" + _cmd + ")")
 	}
 	cmd := exec.Command(parts[0], parts[1:]...)
 	if _verbose {
 		fmt.Println("ExecCommandOrDie:info running cmd")
 	}
-	_, err := cmd.StdoutPipe()
+	_, err = cmd.StdoutPipe()
 	cmd.Run()
 	if err != nil {
 		panic("genutil.ExecCommandOrDie: command (" + _cmd + ") failed with error:" + fmt.Sprint("%v", err))
@@ -2682,14 +2897,14 @@ func SearchForFileWithPattern(pat string) (bool, string) {
 
 // GetLatestDatedDir is shorthand
 func GetLatestDatedDir(parentdir string) string {
-	out := BashExecOrDie(false, fmt.Sprintf("ls -1t %s | grep [12][0-9][0-9][0-9] | head -1", parentdir), "/tmp/")
+	out := BashExecOrDie(false, fmt.Sprintf("ls -1t %s | grep [12][0-9][0-9][0-9] | head -1", parentdir), os.TempDir())
 	out = strings.Trim(out, "\r\n\t ")
 	return out
 }
 
 // GetLatestFileWithPattern is shorthand
 func GetLatestFileWithPattern(pattern string) string {
-	out := BashExecOrDie(false, fmt.Sprintf("ls -1t %s | head -1", pattern), "/tmp/")
+	out := BashExecOrDie(false, fmt.Sprintf("ls -1t %s | head -1", pattern), os.TempDir())
 	out = strings.Trim(out, "\r\n\t ")
 	return out
 
@@ -2697,7 +2912,7 @@ func GetLatestFileWithPattern(pattern string) string {
 
 // GetSecondLatestFileWithPattern is shorthand
 func GetSecondLatestFileWithPattern(pattern string) string {
-	out := BashExecOrDie(false, fmt.Sprintf("ls -1t %s | head -2 | tail -1", pattern), "/tmp/")
+	out := BashExecOrDie(false, fmt.Sprintf("ls -1t %s | head -2 | tail -1", pattern), os.TempDir())
 	out = strings.Trim(out, "\r\n\t ")
 	return out
 }
@@ -2791,6 +3006,48 @@ func CallerInfo2() string {
 	return fmt.Sprintf(" callerFile=%s callerLine=%d pc=%d ok=%t", file, line, pc, ok)
 }
 
+// CallerInfoN returns the file, line and function name of the caller at the given depth, counted as in runtime.Caller
+// from CallerInfoN itself, so CallerInfoN(2) describes the same frame as CallerInfo2
+func CallerInfoN(_depth int) (file string, line int, fn string) {
+	pc, file, line, ok := runtime.Caller(_depth)
+	if !ok {
+		return "?", 0, "?"
+	}
+	if ff := runtime.FuncForPC(pc); ff != nil {
+		fn = ff.Name()
+	}
+	return file, line, fn
+}
+
+// ShortStack returns up to maxFrames frames starting at the caller of ShortStack, as "fn(file:line) <- ..." with file basenames
+func ShortStack(_maxFrames int) string {
+	return shortStackFrom(3, _maxFrames)
+}
+
+// PanicWithContext panics with the message followed by the short stack of the caller
+func PanicWithContext(_msg string) {
+	panic(_msg + " : stack=" + shortStackFrom(3, 8))
+}
+
+// shortStackFrom formats the stack, skipping frames as in runtime.Callers
+func shortStackFrom(_skip, _maxFrames int) string {
+	if _maxFrames < 1 {
+		return ""
+	}
+	pcs := make([]uintptr, _maxFrames)
+	nn := runtime.Callers(_skip, pcs)
+	frames := runtime.CallersFrames(pcs[:nn])
+	parts := []string{}
+	for nn > 0 {
+		frame, more := frames.Next()
+		parts = append(parts, fmt.Sprintf("%s(%s:%d)", frame.Function, filepath.Base(frame.File), frame.Line))
+		if !more {
+			break
+		}
+	}
+	return strings.Join(parts, " <- ")
+}
+
 // FlipIfFalseStr helps compensate for golang not having ternary op a
 func FlipIfFalseStr(_flipIfFalse bool, _val1, _val2 string) (string, string) {
 	if _flipIfFalse {
@@ -2928,7 +3185,7 @@ func CalcPriceIfZero(_px, _badpx string, _val, _qty float64) string {
 	if math.Abs(_qty) < 0.0001 {
 		return _badpx
 	}
-	return fmt.Sprintf("%f", _val/_qty)
+	return sprintfF(_val / _qty)
 }
 
 // CleanString replaces comma with semi
@@ -3132,7 +3389,44 @@ func JoinSlice(_strarr []string, _sep string) string {
 	case "tab", "	":
 		return strings.Join(_strarr, "	")
 	}
-	return strings.Join(_strarr, _sep)
+	return strings.Join(_strarr, StrAorB(SepMap(_sep, true), _sep))
+}
+
+// CsvEscape quotes the field per RFC 4180 if it contains the separator, a double quote, CR or LF,
+// doubling any embedded quotes. The separator may be named as in JoinSlice.
+func CsvEscape(_field, _sep string) string {
+	_sep = csvNamedSep(_sep)
+	if !strings.Contains(_field, _sep) && !strings.ContainsAny(_field, "\"\r\n") {
+		return _field
+	}
+	return "\"" + strings.Replace(_field, "\"", "\"\"", -1) + "\""
+}
+
+// CsvJoin is JoinSlice with each field passed through CsvEscape, so the row reads back with encoding/csv
+func CsvJoin(_fields []string, _sep string) string {
+	_sep = csvNamedSep(_sep)
+	parts := make([]string, len(_fields))
+	for ii, field := range _fields {
+		parts[ii] = CsvEscape(field, _sep)
+	}
+	return strings.Join(parts, _sep)
+}
+
+// csvNamedSep maps the separator names understood by JoinSlice
+func csvNamedSep(_sep string) string {
+	switch strings.ToLower(_sep) {
+	case "pipe":
+		return "|"
+	case "plus":
+		return "+"
+	case "comma":
+		return ","
+	case "space":
+		return " "
+	case "tab":
+		return "	"
+	}
+	return StrAorB(SepMap(_sep, true), _sep)
 }
 
 // JoinSliceWithReverse joins slice elements using named separator, and optionally in reverse
@@ -3148,6 +3442,8 @@ func JoinSliceWithReverse(_strarr []string, _sep string, _reverse bool) string {
 		_sep = " "
 	case "tab", "	":
 		_sep = "	"
+	default:
+		_sep = StrAorB(SepMap(_sep, true), _sep)
 	}
 
 	if !_reverse {
@@ -3164,10 +3460,11 @@ func JoinSliceWithReverse(_strarr []string, _sep string, _reverse bool) string {
 	return str
 }
 
-// JoinSliceLimitingColumns joins slice elements using named separator, and breaking into new "rows" when max cols is reached
+// JoinSliceLimitingColumns joins slice elements using named separator, and breaking into new "rows" when max cols is reached.
+// The separators are resolved by ParseSeparator, so multi-char ones like "||" and escapes like `\x01` work too.
 func JoinSliceLimitingColumns(_strarr []string, _sep, _rowsep string, _maxcol int) string {
-	_sep = sepmap[_sep]
-	_rowsep = sepmap[_rowsep]
+	_sep = resolveSep(_sep)
+	_rowsep = resolveSep(_rowsep)
 	inlen := len(_strarr)
 	nrow := int(inlen / _maxcol)
 	ostr := ""
@@ -3197,13 +3494,15 @@ func CopyStrSlice(_strarr []string, _prefix, _suffix string) []string {
 	return newarr
 }
 
-// SepReplace replaces one named separator with another
+// SepReplace replaces one named separator with another, both resolved by ParseSeparator
 func SepReplace(_str, _insep, _outsep string) string {
-	return strings.Replace(_str, sepmap[_insep], sepmap[_outsep], -1)
+	return strings.Replace(_str, resolveSep(_insep), resolveSep(_outsep), -1)
 }
 
-// SepMap obtains the separator
+// SepMap obtains the separator, "" if the name is unknown. Names added by RegisterSeparator are included.
 func SepMap(_sep string, _anycase bool) string {
+	sepmapMu.RLock()
+	defer sepmapMu.RUnlock()
 	switch _anycase {
 	case true:
 		return sepmap[strings.ToLower(_sep)]
@@ -3211,6 +3510,36 @@ func SepMap(_sep string, _anycase bool) string {
 	return sepmap[_sep]
 }
 
+// SepMapStrict is SepMap in any case, with an error for an unknown name rather than ""
+func SepMapStrict(_sep string) (string, error) {
+	if sep := SepMap(_sep, false); sep != "" {
+		return sep, nil
+	}
+	if sep := SepMap(_sep, true); sep != "" {
+		return sep, nil
+	}
+	return "", fmt.Errorf("genutil.SepMapStrict: unknown separator(%s)", _sep)
+}
+
+// RegisterSeparator adds a separator name understood by SepMap and the functions taking named separators,
+// such as RegisterSeparator("ctrlA", "\x01") for Hive files. The name matches in any case where SepMap does.
+// Registering a name again with the same value is harmless, with another value an error.
+func RegisterSeparator(_name, _value string) error {
+	if _name == "" || _value == "" {
+		return fmt.Errorf("genutil.RegisterSeparator: empty name(%s) or value(%q)", _name, _value)
+	}
+	sepmapMu.Lock()
+	defer sepmapMu.Unlock()
+	for _, name := range []string{_name, strings.ToLower(_name)} {
+		if old, ok := sepmap[name]; ok && old != _value {
+			return fmt.Errorf("genutil.RegisterSeparator: name(%s) is already %q", name, old)
+		}
+	}
+	sepmap[_name] = _value
+	sepmap[strings.ToLower(_name)] = _value
+	return nil
+}
+
 // Str2Bool is shorthand
 func Str2Bool(_str string) bool {
 	switch strings.ToLower(_str) {
@@ -3506,9 +3835,35 @@ func EqualAndZeroStrings(_lstr, _rstr string) bool {
 	return true
 }
 
-// EqualFloats tells if floats are equal
+// EqualFloats tells if floats are within 1e-7 of each other
+//
+// Deprecated: the original (_f1 - _f2) < eps was true for any _f1 < _f2, use AlmostEqual with tolerances suited to the magnitudes
 func EqualFloats(_f1, _f2 float64) bool {
-	return (_f1 - _f2) < 0.0000001
+	return AlmostEqual(_f1, _f2, 0.0000001, 0)
+}
+
+// AlmostEqual is true if |a-b| is within _absTol, or within _relTol of the larger magnitude, as in python's math.isclose.
+// Equal infinities are equal, NaN is never equal.
+func AlmostEqual(_aa, _bb, _absTol, _relTol float64) bool {
+	if _aa == _bb {
+		return true
+	}
+	if math.IsInf(_aa, 0) || math.IsInf(_bb, 0) || math.IsNaN(_aa) || math.IsNaN(_bb) {
+		return false
+	}
+	diff := math.Abs(_aa - _bb)
+	return diff <= _absTol || diff <= _relTol*math.Max(math.Abs(_aa), math.Abs(_bb))
+}
+
+// AlmostEqualStr is AlmostEqual for strings, if either does not parse as a number the trimmed strings must be identical
+func AlmostEqualStr(_aa, _bb string, _absTol, _relTol float64) bool {
+	_aa, _bb = strings.TrimSpace(_aa), strings.TrimSpace(_bb)
+	fa, erra := strconv.ParseFloat(_aa, 64)
+	fb, errb := strconv.ParseFloat(_bb, 64)
+	if erra != nil || errb != nil {
+		return _aa == _bb
+	}
+	return AlmostEqual(fa, fb, _absTol, _relTol)
 }
 
 // StrCapped returns truncated string if exceeds cap
@@ -3632,6 +3987,78 @@ func ChompQuotes(_str string, _trimSpace bool) string {
 	return _str
 }
 
+// UnwrapAll repeatedly strips an enclosing (), [], {} pair or pair of single or double quotes and surrounding space,
+// so `( ["a, b"] )` gives `a, b`. Unlike ChompParens the first and last chars must be a matching pair,
+// so `(a) + (b)` is left alone.
+func UnwrapAll(_str string) string {
+	for {
+		_str = strings.TrimSpace(_str)
+		if len(_str) < 2 || bracketEnd(_str) != len(_str)-1 {
+			return _str
+		}
+		_str = _str[1 : len(_str)-1]
+	}
+}
+
+// SplitTopLevel splits at each _sep that is not nested inside (), [], {} or quotes,
+// so `f(a, b), "c, d", e` split on "," gives [`f(a, b)`, ` "c, d"`, ` e`]
+func SplitTopLevel(_str, _sep string) []string {
+	if len(_sep) == 0 {
+		return []string{_str}
+	}
+	parts := []string{}
+	start := 0
+	for ii := 0; ii < len(_str); {
+		if end := bracketEnd(_str[ii:]); end > 0 {
+			ii += end + 1
+			continue
+		}
+		if strings.HasPrefix(_str[ii:], _sep) {
+			parts = append(parts, _str[start:ii])
+			ii += len(_sep)
+			start = ii
+			continue
+		}
+		ii++
+	}
+	return append(parts, _str[start:])
+}
+
+// bracketEnd returns the index of the char closing the bracket or quote at the start of the string, or -1.
+// Brackets nest and may contain quotes, quotes run to the next unescaped matching quote.
+func bracketEnd(_str string) int {
+	if len(_str) == 0 {
+		return -1
+	}
+	closers := map[byte]byte{'(': ')', '[': ']', '{': '}'}
+	switch _str[0] {
+	case '\'', '"':
+		for ii := 1; ii < len(_str); ii++ {
+			switch _str[ii] {
+			case '\\':
+				ii++
+			case _str[0]:
+				return ii
+			}
+		}
+		return -1
+	case '(', '[', '{':
+		for ii := 1; ii < len(_str); ii++ {
+			switch ch := _str[ii]; {
+			case ch == closers[_str[0]]:
+				return ii
+			case ch == '\'' || ch == '"' || closers[ch] != 0:
+				end := bracketEnd(_str[ii:])
+				if end < 0 {
+					return -1
+				}
+				ii += end
+			}
+		}
+	}
+	return -1
+}
+
 // StryyyymmddLTTernary is shorthand
 func StryyyymmddLTTernary(_dt1, _dt2, _trueStr, _falseStr string) string {
 	if StryyyymmddLT(_dt1, _dt2) {
@@ -3662,13 +4089,18 @@ func SetupLogger(_logfilepath, _logcontentprefix string) (lglocal *log.Logger, e
 	return
 }
 
-// GetFileLineCount counts non-comment lines of a file
+// GetFileLineCount counts non-comment lines of a file, the comments a comma separated list of IsCommentLine tags
 func GetFileLineCount(_fname, _comments string) (int64, error) {
-	comments := strings.Split(_comments, ",")
-	bio, err := OpenAnyErr(_fname)
+	return GetFileLineCountSpec(_fname, ParseCommentSpec(strings.Split(_comments, ",")))
+}
+
+// GetFileLineCountSpec counts the lines of a file that are not comments per the spec
+func GetFileLineCountSpec(_fname string, _comments CommentSpec) (int64, error) {
+	bio, err := OpenAnyReadCloser(_fname)
 	if err != nil {
 		return 0, err
 	}
+	defer bio.Close()
 	count := int64(0)
 	var line []byte
 	for {
@@ -3681,14 +4113,15 @@ func GetFileLineCount(_fname, _comments string) (int64, error) {
 		}
 		line = line[0 : len(line)-1]
 		//fmt.Printf("Line:*%s*\n", string(line))
-		if IsCommentLine(line, comments) { /*fmt.Println("comment");*/ continue
+		if _comments.IsComment(line) { /*fmt.Println("comment");*/ continue
 		}
 		count++
 	}
 	return count, nil
 }
 
-// IsCommentLine checks if a line is one of the list of comment types
+// IsCommentLine checks if a line is one of the list of comment types: "Whitespace" (blank) or "WhitespaceHash"
+// ('#' after leading spaces); other tags are ignored, use CommentSpec for other prefixes
 func IsCommentLine(_line []byte, _commenttags []string) bool {
 	for _, commenttag := range _commenttags {
 		switch commenttag {
@@ -3822,17 +4255,30 @@ func ShrinkSep(_str string, _ch byte) string {
 	return _str
 }
 
-// StrReplaceWithMap replaces substrings in the input string based on the map passed
+// StrReplaceWithMap replaces substrings in the input string based on the map passed, one key after the other,
+// longest keys first and then in sorted order so the result does not vary when keys overlap.
+// Use ReplacerSpec for a single pass, an explicit order or regexps.
 func StrReplaceWithMap(_instr string, _mp map[string]string) string {
+	keys := make([]string, 0, len(_mp))
+	for key := range _mp {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(ii, jj int) bool {
+		if len(keys[ii]) != len(keys[jj]) {
+			return len(keys[ii]) > len(keys[jj])
+		}
+		return keys[ii] < keys[jj]
+	})
 	outstr := _instr
-	for key, val := range _mp {
-		outstr = strings.Replace(outstr, key, val, -1)
+	for _, key := range keys {
+		outstr = strings.Replace(outstr, key, _mp[key], -1)
 	}
 	return outstr
 }
 
 // Next 4 functions are for printing colour text.
 // Usage example:  fmt.Println(GreenBold("Success:") + "Limit check passed")
+// They always emit escape codes, use Style or Colorize for output that may not go to a terminal.
 
 // Green sets a color
 func Green(in string) (out string) {
@@ -0,0 +1,69 @@
+package genutil
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// AppendInt appends the decimal integer to the buffer, as strconv.AppendInt in base 10
+func AppendInt(_dst []byte, _val int64) []byte {
+	return strconv.AppendInt(_dst, _val, 10)
+}
+
+// AppendYYYYMMDD appends the date of the time as YYYYMMDD, as tt.Format("20060102") would without the layout parsing
+func AppendYYYYMMDD(_dst []byte, _tt time.Time) []byte {
+	yyyy, mm, dd := _tt.Date()
+	if yyyy < 0 || yyyy > 9999 {
+		return _tt.AppendFormat(_dst, "20060102")
+	}
+	return append(_dst, byte('0'+yyyy/1000), byte('0'+yyyy/100%10), byte('0'+yyyy/10%10), byte('0'+yyyy%10),
+		byte('0'+int(mm)/10), byte('0'+int(mm)%10), byte('0'+dd/10), byte('0'+dd%10))
+}
+
+// appendFloatMaxScaled bounds the scaled values AppendFloat rounds itself: below 2^43 a float64 resolves
+// thousandths, so a value not within 0.01 of a rounding tie rounds the same as the exact decimal would
+const appendFloatMaxScaled = 1 << 43
+
+// AppendFloat appends the float with exactly _decimals digits after the point, the same text as
+// fmt.Sprintf("%.*f", _decimals, _val) but without fmt: the common case is rounded in integer arithmetic,
+// and values near a rounding tie, huge or not finite fall back to strconv.
+func AppendFloat(_dst []byte, _val float64, _decimals int) []byte {
+	if _decimals < 0 || _decimals >= len(float64pow10) {
+		return strconv.AppendFloat(_dst, _val, 'f', _decimals, 64)
+	}
+	scaled := math.Abs(_val) * float64pow10[_decimals]
+	if !(scaled < appendFloatMaxScaled) || math.Abs(scaled-math.Floor(scaled)-0.5) < 0.01 {
+		return strconv.AppendFloat(_dst, _val, 'f', _decimals, 64)
+	}
+	units := uint64(math.Round(scaled))
+	if math.Signbit(_val) {
+		_dst = append(_dst, '-')
+	}
+	var digits [24]byte
+	pos := len(digits)
+	for ii := 0; ii < _decimals; ii++ {
+		pos--
+		digits[pos] = byte('0' + units%10)
+		units /= 10
+	}
+	if _decimals > 0 {
+		pos--
+		digits[pos] = '.'
+	}
+	for {
+		pos--
+		digits[pos] = byte('0' + units%10)
+		units /= 10
+		if units == 0 {
+			break
+		}
+	}
+	return append(_dst, digits[pos:]...)
+}
+
+// sprintfF is fmt.Sprintf("%f", _val) by AppendFloat
+func sprintfF(_val float64) string {
+	var buf [32]byte
+	return string(AppendFloat(buf[:0], _val, 6))
+}
@@ -0,0 +1,174 @@
+package genutil
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileAuditLogger records file mutations made by genutil helpers to a size-rotated log
+type FileAuditLogger struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	keep     int
+	fo       *os.File
+	size     int64
+}
+
+var fileAuditLogger *FileAuditLogger
+
+// NewFileAuditLogger opens (appending) the audit log at _path.
+// Once it exceeds _maxBytes it is rotated to _path.1, _path.1 to _path.2 and so on, keeping _keep old logs.
+func NewFileAuditLogger(_path string, _maxBytes int64, _keep int) (*FileAuditLogger, error) {
+	us := &FileAuditLogger{path: _path, maxBytes: _maxBytes, keep: _keep}
+	if err := us.open(); err != nil {
+		return nil, err
+	}
+	return us, nil
+}
+
+// SetFileAuditLogger installs the logger used by all genutil helpers, nil turns auditing off
+func SetFileAuditLogger(_al *FileAuditLogger) {
+	fileAuditLogger = _al
+}
+
+// open opens the current log for appending
+func (us *FileAuditLogger) open() error {
+	fo, err := os.OpenFile(us.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0664)
+	if err != nil {
+		return err
+	}
+	stat, err := fo.Stat()
+	if err != nil {
+		fo.Close()
+		return err
+	}
+	us.fo, us.size = fo, stat.Size()
+	return nil
+}
+
+// rotate shifts the old logs up by one and starts a new current log
+func (us *FileAuditLogger) rotate() error {
+	us.fo.Close()
+	for ii := us.keep - 1; ii >= 1; ii-- {
+		from := fmt.Sprintf("%s.%d", us.path, ii)
+		if PathOK(from) {
+			os.Rename(from, fmt.Sprintf("%s.%d", us.path, ii+1))
+		}
+	}
+	if us.keep > 0 {
+		os.Rename(us.path, us.path+".1")
+	} else {
+		os.Remove(us.path)
+	}
+	return us.open()
+}
+
+// Record writes one audit line
+func (us *FileAuditLogger) Record(_op, _fname string, _size int64, _caller string) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	line := fmt.Sprintf("%s op=%s fname=%s size=%d%s\n", time.Now().Format("20060102 15:04:05.000000 MST"), _op, _fname, _size, _caller)
+	if us.maxBytes > 0 && us.size > 0 && us.size+int64(len(line)) > us.maxBytes {
+		if err := us.rotate(); err != nil {
+			fmt.Fprintf(os.Stderr, "genutil.FileAuditLogger: rotate failed: %v\n", err)
+			return
+		}
+	}
+	nn, _ := us.fo.WriteString(line)
+	us.size += int64(nn)
+}
+
+// Close closes the current log
+func (us *FileAuditLogger) Close() {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	us.fo.Close()
+}
+
+// auditFile records a mutation if an audit logger is installed, size -1 means look it up
+func auditFile(_op, _fname string, _size int64) {
+	if fileAuditLogger == nil {
+		return
+	}
+	if _size < 0 {
+		_size = int64(FileSize(_fname))
+	}
+	fileAuditLogger.Record(_op, _fname, _size, auditCallerInfo())
+}
+
+// auditCallerInfo is CallerInfo2 for the first caller outside this package
+func auditCallerInfo() string {
+	pcs := make([]uintptr, 32)
+	nn := runtime.Callers(1, pcs)
+	frames := runtime.CallersFrames(pcs[:nn])
+	self, _ := frames.Next()
+	pkgprefix := strings.TrimSuffix(self.Function, "auditCallerInfo")
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, pkgprefix) {
+			return fmt.Sprintf(" callerFile=%s callerLine=%d pc=%d ok=%t", frame.File, frame.Line, frame.PC, true)
+		}
+		if !more {
+			break
+		}
+	}
+	return CallerInfo2()
+}
+
+// auditSize is the size of the file for an audit record, -1 if it cannot be had
+func auditSize(_fname string) int64 {
+	if fileAuditLogger == nil {
+		return -1
+	}
+	stat, err := os.Lstat(_fname)
+	if err != nil {
+		return -1
+	}
+	return stat.Size()
+}
+
+// auditedRemove is os.Remove recording the removal once it is done
+func auditedRemove(_fname string) error {
+	size := auditSize(_fname)
+	if err := os.Remove(_fname); err != nil {
+		return err
+	}
+	auditFile("remove", _fname, MaxInt64(size, 0))
+	return nil
+}
+
+// auditedRemoveAll is os.RemoveAll recording the removal of the top path once it is done, if it existed
+func auditedRemoveAll(_path string) error {
+	_, statErr := os.Lstat(_path)
+	if err := os.RemoveAll(_path); err != nil {
+		return err
+	}
+	if statErr == nil {
+		auditFile("remove", _path, 0)
+	}
+	return nil
+}
+
+// auditedRename is os.Rename recording the rename once it is done
+func auditedRename(_from, _to string) error {
+	size := auditSize(_from)
+	if err := os.Rename(_from, _to); err != nil {
+		return err
+	}
+	auditFile("rename", _from+" -> "+_to, MaxInt64(size, 0))
+	return nil
+}
+
+// auditedSymlink is os.Symlink recording the new link once it is made
+func auditedSymlink(_target, _linkName string) error {
+	if err := os.Symlink(_target, _linkName); err != nil {
+		return err
+	}
+	auditFile("create", _linkName+" -> "+_target, 0)
+	return nil
+}
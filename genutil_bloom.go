@@ -0,0 +1,152 @@
+package genutil
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// BloomFilter answers "possibly present" or "certainly absent" for string keys in a fixed amount of memory,
+// so a join or dedup over a huge file can skip the exact lookup for most keys. It is not safe for concurrent Add.
+type BloomFilter struct {
+	bits []uint64
+	m    uint64 // number of bits
+	k    int    // number of hashes
+	n    int64  // keys added
+}
+
+// bloomMagic starts a saved BloomFilter
+const bloomMagic = "GBLOOM01"
+
+// BloomSize returns the number of bits and hashes for _n keys at the false positive rate _fpRate (eg 0.01)
+func BloomSize(_n int64, _fpRate float64) (mBits uint64, k int) {
+	if _n < 1 {
+		_n = 1
+	}
+	if _fpRate <= 0 || _fpRate >= 1 {
+		_fpRate = 0.01
+	}
+	mm := math.Ceil(-float64(_n) * math.Log(_fpRate) / (math.Ln2 * math.Ln2))
+	mBits = uint64(math.Max(mm, 64))
+	k = int(math.Round(float64(mBits) / float64(_n) * math.Ln2))
+	return mBits, MaxInt(k, 1)
+}
+
+// NewBloomFilter returns an empty filter sized by BloomSize for _n keys at the false positive rate.
+// 100 million keys at 1% take about 120MB.
+func NewBloomFilter(_n int64, _fpRate float64) *BloomFilter {
+	mBits, k := BloomSize(_n, _fpRate)
+	return &BloomFilter{bits: make([]uint64, (mBits+63)/64), m: mBits, k: k}
+}
+
+// positions calls _fn with each bit position of the key, by double hashing
+func (us *BloomFilter) positions(_key string, _fn func(_pos uint64) bool) {
+	h1 := HashString64(_key)
+	h2 := h1 ^ 0x9e3779b97f4a7c15
+	h2 ^= h2 >> 31
+	h2 *= 0xbf58476d1ce4e5b9
+	h2 ^= h2 >> 29
+	h2 |= 1
+	for ii := 0; ii < us.k; ii++ {
+		if !_fn((h1 + uint64(ii)*h2) % us.m) {
+			return
+		}
+	}
+}
+
+// Add adds the key
+func (us *BloomFilter) Add(_key string) {
+	us.positions(_key, func(_pos uint64) bool {
+		us.bits[_pos/64] |= 1 << (_pos % 64)
+		return true
+	})
+	us.n++
+}
+
+// MaybeContains is false if the key was certainly never added, true if it probably was
+func (us *BloomFilter) MaybeContains(_key string) bool {
+	found := true
+	us.positions(_key, func(_pos uint64) bool {
+		found = us.bits[_pos/64]&(1<<(_pos%64)) != 0
+		return found
+	})
+	return found
+}
+
+// Count returns the number of Add calls, duplicates included
+func (us *BloomFilter) Count() int64 {
+	return us.n
+}
+
+// FalsePositiveRate estimates the current false positive rate from the keys added so far
+func (us *BloomFilter) FalsePositiveRate() float64 {
+	return math.Pow(1-math.Exp(-float64(us.k)*float64(us.n)/float64(us.m)), float64(us.k))
+}
+
+// Save writes the filter to the file, gzipped if the name ends in .gz
+func (us *BloomFilter) Save(_fname string) error {
+	gzf := OpenGzFile(_fname)
+	defer gzf.Close()
+	head := make([]byte, 0, len(bloomMagic)+24)
+	head = append(head, bloomMagic...)
+	head = binary.LittleEndian.AppendUint64(head, us.m)
+	head = binary.LittleEndian.AppendUint64(head, uint64(us.k))
+	head = binary.LittleEndian.AppendUint64(head, uint64(us.n))
+	if _, err := gzf.Write(head); err != nil {
+		return fmt.Errorf("genutil.BloomFilter.Save: %s: %v", _fname, err)
+	}
+	buf := make([]byte, 0, 64*1024)
+	for ii, word := range us.bits {
+		buf = binary.LittleEndian.AppendUint64(buf, word)
+		if len(buf) == cap(buf) || ii == len(us.bits)-1 {
+			if _, err := gzf.Write(buf); err != nil {
+				return fmt.Errorf("genutil.BloomFilter.Save: %s: %v", _fname, err)
+			}
+			buf = buf[:0]
+		}
+	}
+	return nil
+}
+
+// bloomLoadChunkWords bounds what LoadBloomFilter allocates ahead of the data actually read
+const bloomLoadChunkWords = 1 << 20
+
+// LoadBloomFilter reads a filter written by Save (or available compression variant).
+// The size in the header is checked against an uncompressed file's size, and for a compressed one the bits are
+// allocated as they are read, so a corrupt header cannot make it allocate far more than the file holds.
+func LoadBloomFilter(_fname string) (*BloomFilter, error) {
+	bio, err := OpenAnyReadCloser(_fname)
+	if err != nil {
+		return nil, fmt.Errorf("genutil.LoadBloomFilter: %s: %v", _fname, err)
+	}
+	defer bio.Close()
+	head := make([]byte, len(bloomMagic)+24)
+	if _, err := io.ReadFull(bio, head); err != nil || string(head[:len(bloomMagic)]) != bloomMagic {
+		return nil, fmt.Errorf("genutil.LoadBloomFilter: %s: not a saved BloomFilter", _fname)
+	}
+	us := &BloomFilter{
+		m: binary.LittleEndian.Uint64(head[8:]),
+		k: int(binary.LittleEndian.Uint64(head[16:])),
+		n: int64(binary.LittleEndian.Uint64(head[24:])),
+	}
+	if us.m == 0 || us.m > math.MaxInt64-63 || us.k < 1 || us.k > 64 {
+		return nil, fmt.Errorf("genutil.LoadBloomFilter: %s: bad header m(%d) k(%d)", _fname, us.m, us.k)
+	}
+	nwords := (us.m + 63) / 64
+	if size, plain := readableRawSize(_fname); plain && uint64(size) != uint64(len(head))+8*nwords {
+		return nil, fmt.Errorf("genutil.LoadBloomFilter: %s: header m(%d) does not match file size %d", _fname, us.m, size)
+	}
+	us.bits = make([]uint64, 0, MinInt64(int64(nwords), bloomLoadChunkWords))
+	buf := make([]byte, 64*1024)
+	for uint64(len(us.bits)) < nwords {
+		want := int(MinInt64(int64(len(buf)), int64(8*(nwords-uint64(len(us.bits))))))
+		if _, err := io.ReadFull(bio, buf[:want]); err != nil {
+			return nil, fmt.Errorf("genutil.LoadBloomFilter: %s: truncated: %v", _fname, err)
+		}
+		for off := 0; off < want; off += 8 {
+			us.bits = append(us.bits, binary.LittleEndian.Uint64(buf[off:]))
+		}
+	}
+	return us, nil
+}
@@ -0,0 +1,81 @@
+package genutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var strictChecks = false
+
+// SetStrictChecks makes CheckThat and the Require helpers panic (with context) instead of returning the error
+func SetStrictChecks(_strict bool) {
+	strictChecks = _strict
+}
+
+// checkFailed builds the error for a failed check, with the location of the script line that made the check
+func checkFailed(_msg string) error {
+	file, line, _ := CallerInfoN(3)
+	err := fmt.Errorf("%s (at %s:%d)", _msg, filepath.Base(file), line)
+	if strictChecks {
+		PanicWithContext(err.Error())
+	}
+	return err
+}
+
+// CheckThat returns an error built from the format if the condition does not hold
+func CheckThat(_cond bool, _format string, _args ...interface{}) error {
+	if _cond {
+		return nil
+	}
+	return checkFailed(fmt.Sprintf(_format, _args...))
+}
+
+// RequireFile returns an error unless the file (or a compression variant) exists, is not a dir, and can be opened
+func RequireFile(_fname string) error {
+	ofname, _, ofcode := ReadableFilename(_fname)
+	if ofcode == 0 {
+		return checkFailed(fmt.Sprintf("required file(%s) not found in any compression variant", _fname))
+	}
+	if _fname == "-" {
+		return nil
+	}
+	if PathIsDir(ofname) {
+		return checkFailed(fmt.Sprintf("required file(%s) is a dir", ofname))
+	}
+	fi, err := os.Open(ofname)
+	if err != nil {
+		return checkFailed(fmt.Sprintf("required file(%s) cannot be opened: %v", ofname, err))
+	}
+	fi.Close()
+	return nil
+}
+
+// RequireDir returns an error unless the dir exists
+func RequireDir(_dir string) error {
+	if !PathOK(_dir) {
+		return checkFailed(fmt.Sprintf("required dir(%s) not found", _dir))
+	}
+	if !PathIsDir(_dir) {
+		return checkFailed(fmt.Sprintf("required dir(%s) is not a dir", _dir))
+	}
+	return nil
+}
+
+// RequireNonEmpty returns an error if the named value (typically a flag or env var) is blank
+func RequireNonEmpty(_varName, _val string) error {
+	if len(_val) > 0 {
+		return nil
+	}
+	return checkFailed(fmt.Sprintf("required value(%s) is empty", _varName))
+}
+
+// FirstError returns the first non-nil error, so several checks can be made in one statement
+func FirstError(_errs ...error) error {
+	for _, err := range _errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
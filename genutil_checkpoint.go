@@ -0,0 +1,141 @@
+package genutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Checkpoint remembers which keys (typically dates) a batch loop has finished, in a small JSON file
+// that is rewritten atomically on every update, so a restarted job resumes where it stopped:
+//
+//	cp, err := genutil.OpenCheckpoint("/var/run/myjob.ckpt")
+//	for _, dt := range dates {
+//		if cp.ShouldSkip(dt) {
+//			continue
+//		}
+//		process(dt)
+//		cp.Mark(dt)
+//	}
+type Checkpoint struct {
+	fname string
+	state checkpointState
+	done  map[string]bool
+	mu    sync.Mutex
+}
+
+// checkpointState is what is stored in the file
+type checkpointState struct {
+	Last   string   `json:"last"`
+	Offset int64    `json:"offset"`
+	Done   []string `json:"done"`
+}
+
+// OpenCheckpoint loads the checkpoint file if it exists, or starts empty
+func OpenCheckpoint(_fname string) (*Checkpoint, error) {
+	cp := &Checkpoint{fname: _fname, done: map[string]bool{}}
+	buf, err := os.ReadFile(_fname)
+	switch {
+	case os.IsNotExist(err):
+		return cp, nil
+	case err != nil:
+		return nil, fmt.Errorf("genutil.OpenCheckpoint: %v", err)
+	}
+	if err = json.Unmarshal(buf, &cp.state); err != nil {
+		return nil, fmt.Errorf("genutil.OpenCheckpoint: fname(%s) : %v", _fname, err)
+	}
+	for _, key := range cp.state.Done {
+		cp.done[key] = true
+	}
+	return cp, nil
+}
+
+// ShouldSkip is true if the key has been marked done
+func (us *Checkpoint) ShouldSkip(_key string) bool {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	return us.done[_key]
+}
+
+// Mark records the key as done and the last one processed, and saves the checkpoint
+func (us *Checkpoint) Mark(_key string) error {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	if !us.done[_key] {
+		us.done[_key] = true
+		us.state.Done = append(us.state.Done, _key)
+	}
+	us.state.Last = _key
+	return us.save()
+}
+
+// Last returns the key most recently marked, "" if none
+func (us *Checkpoint) Last() string {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	return us.state.Last
+}
+
+// SetOffset records a position within the current key, eg a line number, and saves the checkpoint
+func (us *Checkpoint) SetOffset(_offset int64) error {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	us.state.Offset = _offset
+	return us.save()
+}
+
+// Offset returns the position last given to SetOffset
+func (us *Checkpoint) Offset() int64 {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	return us.state.Offset
+}
+
+// Reset forgets everything and removes the checkpoint file, for a deliberate full rerun
+func (us *Checkpoint) Reset() error {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	us.state, us.done = checkpointState{}, map[string]bool{}
+	if err := auditedRemove(us.fname); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("genutil.Checkpoint.Reset: %v", err)
+	}
+	return nil
+}
+
+// save writes the state with writeFileAtomic
+func (us *Checkpoint) save() error {
+	buf, err := json.Marshal(us.state)
+	if err == nil {
+		err = writeFileAtomic(us.fname, buf)
+	}
+	if err != nil {
+		return fmt.Errorf("genutil.Checkpoint: fname(%s) : %v", us.fname, err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes a temp file in the same dir, syncs it, and renames it over _fname,
+// so readers see either the old or the new content
+func writeFileAtomic(_fname string, _buf []byte) error {
+	fd, err := os.CreateTemp(filepath.Dir(_fname), filepath.Base(_fname)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmpname := fd.Name()
+	_, err = fd.Write(_buf)
+	if err == nil {
+		err = fd.Sync()
+	}
+	if cerr := fd.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = auditedRename(tmpname, _fname)
+	}
+	if err != nil {
+		auditedRemove(tmpname)
+	}
+	return err
+}
@@ -0,0 +1,80 @@
+package genutil
+
+import (
+	"bytes"
+	"strings"
+)
+
+// CommentSpec says which lines of a file are comments and what trailing text is an inline comment:
+//
+//	spec := genutil.CommentSpec{Prefixes: []string{"#", "//"}, Inline: []string{"#"}}
+//	spec.IsComment([]byte("  // note"))   // true
+//	spec.Strip(`a,"b#1",c  # note`)      // a,"b#1",c
+type CommentSpec struct {
+	Blank    bool     // lines of only spaces are comments, as the "Whitespace" tag of IsCommentLine
+	Prefixes []string // lines whose text after leading spaces starts with one of these are comments
+	Inline   []string // Strip drops the text from one of these to the end of the line, outside quotes
+	Quotes   string   // quote characters Strip honors, default `"'`
+}
+
+// ParseCommentSpec makes the spec of the IsCommentLine tags: "Whitespace" for blank lines and "WhitespaceHash"
+// for '#' lines. Other tags are ignored as IsCommentLine does; set Prefixes for "//", ";" or "--" comments.
+func ParseCommentSpec(_tags []string) CommentSpec {
+	spec := CommentSpec{}
+	for _, tag := range _tags {
+		switch tag {
+		case "Whitespace":
+			spec.Blank = true
+		case "WhitespaceHash":
+			spec.Prefixes = append(spec.Prefixes, "#")
+		}
+	}
+	return spec
+}
+
+// IsComment checks the line, which should not have its newline
+func (us CommentSpec) IsComment(_line []byte) bool {
+	if us.Blank && len(_line) > 0 && len(bytes.Trim(_line, " ")) == 0 {
+		return true
+	}
+	if len(us.Prefixes) == 0 {
+		return false
+	}
+	text := bytes.TrimLeft(_line, " ")
+	for _, prefix := range us.Prefixes {
+		if prefix != "" && bytes.HasPrefix(text, []byte(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Strip removes an inline comment and the spaces and tabs before it. A marker inside a quoted field,
+// or after a backslash, is kept.
+func (us CommentSpec) Strip(_line string) string {
+	if len(us.Inline) == 0 {
+		return _line
+	}
+	quotes := StrAorB(us.Quotes, `"'`)
+	var inQuote byte
+	for ii := 0; ii < len(_line); ii++ {
+		ch := _line[ii]
+		switch {
+		case ch == '\\':
+			ii++
+		case inQuote != 0:
+			if ch == inQuote {
+				inQuote = 0
+			}
+		case strings.IndexByte(quotes, ch) >= 0:
+			inQuote = ch
+		default:
+			for _, marker := range us.Inline {
+				if marker != "" && strings.HasPrefix(_line[ii:], marker) {
+					return strings.TrimRight(_line[:ii], " \t")
+				}
+			}
+		}
+	}
+	return _line
+}
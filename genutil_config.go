@@ -0,0 +1,113 @@
+package genutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// readAllAny returns the whole content of the file (or available compression variant)
+func readAllAny(_fname string) ([]byte, error) {
+	bio, err := OpenAnyReadCloser(_fname)
+	if err != nil {
+		return nil, err
+	}
+	defer bio.Close()
+	return ioutil.ReadAll(bio)
+}
+
+// ReadYAMLFile decodes a yaml file into _vv, which may be a *map[string]interface{} or a pointer to a struct
+func ReadYAMLFile(_fname string, _vv interface{}) error {
+	buf, err := readAllAny(_fname)
+	if err != nil {
+		return err
+	}
+	if err = yaml.Unmarshal(buf, _vv); err != nil {
+		return fmt.Errorf("genutil.ReadYAMLFile: fname(%s) : %v", _fname, err)
+	}
+	return nil
+}
+
+// ReadTOMLFile decodes a toml file into _vv, which may be a *map[string]interface{} or a pointer to a struct
+func ReadTOMLFile(_fname string, _vv interface{}) error {
+	buf, err := readAllAny(_fname)
+	if err != nil {
+		return err
+	}
+	if _, err = toml.Decode(string(buf), _vv); err != nil {
+		return fmt.Errorf("genutil.ReadTOMLFile: fname(%s) : %v", _fname, err)
+	}
+	return nil
+}
+
+// ReadConfigFile picks ReadYAMLFile, ReadTOMLFile or ReadJSONFile based on the extension (ignoring compression suffix)
+func ReadConfigFile(_fname string, _vv interface{}) error {
+	base := strings.ToLower(CompressionBasename(_fname))
+	switch {
+	case strings.HasSuffix(base, ".yaml"), strings.HasSuffix(base, ".yml"):
+		return ReadYAMLFile(_fname, _vv)
+	case strings.HasSuffix(base, ".toml"):
+		return ReadTOMLFile(_fname, _vv)
+	case strings.HasSuffix(base, ".json"):
+		return ReadJSONFile(_fname, _vv)
+	}
+	return fmt.Errorf("genutil.ReadConfigFile: unknown config type for fname(%s)", _fname)
+}
+
+// FlattenConfig converts nested config into "a.b.c" keyed values, list elements are keyed by index as in "a.b.0"
+func FlattenConfig(_cfg map[string]interface{}) map[string]string {
+	flat := map[string]string{}
+	flattenConfigInto(flat, "", _cfg)
+	return flat
+}
+
+// FlattenConfigKV returns the flattened config as a key-sorted kvp list usable with GetKV
+func FlattenConfigKV(_cfg map[string]interface{}) string {
+	flat := FlattenConfig(_cfg)
+	keys := make([]string, 0, len(flat))
+	for kk := range flat {
+		keys = append(keys, kk)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for ii, kk := range keys {
+		parts[ii] = kk + "=" + flat[kk]
+	}
+	return strings.Join(parts, ";")
+}
+
+// flattenConfigInto walks one level of nested config
+func flattenConfigInto(_flat map[string]string, _prefix string, _val interface{}) {
+	join := func(_kk string) string {
+		if _prefix == "" {
+			return _kk
+		}
+		return _prefix + "." + _kk
+	}
+	switch vv := _val.(type) {
+	case map[string]interface{}:
+		for kk, elem := range vv {
+			flattenConfigInto(_flat, join(kk), elem)
+		}
+	case map[interface{}]interface{}:
+		for kk, elem := range vv {
+			flattenConfigInto(_flat, join(fmt.Sprint(kk)), elem)
+		}
+	case []map[string]interface{}:
+		for ii, elem := range vv {
+			flattenConfigInto(_flat, join(fmt.Sprintf("%d", ii)), elem)
+		}
+	case []interface{}:
+		for ii, elem := range vv {
+			flattenConfigInto(_flat, join(fmt.Sprintf("%d", ii)), elem)
+		}
+	case nil:
+		_flat[_prefix] = ""
+	default:
+		_flat[_prefix] = fmt.Sprint(vv)
+	}
+}
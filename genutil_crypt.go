@@ -0,0 +1,215 @@
+package genutil
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	agearmor "filippo.io/age/armor"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	pgparmor "github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+// PGPPassphrase, when set, supplies the passphrase for a protected PGP private key or a symmetrically
+// encrypted message read by OpenEncrypted. It is asked once per file.
+var PGPPassphrase func() ([]byte, error)
+
+// encryptedSuffixes are stripped from a file name to find the name of the plain content
+var encryptedSuffixes = []string{".age", ".gpg", ".pgp", ".asc"}
+
+// OpenEncrypted decrypts an age or PGP file (binary or ASCII armored, told apart by content) with the keys in the
+// keyring file: an age identity file (AGE-SECRET-KEY-1... lines) or a PGP secret keyring, armored or binary.
+// Content that is gzipped inside the encryption, as OpenGzFileEncrypted writes x.csv.gz.age, is also uncompressed.
+// The caller must Close the reader to release the file.
+func OpenEncrypted(_fname, _keyring string) (*AnyReadCloser, error) {
+	keys, err := os.ReadFile(_keyring)
+	if err != nil {
+		return nil, fmt.Errorf("genutil.OpenEncrypted: %v", err)
+	}
+	fi, err := os.Open(_fname)
+	if err != nil {
+		return nil, fmt.Errorf("genutil.OpenEncrypted: %v", err)
+	}
+	in := bufio.NewReader(fi)
+	head, _ := in.Peek(64)
+	var plain io.Reader
+	switch {
+	case bytes.HasPrefix(head, []byte("age-encryption.org/")), bytes.HasPrefix(head, []byte(agearmor.Header)):
+		plain, err = ageDecrypt(in, keys, bytes.HasPrefix(head, []byte(agearmor.Header)))
+	default:
+		plain, err = pgpDecrypt(in, keys, bytes.HasPrefix(head, []byte("-----BEGIN PGP")))
+	}
+	if err != nil {
+		fi.Close()
+		return nil, fmt.Errorf("genutil.OpenEncrypted: %s: %v", _fname, err)
+	}
+	out := bufio.NewReaderSize(plain, 20*4096)
+	if magic, _ := out.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzr, err := gzip.NewReader(out)
+		if err != nil {
+			fi.Close()
+			return nil, fmt.Errorf("genutil.OpenEncrypted: %s: %v", _fname, err)
+		}
+		out = bufio.NewReaderSize(gzr, 20*4096)
+	}
+	return &AnyReadCloser{Reader: out, file: fi}, nil
+}
+
+// ageDecrypt opens the age stream with the identities in the key file
+func ageDecrypt(_in io.Reader, _keys []byte, _armored bool) (io.Reader, error) {
+	ids, err := age.ParseIdentities(bytes.NewReader(_keys))
+	if err != nil {
+		return nil, err
+	}
+	if _armored {
+		_in = agearmor.NewReader(_in)
+	}
+	return age.Decrypt(_in, ids...)
+}
+
+// pgpDecrypt opens the PGP message with the secret keyring, asking PGPPassphrase for protected keys
+func pgpDecrypt(_in io.Reader, _keys []byte, _armored bool) (io.Reader, error) {
+	keyring, err := readPGPKeyring(_keys)
+	if err != nil {
+		return nil, err
+	}
+	if _armored {
+		block, err := pgparmor.Decode(_in)
+		if err != nil {
+			return nil, err
+		}
+		_in = block.Body
+	}
+	asked := false
+	prompt := func(_candidates []openpgp.Key, _symmetric bool) ([]byte, error) {
+		if asked || PGPPassphrase == nil {
+			return nil, errors.New("key is passphrase protected and no (correct) PGPPassphrase")
+		}
+		asked = true
+		pass, err := PGPPassphrase()
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range _candidates {
+			if key.PrivateKey != nil && key.PrivateKey.Encrypted {
+				key.PrivateKey.Decrypt(pass)
+			}
+		}
+		return pass, nil
+	}
+	md, err := openpgp.ReadMessage(_in, keyring, prompt, nil)
+	if err != nil {
+		return nil, err
+	}
+	return md.UnverifiedBody, nil
+}
+
+// readPGPKeyring reads an armored or binary keyring
+func readPGPKeyring(_keys []byte) (openpgp.EntityList, error) {
+	if bytes.Contains(_keys[:MinInt(len(_keys), 1024)], []byte("-----BEGIN PGP")) {
+		return openpgp.ReadArmoredKeyRing(bytes.NewReader(_keys))
+	}
+	return openpgp.ReadKeyRing(bytes.NewReader(_keys))
+}
+
+// EncryptingWriter returns a writer that encrypts to _ww for the recipients in the file: age recipients
+// (age1... lines, # comments allowed) or a PGP public keyring. Close it to finish the message, it does not close _ww.
+// PGP output is ASCII armored if _armor is set.
+func EncryptingWriter(_ww io.Writer, _recipients string, _armor bool) (io.WriteCloser, error) {
+	keys, err := os.ReadFile(_recipients)
+	if err != nil {
+		return nil, fmt.Errorf("genutil.EncryptingWriter: %v", err)
+	}
+	if isAgeRecipients(keys) {
+		recips, err := age.ParseRecipients(bytes.NewReader(keys))
+		if err != nil {
+			return nil, fmt.Errorf("genutil.EncryptingWriter: %s: %v", _recipients, err)
+		}
+		ww, err := age.Encrypt(_ww, recips...)
+		if err != nil {
+			return nil, fmt.Errorf("genutil.EncryptingWriter: %v", err)
+		}
+		return ww, nil
+	}
+	keyring, err := readPGPKeyring(keys)
+	if err != nil {
+		return nil, fmt.Errorf("genutil.EncryptingWriter: %s: %v", _recipients, err)
+	}
+	if !_armor {
+		ww, err := openpgp.Encrypt(_ww, keyring, nil, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("genutil.EncryptingWriter: %v", err)
+		}
+		return ww, nil
+	}
+	aw, err := pgparmor.Encode(_ww, "PGP MESSAGE", nil)
+	if err != nil {
+		return nil, fmt.Errorf("genutil.EncryptingWriter: %v", err)
+	}
+	ww, err := openpgp.Encrypt(aw, keyring, nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("genutil.EncryptingWriter: %v", err)
+	}
+	return &stackedWriteCloser{WriteCloser: ww, outer: aw}, nil
+}
+
+// isAgeRecipients tells whether every key line of the file is an age recipient
+func isAgeRecipients(_keys []byte) bool {
+	found := false
+	for _, line := range strings.Split(string(_keys), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "age1"):
+			found = true
+		default:
+			return false
+		}
+	}
+	return found
+}
+
+// stackedWriteCloser closes an encrypting writer and then the armor around it
+type stackedWriteCloser struct {
+	io.WriteCloser
+	outer io.Closer
+}
+
+func (us *stackedWriteCloser) Close() error {
+	if err := us.WriteCloser.Close(); err != nil {
+		return err
+	}
+	return us.outer.Close()
+}
+
+// OpenGzFileEncrypted is OpenGzFile with the content encrypted for the recipients (see EncryptingWriter).
+// The name should end in .age, .gpg, .pgp or .asc (armored PGP); the content is gzipped first if the name
+// without that suffix ends in .gz, as in x.csv.gz.age.
+func OpenGzFileEncrypted(_fname, _recipients string) (GzFile, error) {
+	plain := _fname
+	for _, suffix := range encryptedSuffixes {
+		plain = strings.TrimSuffix(plain, suffix)
+	}
+	checkFreeSpaceOrPanic(_fname) // before the old file is removed or backed up
+	WritableFilename(_fname)
+	fo, err := os.Create(_fname)
+	if err != nil {
+		return GzFile{}, fmt.Errorf("genutil.OpenGzFileEncrypted: %v", err)
+	}
+	gzf := GzFile{fname: _fname, fo: fo, ww: bufio.NewWriter(fo)}
+	if gzf.wwenc, err = EncryptingWriter(gzf.ww, _recipients, strings.HasSuffix(_fname, ".asc")); err != nil {
+		fo.Close()
+		auditedRemove(_fname)
+		return GzFile{}, err
+	}
+	if strings.HasSuffix(plain, ".gz") {
+		gzf.wwgz = gzip.NewWriter(gzf.wwenc)
+	}
+	return gzf, nil
+}
@@ -0,0 +1,132 @@
+package genutil
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// currencyMinorUnits is the number of decimals per ISO 4217 code, others default to 2
+var currencyMinorUnits = map[string]int{
+	"BHD": 3, "BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "IQD": 3, "ISK": 0, "JOD": 3, "JPY": 0, "KMF": 0,
+	"KRW": 0, "KWD": 3, "LYD": 3, "OMR": 3, "PYG": 0, "RWF": 0, "TND": 3, "UGX": 0, "VND": 0, "VUV": 0,
+	"XAF": 0, "XOF": 0, "XPF": 0,
+}
+
+// SetCurrencyMinorUnits overrides the decimals used by FormatAmount for the currency, call it at startup
+func SetCurrencyMinorUnits(_ccy string, _units int) {
+	currencyMinorUnits[strings.ToUpper(_ccy)] = _units
+}
+
+// CurrencyMinorUnits returns the decimals for the currency, 2 if it is not in the table
+func CurrencyMinorUnits(_ccy string) int {
+	if units, ok := currencyMinorUnits[strings.ToUpper(_ccy)]; ok {
+		return units
+	}
+	return 2
+}
+
+// FormatAmount formats the amount with the currency's minor units and thousands separators,
+// so FormatAmount(-1234.5, "USD") is "USD -1,234.50" and FormatAmount(1234.5, "JPY") is "JPY 1,235".
+// An empty ccy gives just the number.
+func FormatAmount(_val float64, _ccy string) string {
+	units := CurrencyMinorUnits(_ccy)
+	str := fmt.Sprintf("%.*f", units, math.Abs(RoundTo(_val, units)))
+	intpart, fracpart := SepSplit2(str, ".")
+	for ii := len(intpart) - 3; ii > 0; ii -= 3 {
+		intpart = intpart[:ii] + "," + intpart[ii:]
+	}
+	if fracpart != "" {
+		intpart += "." + fracpart
+	}
+	if _val < 0 && RoundTo(_val, units) != 0 {
+		intpart = "-" + intpart
+	}
+	if _ccy == "" {
+		return intpart
+	}
+	return strings.ToUpper(_ccy) + " " + intpart
+}
+
+// ParseAmount parses "USD -1,234.50", "-1,234.50 USD", "USD-1234.5" or a bare number into the amount and currency code
+func ParseAmount(_str string) (float64, string, error) {
+	str, ccy := strings.TrimSpace(_str), ""
+	letters := func(_ss string) int {
+		nn := 0
+		for nn < len(_ss) && (('A' <= _ss[nn] && _ss[nn] <= 'Z') || ('a' <= _ss[nn] && _ss[nn] <= 'z')) {
+			nn++
+		}
+		return nn
+	}
+	if nn := letters(str); nn > 0 {
+		ccy, str = str[:nn], str[nn:]
+	} else if idx := strings.LastIndexAny(str, "0123456789."); idx >= 0 && letters(strings.TrimSpace(str[idx+1:])) > 0 {
+		ccy, str = strings.TrimSpace(str[idx+1:]), str[:idx+1]
+	}
+	num := strings.Replace(strings.TrimSpace(str), ",", "", -1)
+	val, err := strconv.ParseFloat(num, 64)
+	if err != nil || (ccy != "" && letters(ccy) != len(ccy)) {
+		return 0, "", fmt.Errorf("ParseAmount: bad amount(%s)", _str)
+	}
+	return val, strings.ToUpper(ccy), nil
+}
+
+// rateOK is true for a usable fx rate: finite and positive
+func rateOK(_rate float64) bool {
+	return _rate > 0 && !math.IsInf(_rate, 0) && !math.IsNaN(_rate)
+}
+
+// InvertRate returns 1/rate, or an error for a zero, negative or non-finite rate
+func InvertRate(_rate float64) (float64, error) {
+	if !rateOK(_rate) {
+		return 0, fmt.Errorf("InvertRate: bad rate(%v)", _rate)
+	}
+	return 1.0 / _rate, nil
+}
+
+// CrossRate returns units of B per unit of A given both quoted per USD, ie _bPerUsd / _aPerUsd,
+// or an error if either rate is zero, negative or non-finite
+func CrossRate(_aPerUsd, _bPerUsd float64) (float64, error) {
+	if !rateOK(_aPerUsd) || !rateOK(_bPerUsd) {
+		return 0, fmt.Errorf("CrossRate: bad rates(%v, %v)", _aPerUsd, _bPerUsd)
+	}
+	return _bPerUsd / _aPerUsd, nil
+}
+
+// formatRate prints with the decimals, or the shortest exact form if _decimals < 0
+func formatRate(_rate float64, _decimals int) string {
+	if _decimals < 0 {
+		return strconv.FormatFloat(_rate, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%.*f", _decimals, RoundTo(_rate, _decimals))
+}
+
+// StrInvertRate is InvertRate for strings, returning _def rather than "+Inf" for an empty or bad rate.
+// A negative _decimals prints the shortest exact form.
+func StrInvertRate(_rate string, _decimals int, _def string) string {
+	f1, err := strconv.ParseFloat(strings.TrimSpace(_rate), 64)
+	if err != nil {
+		return _def
+	}
+	inv, err := InvertRate(f1)
+	if err != nil {
+		return _def
+	}
+	return formatRate(inv, _decimals)
+}
+
+// StrCrossRate is CrossRate for strings, returning _def for an empty or bad rate.
+// A negative _decimals prints the shortest exact form.
+func StrCrossRate(_aPerUsd, _bPerUsd string, _decimals int, _def string) string {
+	fa, erra := strconv.ParseFloat(strings.TrimSpace(_aPerUsd), 64)
+	fb, errb := strconv.ParseFloat(strings.TrimSpace(_bPerUsd), 64)
+	if erra != nil || errb != nil {
+		return _def
+	}
+	cross, err := CrossRate(fa, fb)
+	if err != nil {
+		return _def
+	}
+	return formatRate(cross, _decimals)
+}
@@ -0,0 +1,92 @@
+package genutil
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const daemonEnv = "GENUTIL_DAEMONIZED"
+
+var redirectMu sync.Mutex
+
+// Daemonize re-executes the program in the background, detached from the terminal in its own session,
+// with stdin from /dev/null and stdout/stderr going to <logDir>/<prog>.$YYYY$MM$DD.log, rotated daily
+// by RedirectStdoutStderr. The parent exits 0 once the child has started; in the child Daemonize returns nil.
+// Call it first thing in main, before any goroutines or open files matter.
+func Daemonize(_logDir string) error {
+	if os.Getenv(daemonEnv) != "" {
+		return RedirectStdoutStderr(filepath.Join(_logDir, filepath.Base(os.Args[0])+".$YYYY$MM$DD.log"))
+	}
+	if err := os.MkdirAll(_logDir, 0775); err != nil {
+		return fmt.Errorf("genutil.Daemonize: %v", err)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("genutil.Daemonize: %v", err)
+	}
+	devnull, err := os.Open(os.DevNull)
+	if err != nil {
+		return fmt.Errorf("genutil.Daemonize: %v", err)
+	}
+	defer devnull.Close()
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), daemonEnv+"=1")
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = devnull, devnull, devnull
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err = cmd.Start(); err != nil {
+		return fmt.Errorf("genutil.Daemonize: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "genutil.Daemonize: started pid %d, logging to %s\n", cmd.Process.Pid, _logDir)
+	os.Exit(0)
+	return nil
+}
+
+// RedirectStdoutStderr points file descriptors 1 and 2, and so os.Stdout, os.Stderr, the log package and
+// child processes, at the file opened for append. If the name has FillDate placeholders ($YYYY $MM $DD)
+// it is re-expanded every minute and the output moved to the new file when the date changes.
+func RedirectStdoutStderr(_fname string) error {
+	current := FillDate(_fname, time.Now())
+	if err := redirectTo(current); err != nil {
+		return err
+	}
+	if current == _fname {
+		return nil
+	}
+	go func() {
+		for range time.Tick(time.Minute) {
+			if next := FillDate(_fname, time.Now()); next != current {
+				if err := redirectTo(next); err != nil {
+					fmt.Fprintf(os.Stderr, "%v\n", err)
+					continue
+				}
+				current = next
+			}
+		}
+	}()
+	return nil
+}
+
+// redirectTo dups the file over descriptors 1 and 2
+func redirectTo(_fname string) error {
+	redirectMu.Lock()
+	defer redirectMu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(_fname), 0775); err != nil {
+		return fmt.Errorf("genutil.RedirectStdoutStderr: %v", err)
+	}
+	fd, err := os.OpenFile(_fname, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0664)
+	if err != nil {
+		return fmt.Errorf("genutil.RedirectStdoutStderr: %v", err)
+	}
+	defer fd.Close()
+	for _, target := range []int{1, 2} {
+		if err = syscall.Dup3(int(fd.Fd()), target, 0); err != nil {
+			return fmt.Errorf("genutil.RedirectStdoutStderr: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package genutil
+
+import (
+	"fmt"
+	"path/filepath"
+	"syscall"
+)
+
+var minFreeSpace int64
+
+// SetMinFreeSpace makes OpenGzFile, WriteStringToFile and WriteStringToGzipFile panic before creating a file
+// if its filesystem has fewer than _bytes available, so a job fails fast instead of midway. 0 (the default) disables the check.
+func SetMinFreeSpace(_bytes int64) {
+	minFreeSpace = _bytes
+}
+
+// FreeSpace returns the bytes available to an unprivileged user on the filesystem holding the path
+func FreeSpace(_path string) (int64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(_path, &st); err != nil {
+		return 0, err
+	}
+	return int64(st.Bavail) * int64(st.Bsize), nil
+}
+
+// EnsureFreeSpace returns an error unless the filesystem holding the dir has at least _requiredBytes available
+func EnsureFreeSpace(_dir string, _requiredBytes int64) error {
+	avail, err := FreeSpace(_dir)
+	if err != nil {
+		return fmt.Errorf("genutil.EnsureFreeSpace: dir(%s) : %v", _dir, err)
+	}
+	if avail < _requiredBytes {
+		return fmt.Errorf("genutil.EnsureFreeSpace: dir(%s) has %.2fGB available, need %.2fGB", _dir, float64(avail)/1e9, float64(_requiredBytes)/1e9)
+	}
+	return nil
+}
+
+// checkFreeSpaceOrPanic applies SetMinFreeSpace to the dir of a file about to be created
+func checkFreeSpaceOrPanic(_fname string) {
+	if minFreeSpace <= 0 {
+		return
+	}
+	if err := EnsureFreeSpace(filepath.Dir(_fname), minFreeSpace); err != nil {
+		panic(fmt.Sprintf("cannot create %s: %v", _fname, err))
+	}
+}
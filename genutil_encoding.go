@@ -0,0 +1,220 @@
+package genutil
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encodings understood by OpenAnyWithEncoding and returned by DetectEncoding
+const (
+	EncodingUTF8    = "utf-8"
+	EncodingLatin1  = "iso-8859-1"
+	EncodingCP1252  = "windows-1252"
+	EncodingUTF16LE = "utf-16le"
+	EncodingUTF16BE = "utf-16be"
+)
+
+// encodingAliases maps the spellings seen in feeds and configs to the names above
+var encodingAliases = map[string]string{
+	"utf8": EncodingUTF8, "utf-8": EncodingUTF8, "ascii": EncodingUTF8, "us-ascii": EncodingUTF8,
+	"latin1": EncodingLatin1, "latin-1": EncodingLatin1, "iso-8859-1": EncodingLatin1, "iso8859-1": EncodingLatin1,
+	"cp1252": EncodingCP1252, "windows-1252": EncodingCP1252, "win1252": EncodingCP1252,
+	"utf-16le": EncodingUTF16LE, "utf16le": EncodingUTF16LE, "utf-16": EncodingUTF16LE, "utf16": EncodingUTF16LE,
+	"utf-16be": EncodingUTF16BE, "utf16be": EncodingUTF16BE,
+}
+
+// cp1252High holds the characters windows-1252 puts at 0x80-0x9F, where latin-1 has control codes; 0 is undefined
+var cp1252High = [32]rune{
+	0x20AC, 0, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021, 0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0, 0x017D, 0,
+	0, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014, 0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0, 0x017E, 0x0178,
+}
+
+// encodingSniffBytes is how much of the start of a file DetectEncoding looks at
+const encodingSniffBytes = 64 * 1024
+
+// OpenAnyWithEncoding is OpenAnyErr returning UTF-8 whatever the file is in: the encoding is one of the Encoding*
+// names (or a common alias like latin1, cp1252, utf16), or "" or "auto" to use DetectEncoding. A byte order mark
+// is stripped. For "utf-16" the byte order mark, if any, overrides the little-endian default.
+// The caller must Close the reader, as with OpenAnyReadCloser.
+func OpenAnyWithEncoding(_fname, _encoding string) (*AnyReadCloser, error) {
+	bio, err := OpenAnyReadCloser(_fname)
+	if err != nil {
+		return nil, fmt.Errorf("genutil.OpenAnyWithEncoding: %s: %v", _fname, err)
+	}
+	sniff, _ := bio.Peek(MinInt(encodingSniffBytes, bio.Size()))
+	enc := strings.ToLower(strings.TrimSpace(_encoding))
+	switch enc {
+	case "", "auto":
+		enc = detectEncoding(sniff)
+	case "utf-16", "utf16":
+		enc = EncodingUTF16LE
+		if bytes.HasPrefix(sniff, []byte{0xFE, 0xFF}) {
+			enc = EncodingUTF16BE
+		}
+	default:
+		var ok bool
+		if enc, ok = encodingAliases[enc]; !ok {
+			bio.Close()
+			return nil, fmt.Errorf("genutil.OpenAnyWithEncoding: unknown encoding %s", _encoding)
+		}
+	}
+	switch enc {
+	case EncodingUTF8:
+		if bytes.HasPrefix(sniff, []byte{0xEF, 0xBB, 0xBF}) {
+			bio.Discard(3)
+		}
+		return bio, nil
+	case EncodingUTF16LE, EncodingUTF16BE:
+		if bytes.HasPrefix(sniff, []byte{0xFF, 0xFE}) || bytes.HasPrefix(sniff, []byte{0xFE, 0xFF}) {
+			bio.Discard(2)
+		}
+	}
+	bio.Reader = bufio.NewReaderSize(&decodingReader{src: bio.Reader, enc: enc}, 20*4096)
+	return bio, nil
+}
+
+// DetectEncoding guesses the encoding of the (possibly compressed) file from its first 64KB: a byte order mark
+// decides, then zero bytes in alternate positions mean utf-16, valid UTF-8 means utf-8, and otherwise it is
+// windows-1252 if that explains the 0x80-0x9F bytes, else iso-8859-1.
+func DetectEncoding(_fname string) (string, error) {
+	bio, err := OpenAnyReadCloser(_fname)
+	if err != nil {
+		return "", fmt.Errorf("genutil.DetectEncoding: %s: %v", _fname, err)
+	}
+	defer bio.Close()
+	sniff := make([]byte, encodingSniffBytes)
+	nn, err := io.ReadFull(bio, sniff)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("genutil.DetectEncoding: %s: %v", _fname, err)
+	}
+	return detectEncoding(sniff[:nn]), nil
+}
+
+// detectEncoding is DetectEncoding on the sniffed bytes
+func detectEncoding(_sniff []byte) string {
+	switch {
+	case bytes.HasPrefix(_sniff, []byte{0xEF, 0xBB, 0xBF}):
+		return EncodingUTF8
+	case bytes.HasPrefix(_sniff, []byte{0xFF, 0xFE}):
+		return EncodingUTF16LE
+	case bytes.HasPrefix(_sniff, []byte{0xFE, 0xFF}):
+		return EncodingUTF16BE
+	}
+	zeroEven, zeroOdd := 0, 0
+	for ii, bb := range _sniff {
+		if bb == 0 {
+			if ii%2 == 0 {
+				zeroEven++
+			} else {
+				zeroOdd++
+			}
+		}
+	}
+	if half := len(_sniff) / 2; half > 0 {
+		switch {
+		case zeroOdd > half/2 && zeroOdd > 4*zeroEven:
+			return EncodingUTF16LE
+		case zeroEven > half/2 && zeroEven > 4*zeroOdd:
+			return EncodingUTF16BE
+		}
+	}
+	// a multibyte rune may be cut at the end of the sample
+	trimmed := _sniff
+	for cut := 0; cut < 3 && len(trimmed) > 0 && !utf8.Valid(trimmed); cut++ {
+		trimmed = trimmed[:len(trimmed)-1]
+	}
+	if utf8.Valid(trimmed) {
+		return EncodingUTF8
+	}
+	for _, bb := range _sniff {
+		if bb >= 0x80 && bb <= 0x9F && cp1252High[bb-0x80] == 0 {
+			return EncodingLatin1
+		}
+	}
+	return EncodingCP1252
+}
+
+// decodingReader converts a single byte or utf-16 stream to UTF-8
+type decodingReader struct {
+	src  *bufio.Reader
+	enc  string
+	out  []byte // decoded but not yet returned
+	buf  []byte
+	err  error
+	odd  []byte // a utf-16 byte or surrogate half carried to the next read
+	high rune   // pending high surrogate
+}
+
+func (us *decodingReader) Read(_pp []byte) (int, error) {
+	for len(us.out) == 0 {
+		if us.err != nil {
+			return 0, us.err
+		}
+		us.fill()
+	}
+	nn := copy(_pp, us.out)
+	us.out = us.out[nn:]
+	return nn, nil
+}
+
+// fill decodes the next chunk of the source into out
+func (us *decodingReader) fill() {
+	if us.buf == nil {
+		us.buf = make([]byte, 16*1024)
+	}
+	nn, err := us.src.Read(us.buf)
+	chunk := us.buf[:nn]
+	us.out = us.out[:0]
+	switch us.enc {
+	case EncodingLatin1, EncodingCP1252:
+		for _, bb := range chunk {
+			rr := rune(bb)
+			if us.enc == EncodingCP1252 && bb >= 0x80 && bb <= 0x9F && cp1252High[bb-0x80] != 0 {
+				rr = cp1252High[bb-0x80]
+			}
+			us.out = utf8.AppendRune(us.out, rr)
+		}
+	default:
+		if len(us.odd) > 0 {
+			chunk = append(us.odd, chunk...)
+			us.odd = nil
+		}
+		for ii := 0; ii+1 < len(chunk); ii += 2 {
+			unit := rune(chunk[ii]) | rune(chunk[ii+1])<<8
+			if us.enc == EncodingUTF16BE {
+				unit = rune(chunk[ii])<<8 | rune(chunk[ii+1])
+			}
+			switch {
+			case utf16.IsSurrogate(unit) && unit < 0xDC00:
+				if us.high != 0 {
+					us.out = utf8.AppendRune(us.out, utf8.RuneError)
+				}
+				us.high = unit
+				continue
+			case utf16.IsSurrogate(unit):
+				if us.high == 0 {
+					unit = utf8.RuneError
+				} else {
+					unit = utf16.DecodeRune(us.high, unit)
+				}
+			case us.high != 0:
+				us.out = utf8.AppendRune(us.out, utf8.RuneError)
+			}
+			us.high = 0
+			us.out = utf8.AppendRune(us.out, unit)
+		}
+		if len(chunk)%2 == 1 {
+			us.odd = []byte{chunk[len(chunk)-1]}
+		}
+		if err != nil && (len(us.odd) > 0 || us.high != 0) {
+			us.out = utf8.AppendRune(us.out, utf8.RuneError)
+			us.odd, us.high = nil, 0
+		}
+	}
+	us.err = err
+}
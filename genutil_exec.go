@@ -0,0 +1,158 @@
+package genutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ExecOpts controls how ExecWithOpts runs a command, the zero value runs it in the current dir and environment with no stdin
+type ExecOpts struct {
+	Dir     string        // working dir, empty for the current one
+	Env     []string      // KEY=value entries added to the current environment, overriding the same keys
+	Stdin   io.Reader     // fed to the command, nil for none
+	Timeout time.Duration // kill the command after this long, 0 for no limit
+
+	// MaxOutput caps each of the captured Stdout and Stderr at about this many bytes, keeping the head and the tail
+	// around a truncation marker; 0 captures everything
+	MaxOutput int
+	// LogFile, if set, is appended with the full stdout and stderr regardless of MaxOutput
+	LogFile string
+
+	sysProcAttr *syscall.SysProcAttr // set by ExecAsUser
+}
+
+// execWaitDelay is how long ExecWithOpts waits for the output to close once a timed out command is killed
+const execWaitDelay = 2 * time.Second
+
+// ExecResult is the outcome of ExecWithOpts
+type ExecResult struct {
+	Stdout    string
+	Stderr    string
+	ExitCode  int // -1 if the command did not start or was killed by a signal
+	Duration  time.Duration
+	TimedOut  bool
+	Truncated bool // Stdout or Stderr lost bytes to MaxOutput, see the marker in the text
+}
+
+// ExecWithOpts runs the command without a shell and captures its output. The error is non-nil if the command
+// could not start, exited non-zero, or ran past the timeout; the result is filled in as far as the run got.
+//
+//	res, err := genutil.ExecWithOpts("psql", []string{"-c", query}, genutil.ExecOpts{Env: []string{"PGPASSWORD=" + pw}, Timeout: time.Minute})
+func ExecWithOpts(_name string, _args []string, _opts ExecOpts) (ExecResult, error) {
+	res := ExecResult{ExitCode: -1}
+	ctx := context.Background()
+	if _opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, _opts.Timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, _name, _args...)
+	cmd.Dir, cmd.Stdin, cmd.SysProcAttr = _opts.Dir, _opts.Stdin, _opts.sysProcAttr
+	if _opts.Timeout > 0 {
+		// run it in its own process group and kill the whole group on timeout, else a grandchild holding
+		// stdout or stderr (bash -c "sleep 600; ...") keeps Run waiting long after the command was killed
+		attr := syscall.SysProcAttr{}
+		if _opts.sysProcAttr != nil {
+			attr = *_opts.sysProcAttr
+		}
+		attr.Setpgid = true
+		cmd.SysProcAttr = &attr
+		cmd.Cancel = func() error { return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) }
+		cmd.WaitDelay = execWaitDelay
+	}
+	if len(_opts.Env) > 0 {
+		cmd.Env = append(os.Environ(), _opts.Env...)
+	}
+	stdout, stderr := newCappedBuffer(_opts.MaxOutput), newCappedBuffer(_opts.MaxOutput)
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	if _opts.LogFile != "" {
+		logf, err := os.OpenFile(_opts.LogFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return res, fmt.Errorf("genutil.ExecWithOpts: log file: %v", err)
+		}
+		defer logf.Close()
+		cmd.Stdout, cmd.Stderr = io.MultiWriter(stdout, logf), io.MultiWriter(stderr, logf)
+	}
+
+	start := time.Now()
+	err := cmd.Run()
+	res.Duration = time.Since(start)
+	res.Stdout, res.Stderr = stdout.String(), stderr.String()
+	res.Truncated = stdout.dropped > 0 || stderr.dropped > 0
+	if cmd.ProcessState != nil {
+		res.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	cmdline := strings.Join(cmd.Args, " ")
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		res.TimedOut = true
+		return res, fmt.Errorf("genutil.ExecWithOpts: (%s) timed out after %v", cmdline, _opts.Timeout)
+	}
+	if err != nil {
+		return res, fmt.Errorf("genutil.ExecWithOpts: (%s): %w", cmdline, err)
+	}
+	return res, nil
+}
+
+// BashExecWithOpts runs the string with /bin/bash -c through ExecWithOpts, for when shell syntax is really wanted
+func BashExecWithOpts(_cmd string, _opts ExecOpts) (ExecResult, error) {
+	return ExecWithOpts("/bin/bash", []string{"-c", _cmd}, _opts)
+}
+
+// cappedBuffer keeps the first and last max/2 bytes written to it and counts the rest
+type cappedBuffer struct {
+	max     int
+	head    bytes.Buffer
+	tail    []byte // ring of the latest bytes once head is full
+	pos     int
+	dropped int64
+}
+
+func newCappedBuffer(_max int) *cappedBuffer {
+	return &cappedBuffer{max: _max}
+}
+
+func (us *cappedBuffer) Write(pp []byte) (int, error) {
+	nn := len(pp)
+	if us.max <= 0 {
+		return us.head.Write(pp)
+	}
+	headMax := us.max - us.max/2
+	if room := headMax - us.head.Len(); room > 0 {
+		if room > len(pp) {
+			room = len(pp)
+		}
+		us.head.Write(pp[:room])
+		pp = pp[room:]
+	}
+	tailMax := us.max / 2
+	for _, bb := range pp {
+		if len(us.tail) < tailMax {
+			us.tail = append(us.tail, bb)
+			continue
+		}
+		if tailMax == 0 {
+			us.dropped++
+			continue
+		}
+		us.tail[us.pos] = bb
+		us.pos = (us.pos + 1) % tailMax
+		us.dropped++
+	}
+	return nn, nil
+}
+
+func (us *cappedBuffer) String() string {
+	if us.dropped == 0 {
+		return us.head.String() + string(us.tail)
+	}
+	tail := append(append([]byte{}, us.tail[us.pos:]...), us.tail[:us.pos]...)
+	return fmt.Sprintf("%s\n... [truncated %d bytes] ...\n%s", us.head.String(), us.dropped, tail)
+}
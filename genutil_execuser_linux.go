@@ -0,0 +1,107 @@
+package genutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ErrExecPermission is wrapped by ExecAsUser errors when the switch to the other user was refused,
+// as opposed to the command itself failing, which wraps ErrExecFailed
+var (
+	ErrExecPermission = errors.New("not permitted to run as user")
+	ErrExecFailed     = errors.New("command failed")
+)
+
+// ExecAsUser runs the command line, split by ShellSplit, as the named user (or numeric uid).
+// As the same user it just runs it, as root it sets the uid and gid of the child,
+// otherwise it goes through sudo -n -u so a password prompt fails instead of hanging.
+// opts.Env is passed on to the command in all three cases.
+//
+//	res, err := genutil.ExecAsUser("deploy", "install -m 0644 app.conf /etc/app/", genutil.ExecOpts{})
+//	if errors.Is(err, genutil.ErrExecPermission) { ... }
+func ExecAsUser(_user, _cmd string, _opts ExecOpts) (ExecResult, error) {
+	argv, err := ShellSplit(_cmd)
+	if err == nil && len(argv) == 0 {
+		err = fmt.Errorf("empty command")
+	}
+	if err != nil {
+		return ExecResult{ExitCode: -1}, fmt.Errorf("genutil.ExecAsUser: %v", err)
+	}
+	usr, err := user.Lookup(_user)
+	if err != nil {
+		if usr, err = user.LookupId(_user); err != nil {
+			return ExecResult{ExitCode: -1}, fmt.Errorf("genutil.ExecAsUser: unknown user %s", _user)
+		}
+	}
+	me, _ := user.Current()
+
+	switch {
+	case me != nil && me.Uid == usr.Uid:
+		res, err := ExecWithOpts(argv[0], argv[1:], _opts)
+		return res, classifyExecAsUser(_user, res, err, false)
+	case os.Geteuid() == 0:
+		uid, _ := strconv.ParseUint(usr.Uid, 10, 32)
+		gid, _ := strconv.ParseUint(usr.Gid, 10, 32)
+		groups := []uint32{}
+		if gids, err := usr.GroupIds(); err == nil {
+			for _, gg := range gids {
+				if nn, err := strconv.ParseUint(gg, 10, 32); err == nil {
+					groups = append(groups, uint32(nn))
+				}
+			}
+		}
+		_opts.sysProcAttr = &syscall.SysProcAttr{Credential: &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid), Groups: groups}}
+		_opts.Env = append([]string{"HOME=" + usr.HomeDir, "USER=" + usr.Username, "LOGNAME=" + usr.Username}, _opts.Env...)
+		res, err := ExecWithOpts(argv[0], argv[1:], _opts)
+		return res, classifyExecAsUser(_user, res, err, false)
+	default:
+		// sudo resets the environment, so pass ours through env(1) on the other side
+		args := []string{"-n", "-u", usr.Username, "--"}
+		if len(_opts.Env) > 0 {
+			args = append(append(append(args, "env"), _opts.Env...), argv...)
+			_opts.Env = nil
+		} else {
+			args = append(args, argv...)
+		}
+		res, err := ExecWithOpts("sudo", args, _opts)
+		return res, classifyExecAsUser(_user, res, err, true)
+	}
+}
+
+// classifyExecAsUser wraps the error as ErrExecPermission when the user switch failed, ErrExecFailed otherwise
+func classifyExecAsUser(_user string, _res ExecResult, _err error, _viaSudo bool) error {
+	if _err == nil {
+		return nil
+	}
+	denied := false
+	var exitErr *exec.ExitError
+	switch {
+	case _res.TimedOut:
+	case _viaSudo && errors.Is(_err, exec.ErrNotFound):
+		denied = true
+	case errors.Is(_err, syscall.EPERM) && !errors.As(_err, &exitErr):
+		denied = true // setuid refused at start
+	case _viaSudo && _res.ExitCode == 1 && sudoRefused(_res.Stderr):
+		denied = true
+	}
+	if denied {
+		return fmt.Errorf("genutil.ExecAsUser: %w %s: %v", ErrExecPermission, _user, _err)
+	}
+	return fmt.Errorf("genutil.ExecAsUser: %w: %v", ErrExecFailed, _err)
+}
+
+// sudoRefused spots sudo's own complaints, which it prefixes with "sudo:", among the command's stderr
+func sudoRefused(_stderr string) bool {
+	for _, ln := range strings.Split(_stderr, "\n") {
+		if strings.HasPrefix(ln, "sudo:") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+package genutil
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"unicode"
+)
+
+// EvalExpr evaluates an arithmetic expression like `round((bid+ask)/2, 2) - max(0, fee)` with the named vars.
+// Operators are + - * / and unary minus with the usual precedence, and parentheses.
+// Functions are abs(x), min(x, ...), max(x, ...) and round(x) or round(x, places).
+// Division by zero and unknown vars or functions are errors.
+func EvalExpr(_expr string, _vars map[string]float64) (float64, error) {
+	ep := &exprParser{expr: _expr, vars: _vars}
+	ep.next()
+	val, err := ep.parseSum()
+	if err == nil && ep.tok != "" {
+		err = ep.errorf("unexpected %s", ep.tok)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return val, nil
+}
+
+// exprParser is a recursive descent parser that evaluates as it goes, tok is the current token or "" at the end
+type exprParser struct {
+	expr string
+	pos  int
+	tok  string
+	vars map[string]float64
+}
+
+func (us *exprParser) errorf(_format string, _args ...interface{}) error {
+	return fmt.Errorf("EvalExpr: %s at %d in (%s)", fmt.Sprintf(_format, _args...), us.pos, us.expr)
+}
+
+// next scans the next token: a number, a name, or a single char operator
+func (us *exprParser) next() {
+	for us.pos < len(us.expr) && unicode.IsSpace(rune(us.expr[us.pos])) {
+		us.pos++
+	}
+	start := us.pos
+	switch {
+	case us.pos >= len(us.expr):
+	case IsDigit(us.expr[us.pos]) || us.expr[us.pos] == '.':
+		for us.pos < len(us.expr) && (IsDigit(us.expr[us.pos]) || us.expr[us.pos] == '.') {
+			us.pos++
+		}
+		if us.pos < len(us.expr) && (us.expr[us.pos] == 'e' || us.expr[us.pos] == 'E') {
+			us.pos++
+			if us.pos < len(us.expr) && (us.expr[us.pos] == '+' || us.expr[us.pos] == '-') {
+				us.pos++
+			}
+			for us.pos < len(us.expr) && IsDigit(us.expr[us.pos]) {
+				us.pos++
+			}
+		}
+	case exprIsNameChar(us.expr[us.pos]):
+		for us.pos < len(us.expr) && (exprIsNameChar(us.expr[us.pos]) || IsDigit(us.expr[us.pos])) {
+			us.pos++
+		}
+	default:
+		us.pos++
+	}
+	us.tok = us.expr[start:us.pos]
+}
+
+func exprIsNameChar(_ch byte) bool {
+	return ('a' <= _ch && _ch <= 'z') || ('A' <= _ch && _ch <= 'Z') || _ch == '_'
+}
+
+func (us *exprParser) parseSum() (float64, error) {
+	val, err := us.parseProduct()
+	for err == nil && (us.tok == "+" || us.tok == "-") {
+		op := us.tok
+		us.next()
+		var rhs float64
+		if rhs, err = us.parseProduct(); op == "+" {
+			val += rhs
+		} else {
+			val -= rhs
+		}
+	}
+	return val, err
+}
+
+func (us *exprParser) parseProduct() (float64, error) {
+	val, err := us.parseUnary()
+	for err == nil && (us.tok == "*" || us.tok == "/") {
+		op := us.tok
+		us.next()
+		var rhs float64
+		if rhs, err = us.parseUnary(); err != nil {
+			break
+		}
+		if op == "*" {
+			val *= rhs
+		} else if rhs == 0 {
+			err = us.errorf("division by zero")
+		} else {
+			val /= rhs
+		}
+	}
+	return val, err
+}
+
+func (us *exprParser) parseUnary() (float64, error) {
+	switch us.tok {
+	case "-":
+		us.next()
+		val, err := us.parseUnary()
+		return -val, err
+	case "+":
+		us.next()
+		return us.parseUnary()
+	}
+	return us.parsePrimary()
+}
+
+func (us *exprParser) parsePrimary() (float64, error) {
+	tok := us.tok
+	switch {
+	case tok == "":
+		return 0, us.errorf("unexpected end")
+	case tok == "(":
+		us.next()
+		val, err := us.parseSum()
+		if err == nil && us.tok != ")" {
+			err = us.errorf("missing )")
+		}
+		us.next()
+		return val, err
+	case IsDigit(tok[0]) || tok[0] == '.':
+		val, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return 0, us.errorf("bad number(%s)", tok)
+		}
+		us.next()
+		return val, nil
+	case exprIsNameChar(tok[0]):
+		us.next()
+		if us.tok == "(" {
+			return us.parseCall(tok)
+		}
+		val, ok := us.vars[tok]
+		if !ok {
+			return 0, us.errorf("unknown var(%s)", tok)
+		}
+		return val, nil
+	}
+	return 0, us.errorf("unexpected %s", tok)
+}
+
+// parseCall evaluates the args of the function whose name has been consumed, the current token is (
+func (us *exprParser) parseCall(_name string) (float64, error) {
+	args := []float64{}
+	us.next()
+	for us.tok != ")" {
+		val, err := us.parseSum()
+		if err != nil {
+			return 0, err
+		}
+		args = append(args, val)
+		if us.tok == "," {
+			us.next()
+		} else if us.tok != ")" {
+			return 0, us.errorf("expected , or ) in %s()", _name)
+		}
+	}
+	us.next()
+	switch {
+	case _name == "abs" && len(args) == 1:
+		return math.Abs(args[0]), nil
+	case _name == "round" && len(args) == 1:
+		return math.Round(args[0]), nil
+	case _name == "round" && len(args) == 2:
+		scale := math.Pow(10, math.Round(args[1]))
+		return math.Round(args[0]*scale) / scale, nil
+	case (_name == "min" || _name == "max") && len(args) > 0:
+		val := args[0]
+		for _, arg := range args[1:] {
+			val = FloatTernary(_name == "min", math.Min(val, arg), math.Max(val, arg))
+		}
+		return val, nil
+	case _name == "abs" || _name == "round" || _name == "min" || _name == "max":
+		return 0, us.errorf("wrong number of args(%d) to %s()", len(args), _name)
+	}
+	return 0, us.errorf("unknown function(%s)", _name)
+}
@@ -0,0 +1,83 @@
+package genutil
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// RangeOpts says which lines ExtractRange keeps: those within the line number bounds and, when StartDate or EndDate
+// is set, whose DateCol is within the date bounds. Zero or empty bounds are open.
+type RangeOpts struct {
+	Out       string // output file, compressed if it ends in .gz, "-" for stdout
+	Sep       string // separator named as in SepMap, default comma
+	Header    bool   // the first line is a header, always copied and not counted as a line
+	FromLine  int64  // first line kept, counting from 1
+	ToLine    int64  // last line kept
+	DateCol   int    // 0-based column holding the date, as yyyymmdd, yyyy-mm-dd or yyyy/mm/dd
+	StartDate string // first date kept, yyyymmdd
+	EndDate   string // last date kept, yyyymmdd
+	Sorted    bool   // the file is in ascending DateCol order, so reading stops at the first date past EndDate
+}
+
+// errRangeDone stops the read once no later line can be kept
+var errRangeDone = errors.New("range done")
+
+// ExtractRange copies the lines selected by the options to opts.Out, like head, sed -n 'a,bp' or an awk date filter,
+// and returns the number of lines written, not counting the header. Reading stops after ToLine, and with Sorted at the
+// first date past EndDate. A line whose date column is missing or not a date is skipped when dates are bounded.
+func ExtractRange(_fname string, _opts RangeOpts) (int64, error) {
+	for _, dt := range []string{_opts.StartDate, _opts.EndDate} {
+		if dt != "" && !IsYYYYMMDD(dt) {
+			return 0, fmt.Errorf("genutil.ExtractRange: bad date(%s)", dt)
+		}
+	}
+	if _opts.ToLine > 0 && _opts.ToLine < _opts.FromLine {
+		return 0, fmt.Errorf("genutil.ExtractRange: ToLine(%d) before FromLine(%d)", _opts.ToLine, _opts.FromLine)
+	}
+	if _opts.Out == "" {
+		return 0, fmt.Errorf("genutil.ExtractRange: no Out file")
+	}
+	sep := StrAorB(SepMap(StrAorB(_opts.Sep, ","), true), _opts.Sep)
+	byDate := _opts.StartDate != "" || _opts.EndDate != ""
+	gzf := OpenGzFile(_opts.Out)
+	defer gzf.Close()
+	written, lineno, needHeader := int64(0), int64(0), _opts.Header
+	err := forEachLine(_fname, func(_line string) error {
+		if needHeader {
+			needHeader = false
+			_, err := gzf.WriteString(_line + "\n")
+			return err
+		}
+		lineno++
+		if _opts.ToLine > 0 && lineno > _opts.ToLine {
+			return errRangeDone
+		}
+		if lineno < _opts.FromLine {
+			return nil
+		}
+		if byDate {
+			parts := strings.Split(_line, sep)
+			if _opts.DateCol >= len(parts) {
+				return nil
+			}
+			dt := filterDate(strings.TrimSpace(parts[_opts.DateCol]))
+			switch {
+			case dt == "", _opts.StartDate != "" && dt < _opts.StartDate:
+				return nil
+			case _opts.EndDate != "" && dt > _opts.EndDate:
+				if _opts.Sorted {
+					return errRangeDone
+				}
+				return nil
+			}
+		}
+		written++
+		_, err := gzf.WriteString(_line + "\n")
+		return err
+	})
+	if err != nil && err != errRangeDone {
+		return written, fmt.Errorf("genutil.ExtractRange: %s: %v", _fname, err)
+	}
+	return written, nil
+}
@@ -0,0 +1,88 @@
+package genutil
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// FanoutWriter writes everything to each of its writers. Unlike io.MultiWriter a failing writer does not stop
+// the others: every write goes to all of them and the first error is returned.
+type FanoutWriter struct {
+	writers []io.Writer
+}
+
+// NewFanoutWriter returns a writer duplicating its writes to all the writers, in order
+func NewFanoutWriter(_writers ...io.Writer) *FanoutWriter {
+	return &FanoutWriter{writers: append([]io.Writer(nil), _writers...)}
+}
+
+// Write writes pp to every writer, returning len(pp) if all took it whole
+func (us *FanoutWriter) Write(pp []byte) (int, error) {
+	var first error
+	for _, ww := range us.writers {
+		nn, err := ww.Write(pp)
+		if err == nil && nn != len(pp) {
+			err = io.ErrShortWrite
+		}
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+	if first != nil {
+		return 0, first
+	}
+	return len(pp), nil
+}
+
+// WriteString is Write for a string, using each writer's WriteString where it has one
+func (us *FanoutWriter) WriteString(ss string) (int, error) {
+	var first error
+	for _, ww := range us.writers {
+		nn, err := io.WriteString(ww, ss)
+		if err == nil && nn != len(ss) {
+			err = io.ErrShortWrite
+		}
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+	if first != nil {
+		return 0, first
+	}
+	return len(ss), nil
+}
+
+// Close closes every writer that has a Close, with or without an error result (so GzFile is closed too),
+// returning the first error
+func (us *FanoutWriter) Close() error {
+	var first error
+	for _, ww := range us.writers {
+		switch cc := ww.(type) {
+		case io.Closer:
+			if err := cc.Close(); err != nil && first == nil {
+				first = err
+			}
+		case interface{ Close() }:
+			cc.Close()
+		}
+	}
+	return first
+}
+
+// OpenGzFileTee is OpenGzFile writing the same content to each copy as well, each compressed or not by its own name:
+// OpenGzFileTee("archive/px.20240105.csv.gz", "latest/px.csv") writes the archive and the uncompressed latest copy in one pass.
+// Closing the GzFile closes the copies.
+func OpenGzFileTee(_fname string, _copies ...string) GzFile {
+	for _, cpy := range _copies {
+		if strings.TrimSuffix(cpy, ".gz") == strings.TrimSuffix(_fname, ".gz") {
+			// OpenGzFile removes the other compression variants, which would include the main file
+			panic(fmt.Errorf("genutil.OpenGzFileTee: copy(%s) is the main file(%s) or a compression variant of it", cpy, _fname))
+		}
+	}
+	gzf := OpenGzFile(_fname)
+	for _, cpy := range _copies {
+		gzf.tees = append(gzf.tees, OpenGzFile(cpy))
+	}
+	return gzf
+}
@@ -0,0 +1,116 @@
+package genutil
+
+import (
+	"math"
+	"strconv"
+)
+
+// SplitFieldsBytes splits the line on the separator into out, without allocating: each out[ii] is a subslice
+// of the line, valid until the line's buffer is reused. A trailing newline (or CRLF) is ignored.
+// It returns the number of fields in the line; if that is more than len(out) only the first len(out) are stored,
+// so the caller can grow out and split again.
+//
+//	fields := make([][]byte, 32)
+//	nn := genutil.SplitFieldsBytes(line, ',', fields)
+//	px := genutil.ToFloat(fields[3])
+func SplitFieldsBytes(_line []byte, _sep byte, _out [][]byte) int {
+	if nn := len(_line); nn > 0 && _line[nn-1] == '\n' {
+		_line = _line[:nn-1]
+		if nn > 1 && _line[nn-2] == '\r' {
+			_line = _line[:nn-2]
+		}
+	}
+	count, start := 0, 0
+	for ii := 0; ii < len(_line); ii++ {
+		if _line[ii] == _sep {
+			if count < len(_out) {
+				_out[count] = _line[start:ii:ii]
+			}
+			count++
+			start = ii + 1
+		}
+	}
+	if count < len(_out) {
+		_out[count] = _line[start:len(_line):len(_line)]
+	}
+	return count + 1
+}
+
+// BytesToInt is ToInt on a byte slice: plain decimal integers are parsed in place, anything else
+// (exponent notation, spaces, overflow) takes the ToInt path
+func BytesToInt(_bsl []byte, _def int64) int64 {
+	if val, ok := parseIntFast(_bsl); ok {
+		return val
+	}
+	return ToInt(string(_bsl), _def)
+}
+
+// parseIntFast parses an optionally signed run of at most 18 digits
+func parseIntFast(_bsl []byte) (int64, bool) {
+	digits := _bsl
+	if len(digits) > 0 && (digits[0] == '-' || digits[0] == '+') {
+		digits = digits[1:]
+	}
+	if len(digits) == 0 || len(digits) > 18 {
+		return 0, false
+	}
+	val := int64(0)
+	for _, ch := range digits {
+		if ch < '0' || ch > '9' {
+			return 0, false
+		}
+		val = val*10 + int64(ch-'0')
+	}
+	if _bsl[0] == '-' {
+		val = -val
+	}
+	return val, true
+}
+
+// float64pow10 are the powers of ten exactly representable as float64
+var float64pow10 = [...]float64{1e0, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9, 1e10, 1e11, 1e12, 1e13, 1e14, 1e15,
+	1e16, 1e17, 1e18, 1e19, 1e20, 1e21, 1e22}
+
+// parseFloatFast parses a plain decimal like -123.4500 with at most 15 significant digits. The mantissa and the
+// power of ten are then both exact, so one division gives the correctly rounded result, as strconv would.
+func parseFloatFast(_bsl []byte) (float64, bool) {
+	digits := _bsl
+	neg := false
+	if len(digits) > 0 && (digits[0] == '-' || digits[0] == '+') {
+		neg, digits = digits[0] == '-', digits[1:]
+	}
+	mant, ndigits, frac, seenDot := uint64(0), 0, 0, false
+	for _, ch := range digits {
+		switch {
+		case ch >= '0' && ch <= '9':
+			if mant == 0 && ch == '0' && !seenDot {
+				continue // leading zeros
+			}
+			mant = mant*10 + uint64(ch-'0')
+			if mant != 0 {
+				ndigits++
+			}
+			if seenDot {
+				frac++
+			}
+		case ch == '.' && !seenDot:
+			seenDot = true
+		default:
+			return 0, false
+		}
+	}
+	if len(digits) == 0 || (seenDot && len(digits) == 1) || ndigits > 15 || frac >= len(float64pow10) {
+		return 0, false
+	}
+	val := float64(mant) / float64pow10[frac]
+	if neg {
+		val = math.Copysign(val, -1)
+	}
+	return val, true
+}
+
+// bytesToFloatSlow is the strconv path of ToFloat
+func bytesToFloatSlow(_bsl []byte) float64 {
+	ff, _ := strconv.ParseFloat(string(_bsl), 64)
+	return ff
+}
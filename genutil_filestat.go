@@ -0,0 +1,72 @@
+package genutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// FileStatInfo is the typed form of what FileInfo prints
+type FileStatInfo struct {
+	Fname   string
+	Size    int64
+	Mode    os.FileMode
+	ModTime time.Time
+	IsDir   bool
+	HasSys  bool // false if the platform gave no unix stat, and the fields below are zero
+	Inode   uint64
+	UID     uint32
+	GID     uint32
+	Nlink   uint64
+}
+
+// FileStat stats the file, following symlinks
+func FileStat(_fname string) (FileStatInfo, error) {
+	stat, err := os.Stat(_fname)
+	if err != nil {
+		return FileStatInfo{Fname: _fname}, err
+	}
+	fs := FileStatInfo{Fname: _fname, Size: stat.Size(), Mode: stat.Mode(), ModTime: stat.ModTime(), IsDir: stat.IsDir()}
+	if unixStat, ok := stat.Sys().(*syscall.Stat_t); ok {
+		fs.HasSys = true
+		fs.Inode, fs.UID, fs.GID, fs.Nlink = uint64(unixStat.Ino), unixStat.Uid, unixStat.Gid, uint64(unixStat.Nlink)
+	}
+	return fs, nil
+}
+
+// Format gives the string FileInfo has always returned for the file
+func (us FileStatInfo) Format(_sep string, _fullinfo bool) string {
+	str := fmt.Sprintf("fname=%s%ssize=%d%smode=%s%smodtime=%s",
+		us.Fname, _sep, us.Size, _sep, us.Mode.String(), _sep, us.ModTime.Format("Mon 20060102 15:04:05 MST"))
+	if _fullinfo {
+		str += fmt.Sprintf("%sname=%s%sisdir=%t%s", _sep, filepath.Base(us.Fname), _sep, us.IsDir, _sep)
+		if us.HasSys {
+			str += fmt.Sprintf("inumber=%d%suid=%d%sgid=%d%sNlink=%d", us.Inode, _sep, us.UID, _sep, us.GID, _sep, us.Nlink)
+		}
+	}
+	return str
+}
+
+// MarshalKV gives a kvp list readable by GetKV and GetMapFromKV
+func (us FileStatInfo) MarshalKV() string {
+	return us.Format(";", true)
+}
+
+// MarshalJSON writes the mode as both bits and string, and the mtime in RFC 3339 with nanoseconds
+func (us FileStatInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Fname   string `json:"fname"`
+		Size    int64  `json:"size"`
+		Mode    uint32 `json:"mode"`
+		ModeStr string `json:"modestr"`
+		ModTime string `json:"modtime"`
+		IsDir   bool   `json:"isdir"`
+		Inode   uint64 `json:"inode,omitempty"`
+		UID     uint32 `json:"uid"`
+		GID     uint32 `json:"gid"`
+		Nlink   uint64 `json:"nlink,omitempty"`
+	}{us.Fname, us.Size, uint32(us.Mode), us.Mode.String(), us.ModTime.Format(time.RFC3339Nano), us.IsDir, us.Inode, us.UID, us.GID, us.Nlink})
+}
@@ -0,0 +1,210 @@
+package genutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EvalFilter evaluates a row filter like `sym == "IBM" && (px > 100 || date >= 2024-01-05)` against the kv map.
+// Bare words are keys looked up in kv, or number literals; other literals must be quoted with ' or ".
+// Operators are ==, !=, <, <=, >, >=, !, &&, || and parentheses, with && binding tighter than ||.
+// Values compare numerically when both parse as numbers, dates like 2024-01-05 or 2024/01/05 compare as yyyymmdd,
+// and anything else compares as strings.
+func EvalFilter(_expr string, _kv map[string]string) (bool, error) {
+	toks, err := filterTokens(_expr)
+	if err != nil {
+		return false, err
+	}
+	fp := &filterParser{toks: toks, kv: _kv, expr: _expr}
+	val, err := fp.parseOr()
+	if err == nil && fp.pos < len(fp.toks) {
+		err = fp.errorf("unexpected %s", fp.toks[fp.pos].text)
+	}
+	if err != nil {
+		return false, err
+	}
+	return val, nil
+}
+
+// filterToken is an operator, paren, bare word or (with quoted set) a string literal
+type filterToken struct {
+	text   string
+	quoted bool
+}
+
+// filterTokens splits the expression, operators are matched longest first
+func filterTokens(_expr string) ([]filterToken, error) {
+	toks := []filterToken{}
+	ops := []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")"}
+	for ii := 0; ii < len(_expr); {
+		ch := _expr[ii]
+		if ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' {
+			ii++
+			continue
+		}
+		if ch == '"' || ch == '\'' {
+			end := strings.IndexByte(_expr[ii+1:], ch)
+			if end < 0 {
+				return nil, fmt.Errorf("EvalFilter: unterminated quote at %d in (%s)", ii, _expr)
+			}
+			toks = append(toks, filterToken{text: _expr[ii+1 : ii+1+end], quoted: true})
+			ii += end + 2
+			continue
+		}
+		matched := false
+		for _, op := range ops {
+			if strings.HasPrefix(_expr[ii:], op) {
+				toks = append(toks, filterToken{text: op})
+				ii += len(op)
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+		start := ii
+		for ii < len(_expr) && !strings.ContainsRune(" \t\r\n\"'&|=!<>()", rune(_expr[ii])) {
+			ii++
+		}
+		if ii == start {
+			return nil, fmt.Errorf("EvalFilter: unexpected char(%c) at %d in (%s)", ch, ii, _expr)
+		}
+		toks = append(toks, filterToken{text: _expr[start:ii]})
+	}
+	return toks, nil
+}
+
+// filterParser is a recursive descent parser that evaluates as it goes
+type filterParser struct {
+	toks []filterToken
+	pos  int
+	kv   map[string]string
+	expr string
+}
+
+func (us *filterParser) errorf(_format string, _args ...interface{}) error {
+	return fmt.Errorf("EvalFilter: %s at token %d in (%s)", fmt.Sprintf(_format, _args...), us.pos, us.expr)
+}
+
+// accept consumes the next token if it is the unquoted operator
+func (us *filterParser) accept(_op string) bool {
+	if us.pos < len(us.toks) && !us.toks[us.pos].quoted && us.toks[us.pos].text == _op {
+		us.pos++
+		return true
+	}
+	return false
+}
+
+func (us *filterParser) parseOr() (bool, error) {
+	val, err := us.parseAnd()
+	for err == nil && us.accept("||") {
+		var rhs bool
+		rhs, err = us.parseAnd()
+		val = val || rhs
+	}
+	return val, err
+}
+
+func (us *filterParser) parseAnd() (bool, error) {
+	val, err := us.parseUnary()
+	for err == nil && us.accept("&&") {
+		var rhs bool
+		rhs, err = us.parseUnary()
+		val = val && rhs
+	}
+	return val, err
+}
+
+func (us *filterParser) parseUnary() (bool, error) {
+	switch {
+	case us.accept("!"):
+		val, err := us.parseUnary()
+		return !val, err
+	case us.accept("("):
+		val, err := us.parseOr()
+		if err == nil && !us.accept(")") {
+			err = us.errorf("missing )")
+		}
+		return val, err
+	}
+	lhs, err := us.parseOperand()
+	if err != nil {
+		return false, err
+	}
+	if us.pos >= len(us.toks) {
+		return false, us.errorf("missing comparison")
+	}
+	op := us.toks[us.pos].text
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		us.pos++
+	default:
+		return false, us.errorf("expected comparison, found %s", op)
+	}
+	rhs, err := us.parseOperand()
+	if err != nil {
+		return false, err
+	}
+	cc := filterCompare(lhs, rhs)
+	switch op {
+	case "==":
+		return cc == 0, nil
+	case "!=":
+		return cc != 0, nil
+	case "<":
+		return cc < 0, nil
+	case "<=":
+		return cc <= 0, nil
+	case ">":
+		return cc > 0, nil
+	}
+	return cc >= 0, nil
+}
+
+// parseOperand returns a quoted literal, the value of a key, or a number literal
+func (us *filterParser) parseOperand() (string, error) {
+	if us.pos >= len(us.toks) {
+		return "", us.errorf("missing operand")
+	}
+	tok := us.toks[us.pos]
+	if tok.quoted {
+		us.pos++
+		return tok.text, nil
+	}
+	if strings.ContainsAny(tok.text, "&|=!<>()") {
+		return "", us.errorf("expected operand, found %s", tok.text)
+	}
+	us.pos++
+	if val, ok := us.kv[tok.text]; ok {
+		return val, nil
+	}
+	if _, err := strconv.ParseFloat(tok.text, 64); err == nil || filterDate(tok.text) != "" {
+		return tok.text, nil
+	}
+	us.pos--
+	return "", us.errorf("unknown key(%s)", tok.text)
+}
+
+// filterDate returns yyyymmdd for a yyyymmdd, yyyy-mm-dd or yyyy/mm/dd string, else ""
+func filterDate(_str string) string {
+	if len(_str) == 10 && (_str[4] == '-' || _str[4] == '/') && _str[7] == _str[4] {
+		_str = _str[:4] + _str[5:7] + _str[8:]
+	}
+	return StrTernary(IsYYYYMMDD(_str), _str, "")
+}
+
+// filterCompare compares as dates, then numbers, then strings
+func filterCompare(_aa, _bb string) int {
+	_aa, _bb = strings.TrimSpace(_aa), strings.TrimSpace(_bb)
+	if da, db := filterDate(_aa), filterDate(_bb); da != "" && db != "" {
+		return strings.Compare(da, db)
+	}
+	fa, erra := strconv.ParseFloat(_aa, 64)
+	fb, errb := strconv.ParseFloat(_bb, 64)
+	if erra == nil && errb == nil {
+		return IntTernary(fa < fb, -1, IntTernary(fa > fb, 1, 0))
+	}
+	return strings.Compare(_aa, _bb)
+}
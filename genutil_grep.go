@@ -0,0 +1,69 @@
+package genutil
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// grepMatcher tests a line against one include or exclude pattern
+type grepMatcher func(_line string) bool
+
+// grepMatchers builds the matchers for the patterns, as substrings or (with _useRegex) regexps
+func grepMatchers(_patterns []string, _useRegex bool) ([]grepMatcher, error) {
+	matchers := make([]grepMatcher, 0, len(_patterns))
+	for _, pattern := range _patterns {
+		if !_useRegex {
+			pattern := pattern
+			matchers = append(matchers, func(_line string) bool { return strings.Contains(_line, pattern) })
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern(%s): %v", pattern, err)
+		}
+		matchers = append(matchers, re.MatchString)
+	}
+	return matchers, nil
+}
+
+// GrepFile writes to out the lines of the file that match any include pattern (every line if there are none)
+// and no exclude pattern, and returns how many there were. Patterns are substrings, or regexps with _useRegex.
+// The file is read with OpenAnyErr, so plain and compressed files behave the same, unlike zgrep and friends.
+// Lines are written with a plain newline.
+func GrepFile(_fname string, _include, _exclude []string, _useRegex bool, _out io.Writer) (matched int64, err error) {
+	includes, err := grepMatchers(_include, _useRegex)
+	if err != nil {
+		return 0, fmt.Errorf("genutil.GrepFile: include %v", err)
+	}
+	excludes, err := grepMatchers(_exclude, _useRegex)
+	if err != nil {
+		return 0, fmt.Errorf("genutil.GrepFile: exclude %v", err)
+	}
+	anyMatch := func(_matchers []grepMatcher, _line string) bool {
+		for _, match := range _matchers {
+			if match(_line) {
+				return true
+			}
+		}
+		return false
+	}
+	ww := bufio.NewWriterSize(_out, 64*1024)
+	err = forEachLine(_fname, func(_line string) error {
+		if (len(includes) > 0 && !anyMatch(includes, _line)) || anyMatch(excludes, _line) {
+			return nil
+		}
+		matched++
+		ww.WriteString(_line)
+		return ww.WriteByte('\n')
+	})
+	if err == nil {
+		err = ww.Flush()
+	}
+	if err != nil {
+		return matched, fmt.Errorf("genutil.GrepFile: %s: %v", _fname, err)
+	}
+	return matched, nil
+}
@@ -0,0 +1,645 @@
+package genutil
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// GzIndexSpan is the uncompressed distance between the access points BuildGzIndex records. Each point costs
+// its 32KB window (compressed) in the index, and OpenGzAt decompresses on average half a span before the offset.
+var GzIndexSpan int64 = 4 << 20
+
+const (
+	gzIndexMagic  = "GZIDX001"
+	gzIndexSuffix = ".gzidx"
+	gzWindowSize  = 32768
+)
+
+// gzIndex is the content of an index sidecar
+type gzIndex struct {
+	srcSize  int64
+	srcMtime int64
+	total    int64        // uncompressed size
+	members  [][2]int64   // compressed offset and uncompressed offset of each gzip member
+	points   []gzIdxPoint // in uncompressed order
+}
+
+// gzIdxPoint is a deflate block boundary where decompression can restart given the window before it
+type gzIdxPoint struct {
+	uoff   int64 // uncompressed offset
+	coff   int64 // compressed offset of the block, which starts on a byte boundary
+	member int
+	window []byte
+}
+
+// BuildGzIndex decompresses the gzip file once and writes the sidecar fname.gzidx recording access points about every
+// GzIndexSpan uncompressed bytes (byte-aligned deflate block boundaries with the 32KB window before them, as zlib's
+// zran does), so OpenGzAt can start near any offset. Concatenated gzip members are handled. It returns the uncompressed size.
+func BuildGzIndex(_fname string) (int64, error) {
+	fi, err := os.Stat(_fname)
+	if err != nil {
+		return 0, fmt.Errorf("genutil.BuildGzIndex: %v", err)
+	}
+	fo, err := os.Open(_fname)
+	if err != nil {
+		return 0, fmt.Errorf("genutil.BuildGzIndex: %v", err)
+	}
+	defer fo.Close()
+	idx := &gzIndex{srcSize: fi.Size(), srcMtime: fi.ModTime().UnixNano()}
+	inf := &gzInflater{rd: bufio.NewReaderSize(fo, 256*1024)}
+	if err := inf.run(idx); err != nil {
+		return 0, fmt.Errorf("genutil.BuildGzIndex: %s: %v", _fname, err)
+	}
+	idx.total = inf.out
+	if err := idx.save(_fname + gzIndexSuffix); err != nil {
+		return 0, fmt.Errorf("genutil.BuildGzIndex: %v", err)
+	}
+	return idx.total, nil
+}
+
+// OpenGzAt returns a reader of the gzip file's uncompressed content from the offset on, starting from the
+// nearest access point in the index made by BuildGzIndex instead of from the beginning. A negative offset counts
+// back from the end, so -1000000 gives the last MB. It is an error if the index is missing or older than the file.
+// The caller must Close the reader to release the file.
+func OpenGzAt(_fname string, _offset int64) (*AnyReadCloser, error) {
+	fi, err := os.Stat(_fname)
+	if err != nil {
+		return nil, fmt.Errorf("genutil.OpenGzAt: %v", err)
+	}
+	idx, err := loadGzIndex(_fname + gzIndexSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("genutil.OpenGzAt: %v", err)
+	}
+	if idx.srcSize != fi.Size() || idx.srcMtime != fi.ModTime().UnixNano() {
+		return nil, fmt.Errorf("genutil.OpenGzAt: index %s%s is stale, run BuildGzIndex", _fname, gzIndexSuffix)
+	}
+	if _offset < 0 {
+		_offset = MaxInt64(idx.total+_offset, 0)
+	}
+	if _offset > idx.total {
+		return nil, fmt.Errorf("genutil.OpenGzAt: offset %d beyond uncompressed size %d", _offset, idx.total)
+	}
+	nn := sort.Search(len(idx.points), func(ii int) bool { return idx.points[ii].uoff > _offset }) - 1
+	if nn < 0 {
+		return nil, fmt.Errorf("genutil.OpenGzAt: index %s%s has no access point", _fname, gzIndexSuffix)
+	}
+	pt := idx.points[nn]
+	fo, err := os.Open(_fname)
+	if err != nil {
+		return nil, fmt.Errorf("genutil.OpenGzAt: %v", err)
+	}
+	if _, err := fo.Seek(pt.coff, io.SeekStart); err != nil {
+		fo.Close()
+		return nil, fmt.Errorf("genutil.OpenGzAt: %v", err)
+	}
+	files := &gzAtFiles{fo: fo}
+	var content io.Reader = flate.NewReaderDict(bufio.NewReaderSize(fo, 64*1024), pt.window)
+	if pt.member+1 < len(idx.members) {
+		files.rest = &gzMembersReader{fname: _fname, coff: idx.members[pt.member+1][0]}
+		content = io.MultiReader(content, files.rest)
+	}
+	bio := &AnyReadCloser{Reader: bufio.NewReaderSize(content, 20*4096), file: files}
+	if _, err := bio.Discard(int(_offset - pt.uoff)); err != nil {
+		bio.Close()
+		return nil, fmt.Errorf("genutil.OpenGzAt: %s: %v", _fname, err)
+	}
+	return bio, nil
+}
+
+// gzAtFiles closes the files behind an OpenGzAt reader
+type gzAtFiles struct {
+	fo   *os.File
+	rest *gzMembersReader // the later members, if any
+}
+
+func (us *gzAtFiles) Close() error {
+	if us.rest != nil && us.rest.fo != nil {
+		us.rest.fo.Close()
+	}
+	return us.fo.Close()
+}
+
+// gzMembersReader reads the gzip members from the compressed offset on, opening the file on first use
+type gzMembersReader struct {
+	fname string
+	coff  int64
+	fo    *os.File
+	gzr   *gzip.Reader
+}
+
+func (us *gzMembersReader) Read(_pp []byte) (int, error) {
+	if us.gzr == nil {
+		if us.fo != nil {
+			return 0, fmt.Errorf("genutil.OpenGzAt: %s: bad gzip member at %d", us.fname, us.coff)
+		}
+		var err error
+		if us.fo, err = os.Open(us.fname); err != nil {
+			return 0, err
+		}
+		if _, err := us.fo.Seek(us.coff, io.SeekStart); err != nil {
+			return 0, err
+		}
+		if us.gzr, err = gzip.NewReader(bufio.NewReaderSize(us.fo, 64*1024)); err != nil {
+			return 0, err
+		}
+	}
+	return us.gzr.Read(_pp)
+}
+
+// save writes the index atomically, with the windows deflated
+func (us *gzIndex) save(_fname string) error {
+	var buf bytes.Buffer
+	buf.WriteString(gzIndexMagic)
+	put := func(_vals ...int64) {
+		for _, val := range _vals {
+			buf.Write(binary.LittleEndian.AppendUint64(nil, uint64(val)))
+		}
+	}
+	put(us.srcSize, us.srcMtime, us.total, int64(len(us.members)), int64(len(us.points)))
+	for _, mem := range us.members {
+		put(mem[0], mem[1])
+	}
+	var zbuf bytes.Buffer
+	for _, pt := range us.points {
+		zbuf.Reset()
+		zw, _ := flate.NewWriter(&zbuf, flate.BestSpeed)
+		zw.Write(pt.window)
+		zw.Close()
+		put(pt.uoff, pt.coff, int64(pt.member), int64(zbuf.Len()))
+		buf.Write(zbuf.Bytes())
+	}
+	tmp := _fname + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, _fname); err != nil {
+		return err
+	}
+	auditFile("create", _fname, int64(buf.Len()))
+	return nil
+}
+
+// loadGzIndex reads an index written by save
+func loadGzIndex(_fname string) (*gzIndex, error) {
+	data, err := os.ReadFile(_fname)
+	if err != nil {
+		return nil, fmt.Errorf("no gzip index, run BuildGzIndex: %v", err)
+	}
+	bad := fmt.Errorf("%s is not a valid gzip index", _fname)
+	if !bytes.HasPrefix(data, []byte(gzIndexMagic)) {
+		return nil, bad
+	}
+	data = data[len(gzIndexMagic):]
+	get := func() int64 {
+		if len(data) < 8 {
+			data = nil
+			return -1
+		}
+		val := int64(binary.LittleEndian.Uint64(data))
+		data = data[8:]
+		return val
+	}
+	us := &gzIndex{srcSize: get(), srcMtime: get(), total: get()}
+	nmembers, npoints := get(), get()
+	if nmembers < 0 || npoints < 0 || nmembers > int64(len(data)) || npoints > int64(len(data)) {
+		return nil, bad
+	}
+	for ii := int64(0); ii < nmembers; ii++ {
+		us.members = append(us.members, [2]int64{get(), get()})
+	}
+	for ii := int64(0); ii < npoints; ii++ {
+		pt := gzIdxPoint{uoff: get(), coff: get(), member: int(get())}
+		zlen := get()
+		if zlen < 0 || zlen > int64(len(data)) || pt.member < 0 || pt.member >= len(us.members) {
+			return nil, bad
+		}
+		if pt.window, err = io.ReadAll(flate.NewReader(bytes.NewReader(data[:zlen]))); err != nil {
+			return nil, bad
+		}
+		data = data[zlen:]
+		us.points = append(us.points, pt)
+	}
+	if data == nil {
+		return nil, bad
+	}
+	return us, nil
+}
+
+// gzInflater is a plain deflate decoder that only tracks where blocks start and the window before them,
+// which compress/flate does not expose
+type gzInflater struct {
+	rd        *bufio.Reader
+	consumed  int64 // bytes taken from rd
+	bits      uint64
+	nbits     uint
+	window    [gzWindowSize]byte // ring of the latest output
+	wpos      int
+	out       int64 // total output
+	memberOut int64 // output of the current member
+	lit, dist gzHuffman
+}
+
+var (
+	errGzCorrupt   = errors.New("corrupt deflate data")
+	gzLenBase      = [29]uint16{3, 4, 5, 6, 7, 8, 9, 10, 11, 13, 15, 17, 19, 23, 27, 31, 35, 43, 51, 59, 67, 83, 99, 115, 131, 163, 195, 227, 258}
+	gzLenExtra     = [29]uint8{0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2, 2, 3, 3, 3, 3, 4, 4, 4, 4, 5, 5, 5, 5, 0}
+	gzDistBase     = [30]uint16{1, 2, 3, 4, 5, 7, 9, 13, 17, 25, 33, 49, 65, 97, 129, 193, 257, 385, 513, 769, 1025, 1537, 2049, 3073, 4097, 6145, 8193, 12289, 16385, 24577}
+	gzDistExtra    = [30]uint8{0, 0, 0, 0, 1, 1, 2, 2, 3, 3, 4, 4, 5, 5, 6, 6, 7, 7, 8, 8, 9, 9, 10, 10, 11, 11, 12, 12, 13, 13}
+	gzCodeLenOrder = [19]uint8{16, 17, 18, 0, 8, 7, 9, 6, 10, 5, 11, 4, 12, 3, 13, 2, 14, 1, 15}
+)
+
+// need makes sure at least _nn bits are buffered
+func (us *gzInflater) need(_nn uint) error {
+	for us.nbits < _nn {
+		bb, err := us.rd.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		us.consumed++
+		us.bits |= uint64(bb) << us.nbits
+		us.nbits += 8
+	}
+	return nil
+}
+
+// get takes _nn bits, first in the stream lowest
+func (us *gzInflater) get(_nn uint) (int, error) {
+	if err := us.need(_nn); err != nil {
+		return 0, err
+	}
+	val := int(us.bits & (1<<_nn - 1))
+	us.bits >>= _nn
+	us.nbits -= _nn
+	return val, nil
+}
+
+// bitpos is the stream position in bits of the next unread bit
+func (us *gzInflater) bitpos() int64 {
+	return us.consumed*8 - int64(us.nbits)
+}
+
+func (us *gzInflater) emit(_bb byte) {
+	us.window[us.wpos] = _bb
+	us.wpos = (us.wpos + 1) & (gzWindowSize - 1)
+}
+
+// run decodes every member, adding them and the access points to the index
+func (us *gzInflater) run(_idx *gzIndex) error {
+	lastPoint := int64(0)
+	for member := 0; ; member++ {
+		start, err := us.header()
+		switch {
+		case err == io.EOF && member == 0:
+			return errors.New("not a gzip file")
+		case err == io.EOF:
+			return nil // trailing garbage after the last member is ignored, as gzip does
+		case err != nil:
+			return err
+		}
+		_idx.members = append(_idx.members, [2]int64{start, us.out})
+		us.memberOut = 0
+		for final := false; !final; {
+			// compress/flate can only resume on a byte boundary, where about one block in eight starts
+			if bitpos := us.bitpos(); bitpos%8 == 0 && (us.memberOut == 0 || us.out-lastPoint >= GzIndexSpan) {
+				_idx.points = append(_idx.points, gzIdxPoint{uoff: us.out, coff: bitpos / 8, member: member, window: us.windowBytes()})
+				lastPoint = us.out
+			}
+			hdr, err := us.get(3)
+			if err != nil {
+				return err
+			}
+			final = hdr&1 == 1
+			switch hdr >> 1 {
+			case 0:
+				err = us.stored()
+			case 1:
+				err = us.fixed()
+			case 2:
+				err = us.dynamic()
+			default:
+				err = errGzCorrupt
+			}
+			if err != nil {
+				return err
+			}
+		}
+		us.get(us.nbits & 7)
+		if _, err := us.get(32); err != nil { // crc32
+			return err
+		}
+		isize, err := us.get(32)
+		if err != nil {
+			return err
+		}
+		if uint32(isize) != uint32(us.memberOut) {
+			return fmt.Errorf("member %d length %d does not match trailer %d", member, us.memberOut, isize)
+		}
+	}
+}
+
+// header reads a gzip member header, returning the offset it started at; io.EOF if there is no further member
+func (us *gzInflater) header() (int64, error) {
+	start := us.consumed - int64(us.nbits/8)
+	if us.need(8) != nil && us.nbits == 0 {
+		return start, io.EOF
+	}
+	magic, err := us.get(16)
+	if err != nil || magic != 0x8b1f {
+		return start, io.EOF
+	}
+	method, _ := us.get(8)
+	flags, err := us.get(8)
+	if err != nil || method != 8 {
+		return start, errors.New("bad gzip header")
+	}
+	if _, err := us.get(32); err != nil { // mtime
+		return start, err
+	}
+	us.get(16) // xfl, os
+	if flags&4 != 0 {
+		xlen, err := us.get(16)
+		if err != nil {
+			return start, err
+		}
+		for ; xlen > 0; xlen-- {
+			us.get(8)
+		}
+	}
+	for _, flag := range []int{8, 16} { // name, comment
+		for flags&flag != 0 {
+			ch, err := us.get(8)
+			if err != nil {
+				return start, err
+			}
+			if ch == 0 {
+				break
+			}
+		}
+	}
+	if flags&2 != 0 {
+		if _, err := us.get(16); err != nil {
+			return start, err
+		}
+	}
+	return start, nil
+}
+
+// windowBytes returns the window in order, only as much as the current member has produced
+func (us *gzInflater) windowBytes() []byte {
+	nn := int(MinInt64(us.memberOut, gzWindowSize))
+	out := make([]byte, nn)
+	for ii := 0; ii < nn; ii++ {
+		out[ii] = us.window[(us.wpos-nn+ii)&(gzWindowSize-1)]
+	}
+	return out
+}
+
+func (us *gzInflater) stored() error {
+	us.get(us.nbits & 7)
+	nn, err := us.get(16)
+	if err != nil {
+		return err
+	}
+	if cmp, err := us.get(16); err != nil || cmp != ^nn&0xffff {
+		return errGzCorrupt
+	}
+	for ; nn > 0; nn-- {
+		bb, err := us.get(8)
+		if err != nil {
+			return err
+		}
+		us.emit(byte(bb))
+		us.out++
+		us.memberOut++
+	}
+	return nil
+}
+
+func (us *gzInflater) fixed() error {
+	var lengths [288 + 30]uint8
+	for ii := range lengths {
+		switch {
+		case ii < 144:
+			lengths[ii] = 8
+		case ii < 256:
+			lengths[ii] = 9
+		case ii < 280:
+			lengths[ii] = 7
+		case ii < 288:
+			lengths[ii] = 8
+		default:
+			lengths[ii] = 5
+		}
+	}
+	us.lit.build(lengths[:288])
+	us.dist.build(lengths[288:])
+	return us.codes()
+}
+
+func (us *gzInflater) dynamic() error {
+	nlen, err1 := us.get(5)
+	ndist, err2 := us.get(5)
+	ncode, err3 := us.get(4)
+	if err := errors.Join(err1, err2, err3); err != nil {
+		return err
+	}
+	nlen, ndist, ncode = nlen+257, ndist+1, ncode+4
+	if nlen > 286 || ndist > 30 {
+		return errGzCorrupt
+	}
+	var lengths [320]uint8
+	for ii := 0; ii < ncode; ii++ {
+		val, err := us.get(3)
+		if err != nil {
+			return err
+		}
+		lengths[gzCodeLenOrder[ii]] = uint8(val)
+	}
+	var lencode gzHuffman
+	if lencode.build(lengths[:19]) != nil {
+		return errGzCorrupt
+	}
+	for ii := range lengths[:19] {
+		lengths[ii] = 0
+	}
+	for ii := 0; ii < nlen+ndist; {
+		sym, err := us.decode(&lencode)
+		if err != nil {
+			return err
+		}
+		if sym < 16 {
+			lengths[ii] = uint8(sym)
+			ii++
+			continue
+		}
+		var rep int
+		val := uint8(0)
+		switch sym {
+		case 16:
+			if ii == 0 {
+				return errGzCorrupt
+			}
+			val = lengths[ii-1]
+			rep, err = us.get(2)
+			rep += 3
+		case 17:
+			rep, err = us.get(3)
+			rep += 3
+		default:
+			rep, err = us.get(7)
+			rep += 11
+		}
+		if err != nil {
+			return err
+		}
+		if ii+rep > nlen+ndist {
+			return errGzCorrupt
+		}
+		for ; rep > 0; rep-- {
+			lengths[ii] = val
+			ii++
+		}
+	}
+	if lengths[256] == 0 || us.lit.build(lengths[:nlen]) != nil || us.dist.build(lengths[nlen:nlen+ndist]) != nil {
+		return errGzCorrupt
+	}
+	return us.codes()
+}
+
+// codes decodes literals and matches up to the end of block
+func (us *gzInflater) codes() error {
+	for {
+		sym, err := us.decode(&us.lit)
+		if err != nil {
+			return err
+		}
+		switch {
+		case sym < 256:
+			us.emit(byte(sym))
+			us.out++
+			us.memberOut++
+			continue
+		case sym == 256:
+			return nil
+		case sym > 285:
+			return errGzCorrupt
+		}
+		sym -= 257
+		extra, err := us.get(uint(gzLenExtra[sym]))
+		if err != nil {
+			return err
+		}
+		length := int(gzLenBase[sym]) + extra
+		dsym, err := us.decode(&us.dist)
+		if err != nil {
+			return err
+		}
+		if dsym > 29 {
+			return errGzCorrupt
+		}
+		extra, err = us.get(uint(gzDistExtra[dsym]))
+		if err != nil {
+			return err
+		}
+		dist := int(gzDistBase[dsym]) + extra
+		if int64(dist) > us.memberOut {
+			return errGzCorrupt
+		}
+		for ii := 0; ii < length; ii++ {
+			us.emit(us.window[(us.wpos-dist)&(gzWindowSize-1)])
+		}
+		us.out += int64(length)
+		us.memberOut += int64(length)
+	}
+}
+
+// gzFastBits is the width of the direct lookup table of gzHuffman
+const gzFastBits = 9
+
+// gzHuffman is a canonical Huffman code: short codes are looked up directly, longer ones decoded bit by bit
+type gzHuffman struct {
+	count  [16]uint16
+	symbol [320]uint16
+	fast   [1 << gzFastBits]uint16 // symbol<<4 | length, 0 if the code is longer than gzFastBits
+}
+
+// build sets up the code from the code length of each symbol; an over-subscribed set of lengths is an error,
+// an incomplete one is allowed as deflate permits it for a single distance code
+func (us *gzHuffman) build(_lengths []uint8) error {
+	*us = gzHuffman{}
+	for _, ll := range _lengths {
+		us.count[ll]++
+	}
+	left := 1
+	for ll := 1; ll < 16; ll++ {
+		left = left<<1 - int(us.count[ll])
+		if left < 0 {
+			return errGzCorrupt
+		}
+	}
+	us.count[0] = 0
+	var offs, next [16]int // position in symbol and next canonical code, per length
+	for ll := 2; ll < 16; ll++ {
+		offs[ll] = offs[ll-1] + int(us.count[ll-1])
+		next[ll] = (next[ll-1] + int(us.count[ll-1])) << 1
+	}
+	for sym, ll := range _lengths {
+		if ll == 0 {
+			continue
+		}
+		us.symbol[offs[ll]] = uint16(sym)
+		offs[ll]++
+		cc := next[ll]
+		next[ll]++
+		if ll > gzFastBits {
+			continue
+		}
+		rev := 0
+		for ii := uint8(0); ii < ll; ii++ {
+			rev = rev<<1 | (cc>>ii)&1
+		}
+		for fill := rev; fill < 1<<gzFastBits; fill += 1 << ll {
+			us.fast[fill] = uint16(sym)<<4 | uint16(ll)
+		}
+	}
+	return nil
+}
+
+// decode reads one symbol of the code
+func (us *gzInflater) decode(_hh *gzHuffman) (int, error) {
+	if us.need(gzFastBits) == nil {
+		if entry := _hh.fast[us.bits&(1<<gzFastBits-1)]; entry != 0 {
+			ll := uint(entry & 15)
+			us.bits >>= ll
+			us.nbits -= ll
+			return int(entry >> 4), nil
+		}
+	}
+	code, first, index := 0, 0, 0
+	for ll := 1; ll < 16; ll++ {
+		bit, err := us.get(1)
+		if err != nil {
+			return 0, err
+		}
+		code |= bit
+		count := int(_hh.count[ll])
+		if code-first < count {
+			return int(_hh.symbol[index+code-first]), nil
+		}
+		index += count
+		first = (first + count) << 1
+		code <<= 1
+	}
+	return 0, errGzCorrupt
+}
@@ -0,0 +1,41 @@
+package genutil
+
+// HashString64 is a stable 64 bit hash of the string: FNV-1a with a final bit mix so that nearby keys like
+// ACCT0001 and ACCT0002 spread evenly over the low bits. It does not change between runs, hosts or releases,
+// so it is safe to persist or to use for naming output files.
+func HashString64(_str string) uint64 {
+	hh := uint64(14695981039346656037)
+	for ii := 0; ii < len(_str); ii++ {
+		hh ^= uint64(_str[ii])
+		hh *= 1099511628211
+	}
+	hh ^= hh >> 33
+	hh *= 0xff51afd7ed558ccd
+	hh ^= hh >> 33
+	hh *= 0xc4ceb9fe1a85ec53
+	hh ^= hh >> 33
+	return hh
+}
+
+// PartitionKey returns the shard, 0 to _nShards-1, for the key; 0 if _nShards is less than 2.
+// Changing _nShards moves most keys, use ConsistentHash when shards come and go.
+func PartitionKey(_key string, _nShards int) int {
+	if _nShards < 2 {
+		return 0
+	}
+	return int(HashString64(_key) % uint64(_nShards))
+}
+
+// ConsistentHash picks one of the shards for the key by rendezvous hashing: the shard scoring highest for the key wins.
+// Adding or removing a shard only moves the keys that land on or came from it, and the order of the list does not
+// matter. It returns "" for no shards.
+func ConsistentHash(_key string, _shards []string) string {
+	best, bestScore := "", uint64(0)
+	for ii, shard := range _shards {
+		score := HashString64(shard + "\x00" + _key)
+		if ii == 0 || score > bestScore || (score == bestScore && shard < best) {
+			best, bestScore = shard, score
+		}
+	}
+	return best
+}
@@ -0,0 +1,75 @@
+package genutil
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	heartbeatStage   = ""
+	heartbeatStageMu sync.Mutex
+)
+
+// SetHeartbeatStage sets the stage name written by running heartbeats from their next beat on
+func SetHeartbeatStage(_stage string) {
+	heartbeatStageMu.Lock()
+	defer heartbeatStageMu.Unlock()
+	heartbeatStage = _stage
+}
+
+// StartHeartbeat rewrites the file now and every interval with a kvp list readable by GetMapFromKV:
+//
+//	time=2024-01-05T10:00:00.123Z;pid=1234;host=box1;stage=loading
+//
+// until the returned stop func is called, which is safe to call more than once.
+func StartHeartbeat(_fname string, _interval time.Duration) (stop func()) {
+	host, _ := os.Hostname()
+	beat := func() {
+		heartbeatStageMu.Lock()
+		stage := heartbeatStage
+		heartbeatStageMu.Unlock()
+		line := fmt.Sprintf("time=%s;pid=%d;host=%s;stage=%s\n", time.Now().UTC().Format("2006-01-02T15:04:05.000Z07:00"), os.Getpid(), host, stage)
+		if err := writeFileAtomic(_fname, []byte(line)); err != nil {
+			fmt.Fprintf(os.Stderr, "genutil.StartHeartbeat: %v\n", err)
+		}
+	}
+	beat()
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(_interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				beat()
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// CheckHeartbeat returns the stage and age of the last beat in the file, with an error if the file
+// is missing or unreadable or the beat is older than _maxAge
+func CheckHeartbeat(_fname string, _maxAge time.Duration) (stage string, age time.Duration, err error) {
+	buf, err := os.ReadFile(_fname)
+	if err != nil {
+		return "", 0, fmt.Errorf("genutil.CheckHeartbeat: %v", err)
+	}
+	kv := GetMapFromKV(string(buf))
+	stage = strings.TrimRight(kv["stage"], "\r\n")
+	beatTime, perr := time.Parse(time.RFC3339, kv["time"])
+	if perr != nil {
+		return stage, 0, fmt.Errorf("genutil.CheckHeartbeat: file(%s) has no valid time", _fname)
+	}
+	age = time.Since(beatTime)
+	if age > _maxAge {
+		return stage, age, fmt.Errorf("genutil.CheckHeartbeat: file(%s) is stale, last beat %s ago at stage(%s)", _fname, age.Round(time.Second), stage)
+	}
+	return stage, age, nil
+}
@@ -0,0 +1,230 @@
+package genutil
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTMLFetchTimeout bounds the whole request made by FetchHTMLTable
+var HTMLFetchTimeout = time.Minute
+
+// ParseHTMLTables returns every <table> of the page in document order, each as rows of cell text.
+// Cell text has entities decoded and whitespace collapsed; a cell with colspan=N is followed by N-1 empty cells,
+// N capped at 1000 as browsers do and taken as 1 if below, while rowspan is ignored. A nested table is returned on its own and contributes no text to the enclosing cell.
+// The scan is tag-level and forgiving, so pages that are not well-formed XML still parse; script and style are skipped.
+func ParseHTMLTables(_rr io.Reader) ([][][]string, error) {
+	buf, err := io.ReadAll(_rr)
+	if err != nil {
+		return nil, fmt.Errorf("genutil.ParseHTMLTables: %v", err)
+	}
+	page := string(buf)
+	tables := [][][]string{}
+	stack := []*htmlTable{}
+	for pos := 0; pos < len(page); {
+		lt := strings.IndexByte(page[pos:], '<')
+		if lt < 0 {
+			lt = len(page) - pos
+		}
+		if len(stack) > 0 {
+			stack[len(stack)-1].text(page[pos : pos+lt])
+		}
+		pos += lt
+		if pos >= len(page) {
+			break
+		}
+		name, closing, attrs, end := htmlTag(page, pos)
+		pos = end
+		if !closing && (name == "script" || name == "style") {
+			if stop := strings.Index(strings.ToLower(page[pos:]), "</"+name); stop >= 0 {
+				pos += stop
+			} else {
+				pos = len(page)
+			}
+			continue
+		}
+		switch {
+		case name == "table" && !closing:
+			stack = append(stack, &htmlTable{index: len(tables)})
+			tables = append(tables, nil)
+		case len(stack) == 0:
+		case name == "table":
+			top := stack[len(stack)-1]
+			top.endRow()
+			tables[top.index] = top.rows
+			stack = stack[:len(stack)-1]
+		case name == "tr":
+			stack[len(stack)-1].endRow()
+		case name == "td" || name == "th":
+			top := stack[len(stack)-1]
+			top.endCell()
+			if !closing {
+				top.startCell(attrs)
+			}
+		case name == "br" || name == "p" || name == "div" || name == "li":
+			stack[len(stack)-1].text(" ")
+		}
+	}
+	for len(stack) > 0 { // unclosed tables
+		top := stack[len(stack)-1]
+		top.endRow()
+		tables[top.index] = top.rows
+		stack = stack[:len(stack)-1]
+	}
+	return tables, nil
+}
+
+// FetchHTMLTable gets the page and returns its table number _tableIndex (0-based) as parsed by ParseHTMLTables
+func FetchHTMLTable(_url string, _tableIndex int) ([][]string, error) {
+	client := &http.Client{Timeout: HTMLFetchTimeout}
+	resp, err := client.Get(_url)
+	if err != nil {
+		return nil, fmt.Errorf("genutil.FetchHTMLTable: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("genutil.FetchHTMLTable: %s: %s", _url, resp.Status)
+	}
+	tables, err := ParseHTMLTables(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("genutil.FetchHTMLTable: %s: %v", _url, err)
+	}
+	if _tableIndex < 0 || _tableIndex >= len(tables) {
+		return nil, fmt.Errorf("genutil.FetchHTMLTable: %s has %d tables, no table %d", _url, len(tables), _tableIndex)
+	}
+	return tables[_tableIndex], nil
+}
+
+// htmlMaxColspan caps a cell's colspan as browsers do, so a hostile page cannot make us add billions of cells
+const htmlMaxColspan = 1000
+
+// htmlTable collects the rows of a table being parsed
+type htmlTable struct {
+	index   int
+	rows    [][]string
+	row     []string
+	inRow   bool
+	cell    *strings.Builder // nil outside a cell
+	colspan int
+}
+
+func (us *htmlTable) text(_ss string) {
+	if us.cell != nil {
+		us.cell.WriteString(_ss)
+	}
+}
+
+func (us *htmlTable) startCell(_attrs string) {
+	if !us.inRow {
+		us.row, us.inRow = []string{}, true
+	}
+	us.cell, us.colspan = &strings.Builder{}, 1
+	if span, err := strconv.Atoi(htmlAttr(_attrs, "colspan")); err == nil && span > 1 {
+		us.colspan = MinInt(span, htmlMaxColspan)
+	}
+}
+
+func (us *htmlTable) endCell() {
+	if us.cell == nil {
+		return
+	}
+	us.row = append(us.row, strings.Join(strings.Fields(html.UnescapeString(us.cell.String())), " "))
+	for ii := 1; ii < us.colspan; ii++ {
+		us.row = append(us.row, "")
+	}
+	us.cell = nil
+}
+
+func (us *htmlTable) endRow() {
+	us.endCell()
+	if us.inRow {
+		us.rows = append(us.rows, us.row)
+	}
+	us.row, us.inRow = nil, false
+}
+
+// htmlTag reads the tag starting at _page[_pos] == '<' and returns its lowercased name, whether it is a closing tag,
+// the raw attribute text, and the position after it. Comments and declarations come back with an empty name.
+func htmlTag(_page string, _pos int) (name string, closing bool, attrs string, end int) {
+	rest := _page[_pos:]
+	switch {
+	case strings.HasPrefix(rest, "<!--"):
+		if stop := strings.Index(rest, "-->"); stop >= 0 {
+			return "", false, "", _pos + stop + 3
+		}
+		return "", false, "", len(_page)
+	case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+		if stop := strings.IndexByte(rest, '>'); stop >= 0 {
+			return "", false, "", _pos + stop + 1
+		}
+		return "", false, "", len(_page)
+	}
+	ii := 1
+	if ii < len(rest) && rest[ii] == '/' {
+		closing = true
+		ii++
+	}
+	start := ii
+	for ii < len(rest) && (isASCIILetter(rest[ii]) || (ii > start && rest[ii] >= '0' && rest[ii] <= '9')) {
+		ii++
+	}
+	if ii == start {
+		return "", false, "", _pos + 1 // a lone '<' is text, skipped
+	}
+	name = strings.ToLower(rest[start:ii])
+	attrStart := ii
+	var quote byte
+	for ; ii < len(rest); ii++ {
+		switch ch := rest[ii]; {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '>':
+			return name, closing, rest[attrStart:ii], _pos + ii + 1
+		}
+	}
+	return name, closing, rest[attrStart:], len(_page)
+}
+
+// htmlAttr returns the value of the attribute in the raw attribute text, "" if absent
+func htmlAttr(_attrs, _name string) string {
+	lower := strings.ToLower(_attrs)
+	for from := 0; ; {
+		at := strings.Index(lower[from:], _name)
+		if at < 0 {
+			return ""
+		}
+		at += from
+		from = at + len(_name)
+		if at > 0 && !strings.ContainsRune(" \t\n\r/", rune(lower[at-1])) {
+			continue
+		}
+		rest := strings.TrimLeft(_attrs[from:], " \t\n\r")
+		if !strings.HasPrefix(rest, "=") {
+			continue
+		}
+		rest = strings.TrimLeft(rest[1:], " \t\n\r")
+		if rest != "" && (rest[0] == '"' || rest[0] == '\'') {
+			if stop := strings.IndexByte(rest[1:], rest[0]); stop >= 0 {
+				return html.UnescapeString(rest[1 : stop+1])
+			}
+			return html.UnescapeString(rest[1:])
+		}
+		if stop := strings.IndexAny(rest, " \t\n\r/>"); stop >= 0 {
+			rest = rest[:stop]
+		}
+		return html.UnescapeString(rest)
+	}
+}
+
+// isASCIILetter tells whether the byte is a-z or A-Z
+func isASCIILetter(_ch byte) bool {
+	return (_ch >= 'a' && _ch <= 'z') || (_ch >= 'A' && _ch <= 'Z')
+}
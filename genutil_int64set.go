@@ -0,0 +1,437 @@
+package genutil
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"sort"
+)
+
+// Int64Set is a compressed set of int64s in the style of a roaring bitmap: values are grouped by their high 48 bits
+// into chunks of 65536, each held as a sorted array while sparse and as a bitmap once dense.
+// Row ids and yyyymmdd dates take a few bytes each or less, against about 40 for a map[int64]bool.
+// It is not safe for concurrent use while being modified.
+type Int64Set struct {
+	highs  []uint64 // sorted chunk keys
+	chunks []*int64Chunk
+	card   int64
+}
+
+// int64Chunk holds the low 16 bits of the values of one chunk, in array or bitmap but not both
+type int64Chunk struct {
+	array  []uint16
+	bitmap []uint64 // int64ChunkWords words when dense
+	card   int
+}
+
+const (
+	int64ChunkMaxArray = 4096 // an array chunk becomes a bitmap beyond this, where the two are the same size
+	int64ChunkWords    = 1024
+	int64SetMagic      = "GI64SET1"
+)
+
+// NewInt64Set returns an empty set holding the values
+func NewInt64Set(_vals ...int64) *Int64Set {
+	us := &Int64Set{}
+	for _, val := range _vals {
+		us.Add(val)
+	}
+	return us
+}
+
+// int64SetSplit maps the value to its chunk key and low bits, flipping the sign bit so negatives sort first
+func int64SetSplit(_val int64) (uint64, uint16) {
+	uu := uint64(_val) ^ (1 << 63)
+	return uu >> 16, uint16(uu)
+}
+
+// int64SetJoin is the inverse of int64SetSplit
+func int64SetJoin(_high uint64, _low uint16) int64 {
+	return int64((_high<<16 | uint64(_low)) ^ (1 << 63))
+}
+
+// find returns the position of the chunk key, and whether it is present
+func (us *Int64Set) find(_high uint64) (int, bool) {
+	pos := sort.Search(len(us.highs), func(ii int) bool { return us.highs[ii] >= _high })
+	return pos, pos < len(us.highs) && us.highs[pos] == _high
+}
+
+// Add adds the value, returning false if it was already present
+func (us *Int64Set) Add(_val int64) bool {
+	high, low := int64SetSplit(_val)
+	pos, ok := us.find(high)
+	if !ok {
+		us.highs = append(us.highs, 0)
+		copy(us.highs[pos+1:], us.highs[pos:])
+		us.highs[pos] = high
+		us.chunks = append(us.chunks, nil)
+		copy(us.chunks[pos+1:], us.chunks[pos:])
+		us.chunks[pos] = &int64Chunk{}
+	}
+	if !us.chunks[pos].add(low) {
+		return false
+	}
+	us.card++
+	return true
+}
+
+// Remove removes the value, returning false if it was not present
+func (us *Int64Set) Remove(_val int64) bool {
+	high, low := int64SetSplit(_val)
+	pos, ok := us.find(high)
+	if !ok || !us.chunks[pos].remove(low) {
+		return false
+	}
+	us.card--
+	if us.chunks[pos].card == 0 {
+		us.highs = append(us.highs[:pos], us.highs[pos+1:]...)
+		us.chunks = append(us.chunks[:pos], us.chunks[pos+1:]...)
+	}
+	return true
+}
+
+// Contains tells whether the value is in the set
+func (us *Int64Set) Contains(_val int64) bool {
+	high, low := int64SetSplit(_val)
+	pos, ok := us.find(high)
+	return ok && us.chunks[pos].contains(low)
+}
+
+// Len returns the number of values in the set
+func (us *Int64Set) Len() int64 {
+	return us.card
+}
+
+// ForEach calls _fn with the values in ascending order until it returns false
+func (us *Int64Set) ForEach(_fn func(_val int64) bool) {
+	for ii, chunk := range us.chunks {
+		high := us.highs[ii]
+		if chunk.bitmap == nil {
+			for _, low := range chunk.array {
+				if !_fn(int64SetJoin(high, low)) {
+					return
+				}
+			}
+			continue
+		}
+		for ww, word := range chunk.bitmap {
+			for word != 0 {
+				low := uint16(ww*64 + bits.TrailingZeros64(word))
+				if !_fn(int64SetJoin(high, low)) {
+					return
+				}
+				word &= word - 1
+			}
+		}
+	}
+}
+
+// Slice returns the values in ascending order
+func (us *Int64Set) Slice() []int64 {
+	out := make([]int64, 0, us.card)
+	us.ForEach(func(_val int64) bool {
+		out = append(out, _val)
+		return true
+	})
+	return out
+}
+
+// Union returns a new set with the values in either set
+func (us *Int64Set) Union(_other *Int64Set) *Int64Set {
+	out := &Int64Set{}
+	ii, jj := 0, 0
+	for ii < len(us.highs) || jj < len(_other.highs) {
+		var high uint64
+		var chunk *int64Chunk
+		switch {
+		case jj >= len(_other.highs) || (ii < len(us.highs) && us.highs[ii] < _other.highs[jj]):
+			high, chunk = us.highs[ii], us.chunks[ii].clone()
+			ii++
+		case ii >= len(us.highs) || _other.highs[jj] < us.highs[ii]:
+			high, chunk = _other.highs[jj], _other.chunks[jj].clone()
+			jj++
+		default:
+			high, chunk = us.highs[ii], us.chunks[ii].union(_other.chunks[jj])
+			ii++
+			jj++
+		}
+		out.highs, out.chunks, out.card = append(out.highs, high), append(out.chunks, chunk), out.card+int64(chunk.card)
+	}
+	return out
+}
+
+// Intersect returns a new set with the values in both sets
+func (us *Int64Set) Intersect(_other *Int64Set) *Int64Set {
+	out := &Int64Set{}
+	for ii, jj := 0, 0; ii < len(us.highs) && jj < len(_other.highs); {
+		switch {
+		case us.highs[ii] < _other.highs[jj]:
+			ii++
+		case _other.highs[jj] < us.highs[ii]:
+			jj++
+		default:
+			if chunk := us.chunks[ii].intersect(_other.chunks[jj]); chunk.card > 0 {
+				out.highs, out.chunks, out.card = append(out.highs, us.highs[ii]), append(out.chunks, chunk), out.card+int64(chunk.card)
+			}
+			ii++
+			jj++
+		}
+	}
+	return out
+}
+
+// MarshalBinary encodes the set, chunk by chunk in its compressed form
+func (us *Int64Set) MarshalBinary() ([]byte, error) {
+	out := append([]byte{}, int64SetMagic...)
+	out = binary.LittleEndian.AppendUint64(out, uint64(len(us.chunks)))
+	for ii, chunk := range us.chunks {
+		out = binary.LittleEndian.AppendUint64(out, us.highs[ii])
+		out = binary.LittleEndian.AppendUint32(out, uint32(chunk.card))
+		if chunk.card <= int64ChunkMaxArray { // the reader tells the form by the count
+			if chunk.bitmap != nil {
+				chunk = chunk.clone()
+				chunk.toArray()
+			}
+			for _, low := range chunk.array {
+				out = binary.LittleEndian.AppendUint16(out, low)
+			}
+			continue
+		}
+		for _, word := range chunk.bitmap {
+			out = binary.LittleEndian.AppendUint64(out, word)
+		}
+	}
+	return out, nil
+}
+
+// UnmarshalBinary replaces the set with one encoded by MarshalBinary
+func (us *Int64Set) UnmarshalBinary(_data []byte) error {
+	if len(_data) < len(int64SetMagic)+8 || string(_data[:len(int64SetMagic)]) != int64SetMagic {
+		return fmt.Errorf("genutil.Int64Set.UnmarshalBinary: not an encoded Int64Set")
+	}
+	data := _data[len(int64SetMagic):]
+	nchunks := binary.LittleEndian.Uint64(data)
+	data = data[8:]
+	if nchunks > uint64(len(data)/12) { // each chunk takes at least its 12 byte head
+		return fmt.Errorf("genutil.Int64Set.UnmarshalBinary: chunk count(%d) exceeds the data", nchunks)
+	}
+	*us = Int64Set{}
+	for ; nchunks > 0; nchunks-- {
+		if len(data) < 12 {
+			return fmt.Errorf("genutil.Int64Set.UnmarshalBinary: truncated")
+		}
+		high, card := binary.LittleEndian.Uint64(data), int(binary.LittleEndian.Uint32(data[8:]))
+		data = data[12:]
+		if card < 1 || card > 1<<16 {
+			return fmt.Errorf("genutil.Int64Set.UnmarshalBinary: bad chunk count(%d)", card)
+		}
+		chunk := &int64Chunk{card: card}
+		if card <= int64ChunkMaxArray {
+			if len(data) < 2*card {
+				return fmt.Errorf("genutil.Int64Set.UnmarshalBinary: truncated")
+			}
+			chunk.array = make([]uint16, card)
+			for ii := range chunk.array {
+				chunk.array[ii] = binary.LittleEndian.Uint16(data[2*ii:])
+				if ii > 0 && chunk.array[ii] <= chunk.array[ii-1] {
+					return fmt.Errorf("genutil.Int64Set.UnmarshalBinary: chunk values out of order")
+				}
+			}
+			data = data[2*card:]
+		} else {
+			if len(data) < 8*int64ChunkWords {
+				return fmt.Errorf("genutil.Int64Set.UnmarshalBinary: truncated")
+			}
+			chunk.bitmap = make([]uint64, int64ChunkWords)
+			ones := 0
+			for ii := range chunk.bitmap {
+				chunk.bitmap[ii] = binary.LittleEndian.Uint64(data[8*ii:])
+				ones += bits.OnesCount64(chunk.bitmap[ii])
+			}
+			if ones != card {
+				return fmt.Errorf("genutil.Int64Set.UnmarshalBinary: chunk count(%d) but %d bits set", card, ones)
+			}
+			data = data[8*int64ChunkWords:]
+		}
+		if len(us.highs) > 0 && high <= us.highs[len(us.highs)-1] {
+			return fmt.Errorf("genutil.Int64Set.UnmarshalBinary: chunks out of order")
+		}
+		us.highs, us.chunks, us.card = append(us.highs, high), append(us.chunks, chunk), us.card+int64(card)
+	}
+	return nil
+}
+
+// Save writes the set to the file, gzipped if the name ends in .gz
+func (us *Int64Set) Save(_fname string) error {
+	data, _ := us.MarshalBinary()
+	gzf := OpenGzFile(_fname)
+	defer gzf.Close()
+	if _, err := gzf.Write(data); err != nil {
+		return fmt.Errorf("genutil.Int64Set.Save: %s: %v", _fname, err)
+	}
+	return nil
+}
+
+// LoadInt64Set reads a set written by Save (or available compression variant)
+func LoadInt64Set(_fname string) (*Int64Set, error) {
+	bio, err := OpenAnyReadCloser(_fname)
+	if err != nil {
+		return nil, fmt.Errorf("genutil.LoadInt64Set: %s: %v", _fname, err)
+	}
+	defer bio.Close()
+	data, err := io.ReadAll(bio)
+	if err != nil {
+		return nil, fmt.Errorf("genutil.LoadInt64Set: %s: %v", _fname, err)
+	}
+	us := &Int64Set{}
+	if err := us.UnmarshalBinary(data); err != nil {
+		return nil, fmt.Errorf("genutil.LoadInt64Set: %s: %v", _fname, err)
+	}
+	return us, nil
+}
+
+func (us *int64Chunk) contains(_low uint16) bool {
+	if us.bitmap != nil {
+		return us.bitmap[_low/64]&(1<<(_low%64)) != 0
+	}
+	pos := sort.Search(len(us.array), func(ii int) bool { return us.array[ii] >= _low })
+	return pos < len(us.array) && us.array[pos] == _low
+}
+
+func (us *int64Chunk) add(_low uint16) bool {
+	if us.bitmap != nil {
+		word, bit := &us.bitmap[_low/64], uint64(1)<<(_low%64)
+		if *word&bit != 0 {
+			return false
+		}
+		*word |= bit
+		us.card++
+		return true
+	}
+	pos := sort.Search(len(us.array), func(ii int) bool { return us.array[ii] >= _low })
+	if pos < len(us.array) && us.array[pos] == _low {
+		return false
+	}
+	if len(us.array) == int64ChunkMaxArray {
+		us.toBitmap()
+		return us.add(_low)
+	}
+	us.array = append(us.array, 0)
+	copy(us.array[pos+1:], us.array[pos:])
+	us.array[pos] = _low
+	us.card++
+	return true
+}
+
+func (us *int64Chunk) remove(_low uint16) bool {
+	if us.bitmap != nil {
+		word, bit := &us.bitmap[_low/64], uint64(1)<<(_low%64)
+		if *word&bit == 0 {
+			return false
+		}
+		*word &^= bit
+		us.card--
+		if us.card <= int64ChunkMaxArray/2 {
+			us.toArray()
+		}
+		return true
+	}
+	pos := sort.Search(len(us.array), func(ii int) bool { return us.array[ii] >= _low })
+	if pos == len(us.array) || us.array[pos] != _low {
+		return false
+	}
+	us.array = append(us.array[:pos], us.array[pos+1:]...)
+	us.card--
+	return true
+}
+
+func (us *int64Chunk) toBitmap() {
+	us.bitmap = make([]uint64, int64ChunkWords)
+	for _, low := range us.array {
+		us.bitmap[low/64] |= 1 << (low % 64)
+	}
+	us.array = nil
+}
+
+func (us *int64Chunk) toArray() {
+	us.array = make([]uint16, 0, us.card)
+	for ww, word := range us.bitmap {
+		for word != 0 {
+			us.array = append(us.array, uint16(ww*64+bits.TrailingZeros64(word)))
+			word &= word - 1
+		}
+	}
+	us.bitmap = nil
+}
+
+func (us *int64Chunk) clone() *int64Chunk {
+	return &int64Chunk{array: append([]uint16(nil), us.array...), bitmap: append([]uint64(nil), us.bitmap...), card: us.card}
+}
+
+func (us *int64Chunk) union(_other *int64Chunk) *int64Chunk {
+	if us.bitmap == nil && _other.bitmap == nil && us.card+_other.card <= int64ChunkMaxArray {
+		out := &int64Chunk{array: make([]uint16, 0, us.card+_other.card)}
+		ii, jj := 0, 0
+		for ii < len(us.array) || jj < len(_other.array) {
+			switch {
+			case jj >= len(_other.array) || (ii < len(us.array) && us.array[ii] < _other.array[jj]):
+				out.array = append(out.array, us.array[ii])
+				ii++
+			case ii >= len(us.array) || _other.array[jj] < us.array[ii]:
+				out.array = append(out.array, _other.array[jj])
+				jj++
+			default:
+				out.array = append(out.array, us.array[ii])
+				ii++
+				jj++
+			}
+		}
+		out.card = len(out.array)
+		return out
+	}
+	out := us.clone()
+	if out.bitmap == nil {
+		out.toBitmap()
+	}
+	if _other.bitmap != nil {
+		out.card = 0
+		for ii, word := range _other.bitmap {
+			out.bitmap[ii] |= word
+			out.card += bits.OnesCount64(out.bitmap[ii])
+		}
+	} else {
+		for _, low := range _other.array {
+			out.add(low)
+		}
+	}
+	if out.card <= int64ChunkMaxArray {
+		out.toArray()
+	}
+	return out
+}
+
+func (us *int64Chunk) intersect(_other *int64Chunk) *int64Chunk {
+	out := &int64Chunk{}
+	switch {
+	case us.bitmap != nil && _other.bitmap != nil:
+		out.bitmap = make([]uint64, int64ChunkWords)
+		for ii := range out.bitmap {
+			out.bitmap[ii] = us.bitmap[ii] & _other.bitmap[ii]
+			out.card += bits.OnesCount64(out.bitmap[ii])
+		}
+		if out.card <= int64ChunkMaxArray {
+			out.toArray()
+		}
+	case us.bitmap != nil:
+		return _other.intersect(us)
+	default:
+		for _, low := range us.array {
+			if _other.contains(low) {
+				out.array = append(out.array, low)
+			}
+		}
+		out.card = len(out.array)
+	}
+	return out
+}
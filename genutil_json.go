@@ -0,0 +1,101 @@
+package genutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ReadJSONFile decodes the content of the file (or available compression variant) into _vv
+func ReadJSONFile(_fname string, _vv interface{}) error {
+	bio, err := OpenAnyReadCloser(_fname)
+	if err != nil {
+		return err
+	}
+	defer bio.Close()
+	if err = json.NewDecoder(bio).Decode(_vv); err != nil {
+		return fmt.Errorf("genutil.ReadJSONFile: fname(%s) : %v", _fname, err)
+	}
+	return nil
+}
+
+// WriteJSONFile encodes _vv into the file, gzipped if the name ends in .gz, optionally indented
+func WriteJSONFile(_fname string, _vv interface{}, _indent bool) error {
+	var buf []byte
+	var err error
+	if _indent {
+		buf, err = json.MarshalIndent(_vv, "", "  ")
+	} else {
+		buf, err = json.Marshal(_vv)
+	}
+	if err != nil {
+		return fmt.Errorf("genutil.WriteJSONFile: fname(%s) : %v", _fname, err)
+	}
+	gzf := OpenGzFile(_fname)
+	defer gzf.Close()
+	if _, err = gzf.Write(append(buf, '\n')); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ForEachJSONLine streams a JSON Lines file (or available compression variant), calling _fn for every record.
+// The callback receives the 1-based record number and the raw record, which it may json.Unmarshal as it wishes.
+// Iteration stops at the first error returned by _fn.
+func ForEachJSONLine(_fname string, _fn func(_recno int64, _raw json.RawMessage) error) error {
+	bio, err := OpenAnyReadCloser(_fname)
+	if err != nil {
+		return err
+	}
+	defer bio.Close()
+	dec := json.NewDecoder(bio)
+	recno := int64(0)
+	for {
+		var raw json.RawMessage
+		err = dec.Decode(&raw)
+		if err == io.EOF {
+			return nil
+		}
+		recno++
+		if err != nil {
+			return fmt.Errorf("genutil.ForEachJSONLine: fname(%s) record(%d) : %v", _fname, recno, err)
+		}
+		if err = _fn(recno, raw); err != nil {
+			return err
+		}
+	}
+}
+
+// JSONLinesWriter writes one JSON object per line to a regular or gz file
+type JSONLinesWriter struct {
+	gzf GzFile
+	enc *json.Encoder
+	num int64
+}
+
+// OpenJSONLinesWriter opens a JSON Lines file for writing, gzipped if the name ends in .gz
+func OpenJSONLinesWriter(_fname string) *JSONLinesWriter {
+	us := &JSONLinesWriter{gzf: OpenGzFile(_fname)}
+	us.enc = json.NewEncoder(us.gzf)
+	us.enc.SetEscapeHTML(false)
+	return us
+}
+
+// Write encodes _vv as a single line
+func (us *JSONLinesWriter) Write(_vv interface{}) error {
+	if err := us.enc.Encode(_vv); err != nil {
+		return err
+	}
+	us.num++
+	return nil
+}
+
+// Count returns the number of records written so far
+func (us *JSONLinesWriter) Count() int64 {
+	return us.num
+}
+
+// Close flushes and closes
+func (us *JSONLinesWriter) Close() {
+	us.gzf.Close()
+}
@@ -0,0 +1,96 @@
+package genutil
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// LineStats is what FileLineStats finds in a file
+type LineStats struct {
+	Lines       int64 // all lines, a last line without newline included
+	NonComment  int64 // lines that are neither blank nor comments
+	Blank       int64 // lines of only spaces, tabs or a CR
+	Comment     int64 // non-blank comment lines
+	MaxLen      int   // longest line in bytes, without the newline
+	MaxLenLine  int64 // 1-based number of the first line that long
+	DistinctKey int64 // distinct values of the key column over the non-comment lines, -1 if not asked
+}
+
+// FileLineStats counts the lines of the file (or available compression variant), with '#' lines as comments
+func FileLineStats(_fname string) (LineStats, error) {
+	return FileLineStatsKey(_fname, ",", -1, ParseCommentSpec([]string{"WhitespaceHash"}))
+}
+
+// FileLineStatsKey is FileLineStats with a comment spec and the distinct count of the (0-based) key column
+// of the non-comment lines, -1 for none. Keys are counted by their 64 bit hashes, so memory stays at 8 bytes
+// per key whatever their length; the count may be one short per hash collision, which is vanishingly rare.
+// The separator may be named as in SepMap.
+func FileLineStatsKey(_fname, _sep string, _keyCol int, _comments CommentSpec) (LineStats, error) {
+	sep := []byte(StrAorB(SepMap(_sep, true), _sep))
+	stats := LineStats{DistinctKey: -1}
+	bio, err := OpenAnyReadCloser(_fname)
+	if err != nil {
+		return stats, fmt.Errorf("genutil.FileLineStats: %v", err)
+	}
+	defer bio.Close()
+	var keys map[uint64]struct{}
+	if _keyCol >= 0 {
+		keys = map[uint64]struct{}{}
+	}
+	var long []byte // a line longer than the reader's buffer, put together
+	for {
+		line, err := bio.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			long = append(long, line...)
+			continue
+		}
+		if long != nil {
+			line, long = append(long, line...), nil
+		}
+		if len(line) > 0 {
+			stats.Lines++
+			line = bytes.TrimSuffix(line, []byte("\n"))
+			if len(line) > stats.MaxLen {
+				stats.MaxLen, stats.MaxLenLine = len(line), stats.Lines
+			}
+			switch {
+			case len(bytes.Trim(line, " \t\r")) == 0:
+				stats.Blank++
+			case _comments.IsComment(bytes.TrimSuffix(line, []byte("\r"))):
+				stats.Comment++
+			default:
+				stats.NonComment++
+				if keys != nil {
+					keys[HashString64(string(lineField(line, sep, _keyCol)))] = struct{}{}
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return stats, fmt.Errorf("genutil.FileLineStats: %s: %v", _fname, err)
+		}
+	}
+	if keys != nil {
+		stats.DistinctKey = int64(len(keys))
+	}
+	return stats, nil
+}
+
+// lineField returns the (0-based) field of the line, empty if there are fewer fields
+func lineField(_line, _sep []byte, _col int) []byte {
+	for ii := 0; ii < _col; ii++ {
+		pos := bytes.Index(_line, _sep)
+		if pos < 0 {
+			return nil
+		}
+		_line = _line[pos+len(_sep):]
+	}
+	if pos := bytes.Index(_line, _sep); pos >= 0 {
+		return _line[:pos]
+	}
+	return bytes.TrimSuffix(_line, []byte("\r"))
+}
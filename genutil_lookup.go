@@ -0,0 +1,132 @@
+package genutil
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Lookup maps a key to a value, as loaded by LoadLookup. A lookup returned from the cache is shared, treat it as read-only.
+type Lookup map[string]string
+
+// lookupCacheEntry remembers the file state a cached lookup was loaded from
+type lookupCacheEntry struct {
+	mtime  time.Time
+	size   int64
+	lookup Lookup
+}
+
+var (
+	lookupCache   = map[string]lookupCacheEntry{}
+	lookupCacheMu sync.Mutex
+)
+
+// LoadLookup reads a comma separated file (or available compression variant) into a map from the key column
+// to the value column (both 0-based), see LoadLookupSep
+func LoadLookup(_fname string, _keyCol, _valCol int) (Lookup, error) {
+	return LoadLookupSep(_fname, ",", []int{_keyCol}, []int{_valCol})
+}
+
+// LoadLookupSep reads a delimited file (separator named as in SepMap) into a map whose keys are the key columns
+// and values the value columns, each joined by the separator. Blank and # comment lines are skipped,
+// short lines are an error, and a later line overrides an earlier one with the same key.
+// Lookups are cached by file and columns, and reloaded only when the file's size or mtime changes,
+// so a report loop can call this freely.
+func LoadLookupSep(_fname, _sep string, _keyCols, _valCols []int) (Lookup, error) {
+	sep := StrAorB(SepMap(_sep, true), _sep)
+	if len(_keyCols) == 0 || len(_valCols) == 0 {
+		return nil, fmt.Errorf("genutil.LoadLookup: need key and value columns")
+	}
+	ofname, _, _ := ReadableFilename(_fname)
+	cacheKey := fmt.Sprintf("%s\x00%s\x00%v\x00%v", ofname, sep, _keyCols, _valCols)
+	stat, statErr := os.Stat(ofname)
+	if statErr == nil {
+		lookupCacheMu.Lock()
+		entry, ok := lookupCache[cacheKey]
+		lookupCacheMu.Unlock()
+		if ok && entry.mtime.Equal(stat.ModTime()) && entry.size == stat.Size() {
+			return entry.lookup, nil
+		}
+	}
+	lookup := Lookup{}
+	maxCol := MaxInt(slices.Max(_keyCols), slices.Max(_valCols))
+	lineno := 0
+	err := forEachLine(_fname, func(_line string) error {
+		lineno++
+		if strings.TrimSpace(_line) == "" || IsCommentLine([]byte(_line), []string{"WhitespaceHash"}) {
+			return nil
+		}
+		parts := strings.Split(_line, sep)
+		if len(parts) <= maxCol {
+			return fmt.Errorf("line(%d) has %d fields, want at least %d", lineno, len(parts), maxCol+1)
+		}
+		lookup[lookupKey(parts, _keyCols, sep)] = lookupKey(parts, _valCols, sep)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("genutil.LoadLookup: %s: %v", _fname, err)
+	}
+	if statErr == nil {
+		lookupCacheMu.Lock()
+		lookupCache[cacheKey] = lookupCacheEntry{mtime: stat.ModTime(), size: stat.Size(), lookup: lookup}
+		lookupCacheMu.Unlock()
+	}
+	return lookup, nil
+}
+
+// lookupKey joins the fields at the positions, trimmed of spaces, a missing field as empty
+func lookupKey(_parts []string, _cols []int, _sep string) string {
+	if len(_cols) == 1 {
+		if _cols[0] >= len(_parts) {
+			return "" // a short line, left to the default
+		}
+		return strings.TrimSpace(_parts[_cols[0]])
+	}
+	vals := make([]string, len(_cols))
+	for ii, col := range _cols {
+		if col < len(_parts) {
+			vals[ii] = strings.TrimSpace(_parts[col])
+		}
+	}
+	return strings.Join(vals, _sep)
+}
+
+// EnrichFile copies a comma separated file without header, appending to each line the lookup value for its
+// key column, or _defaultVal when the key is not found
+func EnrichFile(_in, _out string, _lookup Lookup, _keyCol int, _defaultVal string) error {
+	return EnrichFileSep(_in, _out, ",", _lookup, []int{_keyCol}, _defaultVal, "")
+}
+
+// EnrichFileSep is EnrichFile with a separator (named as in SepMap) and a composite key, matching a lookup loaded
+// by LoadLookupSep with the same separator. If _header is not blank the first line is taken as a header and
+// _header is appended to it as the name of the new column. Blank lines are copied as they are.
+func EnrichFileSep(_in, _out, _sep string, _lookup Lookup, _keyCols []int, _defaultVal, _header string) error {
+	sep := StrAorB(SepMap(_sep, true), _sep)
+	gzf := OpenGzFile(_out)
+	defer gzf.Close()
+	first := true
+	err := forEachLine(_in, func(_line string) error {
+		val := _defaultVal
+		switch {
+		case first && _header != "":
+			val = _header
+		case _line == "":
+			_, err := gzf.WriteString("\n")
+			return err
+		default:
+			if found, ok := _lookup[lookupKey(strings.Split(_line, sep), _keyCols, sep)]; ok {
+				val = found
+			}
+		}
+		first = false
+		_, err := gzf.WriteString(_line + sep + val + "\n")
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("genutil.EnrichFile: %s: %v", _in, err)
+	}
+	return nil
+}
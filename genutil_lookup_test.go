@@ -0,0 +1,25 @@
+package genutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnrichFileShortLine(t *testing.T) {
+	dir := t.TempDir()
+	in, out := filepath.Join(dir, "in.csv"), filepath.Join(dir, "out.csv")
+	if err := os.WriteFile(in, []byte("a,b,c\nx\nd,e,f\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := EnrichFile(in, out, Lookup{"c": "C"}, 2, "none"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a,b,c,C\nx,none\nd,e,f,none\n"; string(got) != want {
+		t.Errorf("EnrichFile wrote %q, want %q", got, want)
+	}
+}
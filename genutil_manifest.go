@@ -0,0 +1,121 @@
+package genutil
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var manifestDir = os.TempDir()
+
+// SetManifestDir sets where RunManifest.Finish writes, the default is os.TempDir()
+func SetManifestDir(_dir string) {
+	manifestDir = _dir
+}
+
+// ManifestFile describes one input or output of a run
+type ManifestFile struct {
+	Name    string    `json:"name"`             // as given by the script
+	Path    string    `json:"path"`             // the file actually found, possibly a compression variant
+	Size    int64     `json:"size"`             // -1 if not found
+	ModTime time.Time `json:"mtime"`            // zero if not found
+	SHA256  string    `json:"sha256,omitempty"` // of the bytes on disk, empty for stdin, fifos or missing files
+	Error   string    `json:"error,omitempty"`  // why the file could not be described
+}
+
+// RunManifest records the inputs and outputs of a script run for lineage, and writes them as JSON on Finish.
+// The zero value is ready to use, the methods are safe for concurrent use.
+//
+//	var rm genutil.RunManifest
+//	rm.Start("nightly-positions")
+//	rm.RecordInput(posfile)
+//	...
+//	rm.RecordOutput(outfile) // after the output is closed
+//	mfname, err := rm.Finish("ok")
+type RunManifest struct {
+	Name     string         `json:"name"`
+	Host     string         `json:"host"`
+	Pid      int            `json:"pid"`
+	Args     []string       `json:"args"`
+	Started  time.Time      `json:"start"`
+	Finished time.Time      `json:"finish"`
+	Status   string         `json:"status"`
+	Inputs   []ManifestFile `json:"inputs"`
+	Outputs  []ManifestFile `json:"outputs"`
+	mu       sync.Mutex
+}
+
+// Start starts the run, recording the time, host, pid and command line
+func (us *RunManifest) Start(_name string) {
+	host, _ := os.Hostname()
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	us.Name, us.Host, us.Pid, us.Args = _name, host, os.Getpid(), os.Args
+	us.Started = time.Now()
+	us.Inputs, us.Outputs = []ManifestFile{}, []ManifestFile{}
+}
+
+// RecordInput captures the size, mtime and checksum of an input, resolving compression variants as OpenAny does
+func (us *RunManifest) RecordInput(_fname string) {
+	ofname, _, ofcode := ReadableFilename(_fname)
+	mf := describeManifestFile(_fname, StrTernary(ofcode == 0, _fname, ofname))
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	us.Inputs = append(us.Inputs, mf)
+}
+
+// RecordOutput captures the size, mtime and checksum of an output, so call it once the output is closed
+func (us *RunManifest) RecordOutput(_fname string) {
+	mf := describeManifestFile(_fname, _fname)
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	us.Outputs = append(us.Outputs, mf)
+}
+
+// Finish records the status and writes the manifest as <dir>/<name>.<yyyymmdd_hhmmss>.<pid>.json, returning its path
+func (us *RunManifest) Finish(_status string) (string, error) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	us.Finished, us.Status = time.Now(), _status
+	mfname := filepath.Join(manifestDir, fmt.Sprintf("%s.%s.%d.json", shardKeyName(us.Name), us.Started.Format("20060102_150405"), us.Pid))
+	if err := WriteJSONFile(mfname, us, true); err != nil {
+		return "", err
+	}
+	return mfname, nil
+}
+
+// describeManifestFile stats and checksums the file at _path
+func describeManifestFile(_name, _path string) ManifestFile {
+	mf := ManifestFile{Name: _name, Path: _path, Size: -1}
+	if _name == "-" {
+		mf.Path = "-"
+		return mf
+	}
+	stat, err := os.Stat(_path)
+	if err != nil {
+		mf.Error = err.Error()
+		return mf
+	}
+	mf.Size, mf.ModTime = stat.Size(), stat.ModTime()
+	if !stat.Mode().IsRegular() {
+		return mf
+	}
+	fd, err := os.Open(_path)
+	if err != nil {
+		mf.Error = err.Error()
+		return mf
+	}
+	defer fd.Close()
+	hh := sha256.New()
+	if _, err = io.Copy(hh, fd); err != nil {
+		mf.Error = err.Error()
+		return mf
+	}
+	mf.SHA256 = hex.EncodeToString(hh.Sum(nil))
+	return mf
+}
@@ -0,0 +1,39 @@
+package genutil
+
+// MaskVisible is how many trailing characters MaskFields leaves visible
+var MaskVisible = 4
+
+// MaskAccount replaces every letter and digit of the account number or IBAN with '*' except the last _visible,
+// keeping spaces, dashes and other punctuation so the layout stays recognisable:
+// MaskAccount("GB82 WEST 1234 5698 7654 32", 4) is "**** **** **** **** **54 32".
+// At most half of the letters and digits are left visible, so a short value is not given away whole.
+func MaskAccount(_str string, _visible int) string {
+	alnum := 0
+	for ii := 0; ii < len(_str); ii++ {
+		if isASCIILetter(_str[ii]) || (_str[ii] >= '0' && _str[ii] <= '9') {
+			alnum++
+		}
+	}
+	visible := MinInt(MaxInt(_visible, 0), alnum/2)
+	out := []byte(_str)
+	for ii := 0; ii < len(out) && alnum > visible; ii++ {
+		if isASCIILetter(out[ii]) || (out[ii] >= '0' && out[ii] <= '9') {
+			out[ii] = '*'
+			alnum--
+		}
+	}
+	return string(out)
+}
+
+// MaskFields returns a copy of the fields with those at the positions (0-based) passed through MaskAccount,
+// keeping MaskVisible characters. Positions past the end are ignored.
+func MaskFields(_fields []string, _cols []int) []string {
+	out := make([]string, len(_fields))
+	copy(out, _fields)
+	for _, col := range _cols {
+		if col >= 0 && col < len(out) {
+			out[col] = MaskAccount(out[col], MaskVisible)
+		}
+	}
+	return out
+}
@@ -0,0 +1,47 @@
+package genutil
+
+// LineIterator steps through the lines of a byte buffer, such as one from OpenMmap, using IndexNl.
+// Lines are subslices of the buffer without the newline (a CR before it is kept), so nothing is copied.
+type LineIterator struct {
+	data []byte
+	pos  int
+}
+
+// NewLineIterator returns an iterator at the start of the buffer
+func NewLineIterator(_data []byte) *LineIterator {
+	return &LineIterator{data: _data}
+}
+
+// Next returns the next line, and false at the end of the buffer. A last line without newline is still returned.
+func (us *LineIterator) Next() ([]byte, bool) {
+	if us.pos >= len(us.data) {
+		return nil, false
+	}
+	start := us.pos
+	us.pos = IndexNl(us.data, len(us.data), start)
+	end := us.pos
+	if end > start && us.data[end-1] == '\n' {
+		end--
+	}
+	return us.data[start:end:end], true
+}
+
+// Offset returns the position of the line Next will return
+func (us *LineIterator) Offset() int {
+	return us.pos
+}
+
+// Seek moves to the first line starting at or after the offset, so a reader can jump into the middle of a file,
+// for example to bisect a sorted file, and resume at a line boundary
+func (us *LineIterator) Seek(_offset int) {
+	switch {
+	case _offset <= 0:
+		us.pos = 0
+	case _offset >= len(us.data):
+		us.pos = len(us.data)
+	case us.data[_offset-1] == '\n':
+		us.pos = _offset
+	default:
+		us.pos = IndexNl(us.data, len(us.data), _offset)
+	}
+}
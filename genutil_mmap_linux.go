@@ -0,0 +1,37 @@
+package genutil
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// OpenMmap maps the whole plain (uncompressed) file read-only and returns its bytes and a function that unmaps it.
+// The bytes must not be used after the unmap, and writing to them faults. Reads go straight to the page cache,
+// so a multi-GB reference file can be searched or sliced without copying it through bufio; see LineIterator.
+// An empty file gives an empty slice.
+func OpenMmap(_fname string) ([]byte, func(), error) {
+	fo, err := os.Open(_fname)
+	if err != nil {
+		return nil, nil, fmt.Errorf("genutil.OpenMmap: %v", err)
+	}
+	defer fo.Close()
+	fi, err := fo.Stat()
+	if err != nil {
+		return nil, nil, fmt.Errorf("genutil.OpenMmap: %v", err)
+	}
+	if !fi.Mode().IsRegular() {
+		return nil, nil, fmt.Errorf("genutil.OpenMmap: %s is not a regular file", _fname)
+	}
+	if fi.Size() == 0 {
+		return []byte{}, func() {}, nil
+	}
+	if int64(int(fi.Size())) != fi.Size() {
+		return nil, nil, fmt.Errorf("genutil.OpenMmap: %s is too large to map", _fname)
+	}
+	data, err := syscall.Mmap(int(fo.Fd()), 0, int(fi.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, fmt.Errorf("genutil.OpenMmap: %s: %v", _fname, err)
+	}
+	return data, func() { syscall.Munmap(data) }, nil
+}
@@ -0,0 +1,120 @@
+package genutil
+
+import (
+	"sort"
+	"strings"
+)
+
+// NaturalLess compares strings treating runs of digits as numbers, so that file2.gz sorts before file10.gz.
+// Numbers equal in value but with more leading zeros sort later, other bytes compare as usual.
+func NaturalLess(_aa, _bb string) bool {
+	ia, ib := 0, 0
+	for ia < len(_aa) && ib < len(_bb) {
+		ca, cb := _aa[ia], _bb[ib]
+		if !IsDigit(ca) || !IsDigit(cb) {
+			if ca != cb {
+				return ca < cb
+			}
+			ia++
+			ib++
+			continue
+		}
+		ja, jb := ia, ib
+		for ja < len(_aa) && IsDigit(_aa[ja]) {
+			ja++
+		}
+		for jb < len(_bb) && IsDigit(_bb[jb]) {
+			jb++
+		}
+		numa, numb := strings.TrimLeft(_aa[ia:ja], "0"), strings.TrimLeft(_bb[ib:jb], "0")
+		switch {
+		case len(numa) != len(numb):
+			return len(numa) < len(numb)
+		case numa != numb:
+			return numa < numb
+		case ja-ia != jb-ib:
+			return ja-ia < jb-ib
+		}
+		ia, ib = ja, jb
+	}
+	return len(_aa)-ia < len(_bb)-ib
+}
+
+// SortNatural sorts the slice in place using NaturalLess
+func SortNatural(_strs []string) {
+	sort.SliceStable(_strs, func(i, j int) bool { return NaturalLess(_strs[i], _strs[j]) })
+}
+
+// FileListNatural returns files in dir in natural order, see NaturalLess
+func FileListNatural(_dname string) []string {
+	flist := FileList(_dname)
+	SortNatural(flist)
+	return flist
+}
+
+// CompareVersions returns -1, 0 or 1 as version _aa is older, equal or newer than _bb.
+// A leading v and any +build suffix are ignored, missing components count as 0 (1.2 == 1.2.0),
+// components compare numerically when both are numeric, and a -prerelease suffix sorts before the release
+// with its dot-separated identifiers compared as in semver.
+func CompareVersions(_aa, _bb string) int {
+	corea, prea := versionSplit(_aa)
+	coreb, preb := versionSplit(_bb)
+	partsa, partsb := strings.Split(corea, "."), strings.Split(coreb, ".")
+	for ii := 0; ii < MaxInt(len(partsa), len(partsb)); ii++ {
+		pa, pb := "0", "0"
+		if ii < len(partsa) && partsa[ii] != "" {
+			pa = partsa[ii]
+		}
+		if ii < len(partsb) && partsb[ii] != "" {
+			pb = partsb[ii]
+		}
+		if cc := versionCompareIdent(pa, pb); cc != 0 {
+			return cc
+		}
+	}
+	switch {
+	case prea == preb:
+		return 0
+	case prea == "":
+		return 1
+	case preb == "":
+		return -1
+	}
+	idsa, idsb := strings.Split(prea, "."), strings.Split(preb, ".")
+	for ii := 0; ii < MinInt(len(idsa), len(idsb)); ii++ {
+		if cc := versionCompareIdent(idsa[ii], idsb[ii]); cc != 0 {
+			return cc
+		}
+	}
+	return IntTernary(len(idsa) < len(idsb), -1, IntTernary(len(idsa) > len(idsb), 1, 0))
+}
+
+// versionSplit returns the core version and the prerelease part
+func versionSplit(_ver string) (core, pre string) {
+	_ver = strings.TrimSpace(_ver)
+	if strings.HasPrefix(_ver, "v") || strings.HasPrefix(_ver, "V") {
+		_ver = _ver[1:]
+	}
+	if idx := strings.Index(_ver, "+"); idx >= 0 {
+		_ver = _ver[:idx]
+	}
+	core, pre = SepSplit2(_ver, "-")
+	return
+}
+
+// versionCompareIdent compares numerically if both are numeric, numbers sort before words
+func versionCompareIdent(_aa, _bb string) int {
+	numa, numb := IsPositiveInteger(_aa), IsPositiveInteger(_bb)
+	switch {
+	case numa && numb:
+		_aa, _bb = StrAorB(strings.TrimLeft(_aa, "0"), "0"), StrAorB(strings.TrimLeft(_bb, "0"), "0")
+		if len(_aa) != len(_bb) {
+			return IntTernary(len(_aa) < len(_bb), -1, 1)
+		}
+	case numa:
+		return -1
+	case numb:
+		return 1
+	}
+	return strings.Compare(_aa, _bb)
+}
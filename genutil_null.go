@@ -0,0 +1,86 @@
+package genutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NullPolicy declares which field values mean "no value". Fields are compared with surrounding spaces trimmed.
+type NullPolicy struct {
+	Tokens      []string
+	IgnoreCase  bool
+	Replacement string // what CoalesceFields writes in place of a null, and RecordWriter for a nil pointer field
+}
+
+// DefaultNullPolicy is used by IsNull, and wherever a nil *NullPolicy is passed
+var DefaultNullPolicy = &NullPolicy{Tokens: []string{"", "NA", "N/A", "NULL", SQLNull, "-"}, IgnoreCase: true}
+
+// recordNullPolicy is set by SetRecordNullPolicy
+var recordNullPolicy *NullPolicy
+
+// SetRecordNullPolicy makes UnmarshalRow and ReadRecords treat the policy's null tokens like a blank field:
+// pointer fields are left nil and other fields get their zero value, instead of "NA" failing to parse as a number.
+// RecordWriter then writes nil pointer fields as the policy's Replacement. nil restores the default, where only a
+// blank field is null.
+func SetRecordNullPolicy(_policy *NullPolicy) {
+	recordNullPolicy = _policy
+}
+
+// IsNull tells whether the field is one of the policy's null tokens
+func (us *NullPolicy) IsNull(_field string) bool {
+	if us == nil {
+		us = DefaultNullPolicy
+	}
+	field := strings.TrimSpace(_field)
+	for _, tok := range us.Tokens {
+		if field == tok || (us.IgnoreCase && strings.EqualFold(field, tok)) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNull tells whether the field is null under DefaultNullPolicy
+func IsNull(_field string) bool {
+	return DefaultNullPolicy.IsNull(_field)
+}
+
+// CoalesceFields returns a copy of the fields with every null, under the policy, replaced by the policy's Replacement
+func CoalesceFields(_fields []string, _policy *NullPolicy) []string {
+	if _policy == nil {
+		_policy = DefaultNullPolicy
+	}
+	out := make([]string, len(_fields))
+	for ii, field := range _fields {
+		out[ii] = field
+		if _policy.IsNull(field) {
+			out[ii] = _policy.Replacement
+		}
+	}
+	return out
+}
+
+// ParseIntNull parses the field as an int64, reporting a null under the policy separately from a malformed number,
+// which ToInt would both turn into its default
+func ParseIntNull(_field string, _policy *NullPolicy) (val int64, isNull bool, err error) {
+	if _policy.IsNull(_field) {
+		return 0, true, nil
+	}
+	if val, err = strconv.ParseInt(strings.TrimSpace(_field), 10, 64); err != nil {
+		return 0, false, fmt.Errorf("genutil.ParseIntNull: bad int (%s)", _field)
+	}
+	return val, false, nil
+}
+
+// ParseFloatNull parses the field as a float64, reporting a null under the policy separately from a malformed number,
+// which StrToFloat would both turn into 0
+func ParseFloatNull(_field string, _policy *NullPolicy) (val float64, isNull bool, err error) {
+	if _policy.IsNull(_field) {
+		return 0, true, nil
+	}
+	if val, err = strconv.ParseFloat(strings.TrimSpace(_field), 64); err != nil {
+		return 0, false, fmt.Errorf("genutil.ParseFloatNull: bad float (%s)", _field)
+	}
+	return val, false, nil
+}
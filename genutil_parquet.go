@@ -0,0 +1,525 @@
+package genutil
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ColType is the type of a column written by WriteParquet, named as the Schema* types of ValidateFile
+type ColType string
+
+// Column types understood by WriteParquet
+const (
+	ColString   ColType = SchemaString   // UTF8 byte array
+	ColInt      ColType = SchemaInt      // int64
+	ColFloat    ColType = SchemaFloat    // double
+	ColYYYYMMDD ColType = SchemaYYYYMMDD // date
+	ColBool     ColType = "bool"         // true/false, 1/0, yes/no
+)
+
+// Parquet codecs understood by WriteParquetCodec
+const (
+	ParquetUncompressed = "none"
+	ParquetSnappy       = "snappy"
+	ParquetGzip         = "gzip"
+)
+
+// ParquetRowGroupRows is how many rows WriteParquet holds in memory before writing them out as a row group
+var ParquetRowGroupRows = 100000
+
+// parquet thrift enums, from parquet.thrift
+const (
+	pqBoolean   = 0
+	pqInt32     = 1
+	pqInt64     = 2
+	pqDouble    = 5
+	pqByteArray = 6
+
+	pqConvertedUTF8 = 0
+	pqConvertedDate = 6
+
+	pqEncodingPlain = 0
+	pqEncodingRLE   = 3
+)
+
+var parquetCodecs = map[string]int32{ParquetUncompressed: 0, ParquetSnappy: 1, ParquetGzip: 2}
+
+// WriteParquet writes the rows to a snappy compressed parquet file, see WriteParquetCodec
+func WriteParquet(_fname string, _header []string, _types []ColType, _rows <-chan []string) error {
+	return WriteParquetCodec(_fname, _header, _types, _rows, ParquetSnappy)
+}
+
+// WriteParquetCodec writes the rows to a parquet file with one optional column per header name, typed by _types,
+// compressed with _codec (ParquetUncompressed, ParquetSnappy or ParquetGzip). SQLNull is a null in every column,
+// and so is "" except in ColString columns. Rows are buffered ParquetRowGroupRows at a time into row groups.
+// The file appears under its name only once complete. A bad value or short row is an error naming the row,
+// after which the channel is drained so the producer is not left blocked.
+func WriteParquetCodec(_fname string, _header []string, _types []ColType, _rows <-chan []string, _codec string) (err error) {
+	drain := func() {
+		for range _rows {
+		}
+	}
+	codec, ok := parquetCodecs[strings.ToLower(_codec)]
+	if !ok {
+		drain()
+		return fmt.Errorf("genutil.WriteParquet: unknown codec %s", _codec)
+	}
+	if len(_header) == 0 || len(_header) != len(_types) {
+		drain()
+		return fmt.Errorf("genutil.WriteParquet: %d header names for %d types", len(_header), len(_types))
+	}
+	cols := make([]*parquetColumn, len(_header))
+	for ii, name := range _header {
+		if cols[ii], err = newParquetColumn(name, _types[ii]); err != nil {
+			drain()
+			return fmt.Errorf("genutil.WriteParquet: %v", err)
+		}
+	}
+
+	tmpname := _fname + ".tmp"
+	fo, err := os.Create(tmpname)
+	if err != nil {
+		drain()
+		return fmt.Errorf("genutil.WriteParquet: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			fo.Close()
+			os.Remove(tmpname)
+			drain()
+		}
+	}()
+	pw := &parquetWriter{ww: bufio.NewWriterSize(fo, 1<<20), codec: codec, cols: cols}
+	pw.write([]byte("PAR1"))
+	nrows := int64(0)
+	for row := range _rows {
+		nrows++
+		if len(row) != len(cols) {
+			return fmt.Errorf("genutil.WriteParquet: row %d has %d fields, want %d", nrows, len(row), len(cols))
+		}
+		for ii, col := range cols {
+			if err = col.add(row[ii]); err != nil {
+				return fmt.Errorf("genutil.WriteParquet: row %d: %v", nrows, err)
+			}
+		}
+		if cols[0].numValues >= MaxInt(ParquetRowGroupRows, 1) {
+			pw.flushRowGroup()
+		}
+	}
+	pw.flushRowGroup()
+	pw.writeFooter(nrows)
+	if err = pw.err; err == nil {
+		err = pw.ww.Flush()
+	}
+	if cerr := fo.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmpname, _fname)
+	}
+	if err != nil {
+		os.Remove(tmpname)
+		return fmt.Errorf("genutil.WriteParquet: %s: %v", _fname, err)
+	}
+	auditFile("create", _fname, -1)
+	return nil
+}
+
+// parquetColumn buffers one column of the current row group, plain encoded
+type parquetColumn struct {
+	name      string
+	ctype     ColType
+	ptype     int32
+	converted int32 // -1 for none
+	defs      []byte
+	values    bytes.Buffer
+	bools     []bool
+	numValues int
+}
+
+func newParquetColumn(_name string, _ctype ColType) (*parquetColumn, error) {
+	col := &parquetColumn{name: _name, ctype: _ctype, converted: -1}
+	switch _ctype {
+	case ColString, "":
+		col.ctype, col.ptype, col.converted = ColString, pqByteArray, pqConvertedUTF8
+	case ColInt:
+		col.ptype = pqInt64
+	case ColFloat:
+		col.ptype = pqDouble
+	case ColYYYYMMDD:
+		col.ptype, col.converted = pqInt32, pqConvertedDate
+	case ColBool:
+		col.ptype = pqBoolean
+	default:
+		return nil, fmt.Errorf("column %s: unknown type %s", _name, _ctype)
+	}
+	return col, nil
+}
+
+// add parses the field and appends it
+func (us *parquetColumn) add(_field string) error {
+	us.numValues++
+	if _field == SQLNull || (_field == "" && us.ctype != ColString) {
+		us.defs = append(us.defs, 0)
+		return nil
+	}
+	us.defs = append(us.defs, 1)
+	var buf [8]byte
+	switch us.ctype {
+	case ColString:
+		binary.LittleEndian.PutUint32(buf[:4], uint32(len(_field)))
+		us.values.Write(buf[:4])
+		us.values.WriteString(_field)
+	case ColInt:
+		vv, err := strconv.ParseInt(strings.TrimSpace(_field), 10, 64)
+		if err != nil {
+			return fmt.Errorf("column %s: bad int (%s)", us.name, _field)
+		}
+		binary.LittleEndian.PutUint64(buf[:], uint64(vv))
+		us.values.Write(buf[:])
+	case ColFloat:
+		vv, err := strconv.ParseFloat(strings.TrimSpace(_field), 64)
+		if err != nil {
+			return fmt.Errorf("column %s: bad float (%s)", us.name, _field)
+		}
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(vv))
+		us.values.Write(buf[:])
+	case ColYYYYMMDD:
+		tt, err := time.Parse("20060102", strings.TrimSpace(_field))
+		if err != nil {
+			return fmt.Errorf("column %s: bad yyyymmdd (%s)", us.name, _field)
+		}
+		binary.LittleEndian.PutUint32(buf[:4], uint32(int32(tt.Unix()/86400)))
+		us.values.Write(buf[:4])
+	case ColBool:
+		switch strings.ToLower(strings.TrimSpace(_field)) {
+		case "true", "1", "yes", "y", "t":
+			us.bools = append(us.bools, true)
+		case "false", "0", "no", "n", "f":
+			us.bools = append(us.bools, false)
+		default:
+			return fmt.Errorf("column %s: bad bool (%s)", us.name, _field)
+		}
+	}
+	return nil
+}
+
+// page returns the uncompressed data page: the definition levels, RLE encoded with a length prefix, then the values
+func (us *parquetColumn) page() []byte {
+	levels := []byte{}
+	for ii := 0; ii < len(us.defs); {
+		jj := ii
+		for jj < len(us.defs) && us.defs[jj] == us.defs[ii] {
+			jj++
+		}
+		levels = binary.AppendUvarint(levels, uint64(jj-ii)<<1)
+		levels = append(levels, us.defs[ii])
+		ii = jj
+	}
+	page := binary.LittleEndian.AppendUint32(nil, uint32(len(levels)))
+	page = append(page, levels...)
+	if us.ctype == ColBool {
+		packed := make([]byte, (len(us.bools)+7)/8)
+		for ii, bb := range us.bools {
+			if bb {
+				packed[ii/8] |= 1 << (ii % 8)
+			}
+		}
+		return append(page, packed...)
+	}
+	return append(page, us.values.Bytes()...)
+}
+
+func (us *parquetColumn) reset() {
+	us.defs, us.bools, us.numValues = us.defs[:0], us.bools[:0], 0
+	us.values.Reset()
+}
+
+// parquetChunk is where a column chunk of a row group ended up in the file
+type parquetChunk struct {
+	offset, numValues, uncompressed, compressed int64
+}
+
+type parquetRowGroup struct {
+	numRows int64
+	chunks  []parquetChunk
+}
+
+// parquetWriter tracks the file offset and the row groups written so far, the first error sticks
+type parquetWriter struct {
+	ww        *bufio.Writer
+	pos       int64
+	err       error
+	codec     int32
+	cols      []*parquetColumn
+	rowGroups []parquetRowGroup
+}
+
+func (us *parquetWriter) write(_pp []byte) {
+	if us.err != nil {
+		return
+	}
+	var nn int
+	nn, us.err = us.ww.Write(_pp)
+	us.pos += int64(nn)
+}
+
+// flushRowGroup writes one data page per column and resets the buffers
+func (us *parquetWriter) flushRowGroup() {
+	if us.cols[0].numValues == 0 || us.err != nil {
+		return
+	}
+	rg := parquetRowGroup{numRows: int64(us.cols[0].numValues)}
+	for _, col := range us.cols {
+		page := col.page()
+		body, err := parquetCompress(page, us.codec)
+		if err != nil {
+			us.err = err
+			return
+		}
+		tw := newThriftWriter()
+		tw.i32(1, 0) // DATA_PAGE
+		tw.i32(2, int32(len(page)))
+		tw.i32(3, int32(len(body)))
+		tw.structBegin(5)
+		tw.i32(1, int32(col.numValues))
+		tw.i32(2, pqEncodingPlain)
+		tw.i32(3, pqEncodingRLE)
+		tw.i32(4, pqEncodingRLE)
+		tw.structEnd()
+		header := tw.bytes()
+		chunk := parquetChunk{offset: us.pos, numValues: int64(col.numValues),
+			uncompressed: int64(len(header) + len(page)), compressed: int64(len(header) + len(body))}
+		us.write(header)
+		us.write(body)
+		rg.chunks = append(rg.chunks, chunk)
+		col.reset()
+	}
+	us.rowGroups = append(us.rowGroups, rg)
+}
+
+// writeFooter writes the FileMetaData, its length and the closing magic
+func (us *parquetWriter) writeFooter(_nrows int64) {
+	tw := newThriftWriter()
+	tw.i32(1, 1) // version
+	tw.listBegin(2, thriftStruct, len(us.cols)+1)
+	tw.elemBegin()
+	tw.binary(4, "schema")
+	tw.i32(5, int32(len(us.cols)))
+	tw.structEnd()
+	for _, col := range us.cols {
+		tw.elemBegin()
+		tw.i32(1, col.ptype)
+		tw.i32(3, 1) // OPTIONAL
+		tw.binary(4, col.name)
+		if col.converted >= 0 {
+			tw.i32(6, col.converted)
+		}
+		tw.structEnd()
+	}
+	tw.i64(3, _nrows)
+	tw.listBegin(4, thriftStruct, len(us.rowGroups))
+	for _, rg := range us.rowGroups {
+		tw.elemBegin()
+		total := int64(0)
+		tw.listBegin(1, thriftStruct, len(rg.chunks))
+		for ii, chunk := range rg.chunks {
+			col := us.cols[ii]
+			total += chunk.uncompressed
+			tw.elemBegin()
+			tw.i64(2, chunk.offset)
+			tw.structBegin(3)
+			tw.i32(1, col.ptype)
+			tw.listBegin(2, thriftI32, 2)
+			tw.elemI32(pqEncodingPlain)
+			tw.elemI32(pqEncodingRLE)
+			tw.listBegin(3, thriftBinary, 1)
+			tw.elemBinary(col.name)
+			tw.i32(4, us.codec)
+			tw.i64(5, chunk.numValues)
+			tw.i64(6, chunk.uncompressed)
+			tw.i64(7, chunk.compressed)
+			tw.i64(9, chunk.offset)
+			tw.structEnd()
+			tw.structEnd()
+		}
+		tw.i64(2, total)
+		tw.i64(3, rg.numRows)
+		tw.structEnd()
+	}
+	tw.binary(6, "genutil")
+	meta := tw.bytes()
+	us.write(meta)
+	us.write(binary.LittleEndian.AppendUint32(nil, uint32(len(meta))))
+	us.write([]byte("PAR1"))
+}
+
+// parquetCompress compresses a whole page with the codec
+func parquetCompress(_page []byte, _codec int32) ([]byte, error) {
+	switch _codec {
+	case 1:
+		return snappyEncode(_page), nil
+	case 2:
+		var buf bytes.Buffer
+		gzw := gzip.NewWriter(&buf)
+		if _, err := gzw.Write(_page); err != nil {
+			return nil, err
+		}
+		if err := gzw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return _page, nil
+}
+
+// thrift compact protocol type ids
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes a struct in the thrift compact protocol, which is how parquet serializes its metadata
+type thriftWriter struct {
+	buf  bytes.Buffer
+	last []int16 // last field id of each open struct
+}
+
+func newThriftWriter() *thriftWriter {
+	return &thriftWriter{last: []int16{0}}
+}
+
+func (us *thriftWriter) field(_id int16, _typ byte) {
+	top := len(us.last) - 1
+	if delta := _id - us.last[top]; delta > 0 && delta <= 15 {
+		us.buf.WriteByte(byte(delta)<<4 | _typ)
+	} else {
+		us.buf.WriteByte(_typ)
+		us.uvarint(uint64((int64(_id) << 1) ^ (int64(_id) >> 63)))
+	}
+	us.last[top] = _id
+}
+
+func (us *thriftWriter) uvarint(_vv uint64) {
+	us.buf.Write(binary.AppendUvarint(nil, _vv))
+}
+
+func (us *thriftWriter) i32(_id int16, _vv int32) {
+	us.field(_id, thriftI32)
+	us.elemI32(_vv)
+}
+
+func (us *thriftWriter) i64(_id int16, _vv int64) {
+	us.field(_id, thriftI64)
+	us.uvarint(uint64((_vv << 1) ^ (_vv >> 63)))
+}
+
+func (us *thriftWriter) binary(_id int16, _ss string) {
+	us.field(_id, thriftBinary)
+	us.elemBinary(_ss)
+}
+
+func (us *thriftWriter) structBegin(_id int16) {
+	us.field(_id, thriftStruct)
+	us.elemBegin()
+}
+
+func (us *thriftWriter) structEnd() {
+	us.buf.WriteByte(0)
+	us.last = us.last[:len(us.last)-1]
+}
+
+func (us *thriftWriter) listBegin(_id int16, _elemType byte, _size int) {
+	us.field(_id, thriftList)
+	if _size < 15 {
+		us.buf.WriteByte(byte(_size)<<4 | _elemType)
+		return
+	}
+	us.buf.WriteByte(0xf0 | _elemType)
+	us.uvarint(uint64(_size))
+}
+
+// elemBegin starts a struct inside a list, to be closed by structEnd
+func (us *thriftWriter) elemBegin() {
+	us.last = append(us.last, 0)
+}
+
+func (us *thriftWriter) elemI32(_vv int32) {
+	us.uvarint(uint64(uint32((_vv << 1) ^ (_vv >> 31))))
+}
+
+func (us *thriftWriter) elemBinary(_ss string) {
+	us.uvarint(uint64(len(_ss)))
+	us.buf.WriteString(_ss)
+}
+
+// bytes closes the outer struct and returns the encoding
+func (us *thriftWriter) bytes() []byte {
+	us.structEnd()
+	return us.buf.Bytes()
+}
+
+// snappyEncode compresses to the snappy block format parquet expects, greedily matching 4 byte sequences
+// within 64KB blocks so every copy fits a 2 byte offset
+func snappyEncode(_src []byte) []byte {
+	dst := binary.AppendUvarint(nil, uint64(len(_src)))
+	var table [1 << 14]int32
+	for start := 0; start < len(_src); start += 1 << 16 {
+		block := _src[start:MinInt(start+1<<16, len(_src))]
+		for ii := range table {
+			table[ii] = 0
+		}
+		lit := 0
+		for ii := 0; ii+4 <= len(block); {
+			cur := binary.LittleEndian.Uint32(block[ii:])
+			hh := (cur * 0x1e35a7bd) >> 18
+			cand := int(table[hh]) - 1
+			table[hh] = int32(ii + 1)
+			if cand < 0 || binary.LittleEndian.Uint32(block[cand:]) != cur {
+				ii++
+				continue
+			}
+			dst = snappyLiteral(dst, block[lit:ii])
+			length := 4
+			for ii+length < len(block) && block[cand+length] == block[ii+length] {
+				length++
+			}
+			for offset := ii - cand; length > 0; {
+				nn := MinInt(length, 64)
+				dst = append(dst, byte(nn-1)<<2|2, byte(offset), byte(offset>>8))
+				length -= nn
+				ii += nn
+			}
+			lit = ii
+		}
+		dst = snappyLiteral(dst, block[lit:])
+	}
+	return dst
+}
+
+// snappyLiteral appends a literal element
+func snappyLiteral(_dst, _lit []byte) []byte {
+	nn := len(_lit) - 1
+	switch {
+	case len(_lit) == 0:
+		return _dst
+	case nn < 60:
+		_dst = append(_dst, byte(nn)<<2)
+	case nn < 1<<8:
+		_dst = append(_dst, 60<<2, byte(nn))
+	default:
+		_dst = append(_dst, 61<<2, byte(nn), byte(nn>>8))
+	}
+	return append(_dst, _lit...)
+}
@@ -0,0 +1,48 @@
+package genutil
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// IsPathWithin is true if the path, made absolute and cleaned, is base itself or lies under it.
+// The check is lexical, symlinks inside base are not followed.
+func IsPathWithin(_base, _path string) bool {
+	base, err1 := filepath.Abs(_base)
+	path, err2 := filepath.Abs(_path)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	rel, err := filepath.Rel(base, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// CleanJoin joins the parts onto base as filepath.Join does, but returns an error if the result escapes base,
+// as "../../etc/passwd" from an external file list would, or if a part contains a NUL byte
+func CleanJoin(_base string, _parts ...string) (string, error) {
+	for _, part := range _parts {
+		if strings.ContainsRune(part, 0) {
+			return "", fmt.Errorf("genutil.CleanJoin: part(%q) contains NUL", part)
+		}
+	}
+	path := filepath.Join(append([]string{_base}, _parts...)...)
+	if !IsPathWithin(_base, path) {
+		return "", fmt.Errorf("genutil.CleanJoin: path(%s) escapes base(%s)", path, _base)
+	}
+	return path, nil
+}
+
+var fillDateLeftover = regexp.MustCompile(`\$[A-Za-z]+`)
+
+// FillDatePath is FillDate followed by CleanJoin onto base, and returns an error if the pattern leaves
+// a $ placeholder FillDate does not know (eg $HH) or the result escapes base
+func FillDatePath(_base, _pat string, _ctime time.Time) (string, error) {
+	filled := FillDate(_pat, _ctime)
+	if left := fillDateLeftover.FindString(filled); left != "" {
+		return "", fmt.Errorf("genutil.FillDatePath: unknown placeholder(%s) in pattern(%s)", left, _pat)
+	}
+	return CleanJoin(_base, filled)
+}
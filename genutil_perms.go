@@ -0,0 +1,81 @@
+package genutil
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// MakeDirAll creates the dir and any missing parents with exactly _perm (the umask is overridden by a chmod),
+// leaving existing components untouched. Unlike MakeDirOrDie an existing dir is not an error.
+func MakeDirAll(_path string, _perm os.FileMode) error {
+	_path = filepath.Clean(_path)
+	missing := []string{}
+	for dir := _path; !PathOK(dir); dir = filepath.Dir(dir) {
+		missing = append(missing, dir)
+		if dir == filepath.Dir(dir) {
+			break
+		}
+	}
+	if err := os.MkdirAll(_path, _perm); err != nil {
+		return fmt.Errorf("genutil.MakeDirAll: %v", err)
+	}
+	for ii := len(missing) - 1; ii >= 0; ii-- {
+		if err := os.Chmod(missing[ii], _perm); err != nil {
+			return fmt.Errorf("genutil.MakeDirAll: %v", err)
+		}
+	}
+	if !PathIsDir(_path) {
+		return fmt.Errorf("genutil.MakeDirAll: path(%s) is not a dir", _path)
+	}
+	return nil
+}
+
+// ChmodRecursive sets _fileMode on every file and _dirMode on every dir under (and including) _dir, symlinks are not followed
+func ChmodRecursive(_dir string, _fileMode, _dirMode os.FileMode) error {
+	err := filepath.WalkDir(_dir, func(_path string, _de fs.DirEntry, _err error) error {
+		switch {
+		case _err != nil:
+			return _err
+		case _de.Type()&fs.ModeSymlink != 0:
+			return nil
+		case _de.IsDir():
+			return os.Chmod(_path, _dirMode)
+		}
+		return os.Chmod(_path, _fileMode)
+	})
+	if err != nil {
+		return fmt.Errorf("genutil.ChmodRecursive: %v", err)
+	}
+	return nil
+}
+
+// EnsureGroup changes the group of the path to the named (or numeric) group if it is not already, leaving the owner alone.
+// The caller must be a member of the group, or root.
+func EnsureGroup(_path, _group string) error {
+	grp, err := user.LookupGroup(_group)
+	if err != nil {
+		if grp, err = user.LookupGroupId(_group); err != nil {
+			return fmt.Errorf("genutil.EnsureGroup: unknown group(%s)", _group)
+		}
+	}
+	gid, err := strconv.Atoi(grp.Gid)
+	if err != nil {
+		return fmt.Errorf("genutil.EnsureGroup: bad gid(%s) for group(%s)", grp.Gid, _group)
+	}
+	stat, err := os.Stat(_path)
+	if err != nil {
+		return fmt.Errorf("genutil.EnsureGroup: %v", err)
+	}
+	if st, ok := stat.Sys().(*syscall.Stat_t); ok && int(st.Gid) == gid {
+		return nil
+	}
+	if err = os.Chown(_path, -1, gid); err != nil {
+		return fmt.Errorf("genutil.EnsureGroup: %v", err)
+	}
+	return nil
+}
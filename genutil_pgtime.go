@@ -0,0 +1,123 @@
+package genutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParsePgTimestamp parses timestamps as PostgreSQL prints them, tolerating the variations seen in dumps:
+// a space or T between date and time, 0 to 9 fractional digits, and a zone of Z, +hh, +hhmm, +hh:mm or +hh:mm:ss.
+// A bare date is midnight, and a missing zone is UTC. The result keeps the zone offset of the text.
+//
+//	ParsePgTimestamp("2020-01-09 16:45:07.5-05")
+//	ParsePgTimestamp("2020-01-09T16:45:07.123456+05:30")
+func ParsePgTimestamp(_ss string) (time.Time, error) {
+	ss := strings.TrimSpace(_ss)
+	bad := func(_why string) (time.Time, error) {
+		return time.Time{}, fmt.Errorf("genutil.ParsePgTimestamp: bad timestamp (%s): %s", _ss, _why)
+	}
+	if len(ss) < 10 || ss[4] != '-' || ss[7] != '-' {
+		return bad("want YYYY-MM-DD")
+	}
+	yyyy, err1 := strconv.Atoi(ss[0:4])
+	mo, err2 := strconv.Atoi(ss[5:7])
+	dd, err3 := strconv.Atoi(ss[8:10])
+	if err1 != nil || err2 != nil || err3 != nil {
+		return bad("date is not numeric")
+	}
+	hh, mi, sec, nsec := 0, 0, 0, 0
+	rest := ss[10:]
+	if rest != "" {
+		if rest[0] != ' ' && rest[0] != 'T' {
+			return bad("want space or T after the date")
+		}
+		rest = rest[1:]
+		if len(rest) < 8 || rest[2] != ':' || rest[5] != ':' {
+			return bad("want HH:MM:SS")
+		}
+		var err4, err5, err6 error
+		hh, err4 = strconv.Atoi(rest[0:2])
+		mi, err5 = strconv.Atoi(rest[3:5])
+		sec, err6 = strconv.Atoi(rest[6:8])
+		if err4 != nil || err5 != nil || err6 != nil {
+			return bad("time is not numeric")
+		}
+		rest = rest[8:]
+		if rest != "" && rest[0] == '.' {
+			nd := 1
+			for nd < len(rest) && rest[nd] >= '0' && rest[nd] <= '9' {
+				nd++
+			}
+			frac := rest[1:nd]
+			if frac == "" || len(frac) > 9 {
+				return bad("want 1 to 9 fractional digits")
+			}
+			nsec, _ = strconv.Atoi(frac + strings.Repeat("0", 9-len(frac)))
+			rest = rest[nd:]
+		}
+	}
+	loc, err := pgZone(strings.TrimSpace(rest))
+	if err != nil {
+		return bad(err.Error())
+	}
+	tt := time.Date(yyyy, time.Month(mo), dd, hh, mi, sec, nsec, loc)
+	if tt.Day() != dd || int(tt.Month()) != mo || tt.Hour() != hh || tt.Minute() != mi || tt.Second() != sec {
+		return bad("field out of range")
+	}
+	return tt, nil
+}
+
+// pgZone turns the zone suffix of a pg timestamp into a fixed location
+func pgZone(_zz string) (*time.Location, error) {
+	if _zz == "" || _zz == "Z" || _zz == "+00" || _zz == "UTC" {
+		return time.UTC, nil
+	}
+	if _zz[0] != '+' && _zz[0] != '-' {
+		return nil, fmt.Errorf("bad zone %s", _zz)
+	}
+	digits := strings.ReplaceAll(_zz[1:], ":", "")
+	if len(digits) != 2 && len(digits) != 4 && len(digits) != 6 {
+		return nil, fmt.Errorf("bad zone %s", _zz)
+	}
+	digits += strings.Repeat("0", 6-len(digits))
+	hh, err1 := strconv.Atoi(digits[0:2])
+	mm, err2 := strconv.Atoi(digits[2:4])
+	ss, err3 := strconv.Atoi(digits[4:6])
+	if err1 != nil || err2 != nil || err3 != nil || mm > 59 || ss > 59 {
+		return nil, fmt.Errorf("bad zone %s", _zz)
+	}
+	offset := hh*3600 + mm*60 + ss
+	if _zz[0] == '-' {
+		offset = -offset
+	}
+	return time.FixedZone("", offset), nil
+}
+
+// FormatPgTimestamp writes the time the way PostgreSQL prints a timestamptz, which ParsePgTimestamp reads back:
+// trailing zeros of the fraction dropped, and the offset as +hh, or +hh:mm when not a whole hour
+func FormatPgTimestamp(_tt time.Time) string {
+	_, offset := _tt.Zone()
+	zone := "-07"
+	switch {
+	case offset%60 != 0:
+		zone = "-07:00:00"
+	case offset%3600 != 0:
+		zone = "-07:00"
+	}
+	return _tt.Format("2006-01-02 15:04:05.999999999" + zone)
+}
+
+// PgTimestamp2yyyymmdd_hhmmss parses with ParsePgTimestamp and returns the date, time and milliseconds as ints,
+// in the zone of the text, or converted to _loc if that is not nil
+func PgTimestamp2yyyymmdd_hhmmss(_ss string, _loc *time.Location) (yyyymmdd, hhmmss, mmm int64, err error) {
+	tt, err := ParsePgTimestamp(_ss)
+	if err != nil {
+		return 19010101, -1, -1, err
+	}
+	if _loc != nil {
+		tt = tt.In(_loc)
+	}
+	return int64(Time2YYYYMMDD(tt)), int64(tt.Hour()*10000 + tt.Minute()*100 + tt.Second()), int64(tt.Nanosecond() / 1e6), nil
+}
@@ -0,0 +1,145 @@
+package genutil
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// Pipeline runs commands connected stdout to stdin without going through /bin/bash, so arguments need no quoting:
+//
+//	codes, err := genutil.NewPipeline().Cmd("zcat", fname).Cmd("grep", "-F", pat).Cmd("sort", "-u").RunToFile(out)
+//
+// Each stage's stderr goes to os.Stderr. A stage killed by SIGPIPE, as when a later head exits early, is not a failure.
+// A pipeline may be run again, its commands are started afresh each time; a Stdin reader is not rewound.
+type Pipeline struct {
+	argvs [][]string // name and args of each stage
+	dir   string
+	stdin io.Reader
+	codes []int
+}
+
+// NewPipeline returns an empty pipeline
+func NewPipeline() *Pipeline {
+	return &Pipeline{}
+}
+
+// Cmd appends a stage, the name is looked up in PATH as exec.Command does
+func (us *Pipeline) Cmd(_name string, _args ...string) *Pipeline {
+	us.argvs = append(us.argvs, append([]string{_name}, _args...))
+	return us
+}
+
+// Dir sets the working dir of every stage
+func (us *Pipeline) Dir(_dir string) *Pipeline {
+	us.dir = _dir
+	return us
+}
+
+// Stdin feeds the first stage from the reader, by default it reads nothing
+func (us *Pipeline) Stdin(_rr io.Reader) *Pipeline {
+	us.stdin = _rr
+	return us
+}
+
+// String shows the pipeline shell style, for logging
+func (us *Pipeline) String() string {
+	parts := make([]string, len(us.argvs))
+	for ii, argv := range us.argvs {
+		parts[ii] = strings.Join(argv, " ")
+	}
+	return strings.Join(parts, " | ")
+}
+
+// ExitCodes returns the exit code of each stage of the last run, -1 for a stage killed by a signal or never started
+func (us *Pipeline) ExitCodes() []int {
+	return us.codes
+}
+
+// Run wires the stages, writes the last stage's output to _ww, waits for all of them, and returns
+// an error naming every failed stage with its exit code
+func (us *Pipeline) Run(_ww io.Writer) error {
+	nn := len(us.argvs)
+	if nn == 0 {
+		return fmt.Errorf("genutil.Pipeline: no commands")
+	}
+	us.codes = make([]int, nn)
+	cmds := make([]*exec.Cmd, nn)
+	for ii, argv := range us.argvs {
+		cmds[ii] = exec.Command(argv[0], argv[1:]...)
+	}
+	pipes := []io.ReadCloser{}
+	closePipes := func() {
+		for _, pr := range pipes {
+			pr.Close()
+		}
+	}
+	for ii, cmd := range cmds {
+		us.codes[ii] = -1
+		cmd.Dir, cmd.Stderr = us.dir, os.Stderr
+		if ii == 0 {
+			cmd.Stdin = us.stdin
+		}
+		if ii == nn-1 {
+			cmd.Stdout = _ww
+			continue
+		}
+		pr, err := cmd.StdoutPipe()
+		if err != nil {
+			closePipes()
+			return fmt.Errorf("genutil.Pipeline: stage %d (%s): %v", ii, strings.Join(cmd.Args, " "), err)
+		}
+		cmds[ii+1].Stdin = pr
+		pipes = append(pipes, pr)
+	}
+	for ii, cmd := range cmds {
+		if err := cmd.Start(); err != nil {
+			for _, started := range cmds[:ii] {
+				started.Process.Kill()
+				started.Wait()
+			}
+			closePipes()
+			return fmt.Errorf("genutil.Pipeline: stage %d (%s): %v", ii, strings.Join(cmd.Args, " "), err)
+		}
+	}
+	// the children hold their own copies, ours would keep a writer blocked after its reader exits
+	closePipes()
+	failures := []string{}
+	for ii := nn - 1; ii >= 0; ii-- {
+		err := cmds[ii].Wait()
+		state := cmds[ii].ProcessState
+		if state != nil {
+			us.codes[ii] = state.ExitCode()
+		}
+		if err == nil {
+			continue
+		}
+		if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() && ws.Signal() == syscall.SIGPIPE && ii < nn-1 {
+			continue
+		}
+		failures = append([]string{fmt.Sprintf("stage %d (%s): %v", ii, strings.Join(cmds[ii].Args, " "), err)}, failures...)
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("genutil.Pipeline: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// RunToFile runs the pipeline into the file, compressed if the name ends in .gz, and returns the exit codes
+func (us *Pipeline) RunToFile(_fname string) ([]int, error) {
+	gzf := OpenGzFile(_fname)
+	err := us.Run(gzf)
+	gzf.Close()
+	return us.codes, err
+}
+
+// RunToString runs the pipeline and returns its output
+func (us *Pipeline) RunToString() (string, error) {
+	var buf bytes.Buffer
+	err := us.Run(&buf)
+	return buf.String(), err
+}
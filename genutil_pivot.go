@@ -0,0 +1,158 @@
+package genutil
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Pivot turns long-format rows like (date, symbol, value) into a wide matrix with one row per row key
+// and one column per column key. Row keys keep their first-seen order, column keys are sorted by NaturalLess.
+// The header is "" followed by the column keys, missing cells are "", a repeated (row, col) keeps the last value,
+// and rows too short for the three columns are skipped.
+func Pivot(_rows [][]string, _rowKeyCol, _colKeyCol, _valCol int) (header []string, out [][]string) {
+	need := MaxInt(_rowKeyCol, MaxInt(_colKeyCol, _valCol))
+	rowIdx := map[string]int{}
+	rowKeys := []string{}
+	cells := map[string]map[string]string{}
+	colSeen := map[string]bool{}
+	for _, row := range _rows {
+		if len(row) <= need {
+			continue
+		}
+		rkey, ckey := row[_rowKeyCol], row[_colKeyCol]
+		if _, ok := rowIdx[rkey]; !ok {
+			rowIdx[rkey] = len(rowKeys)
+			rowKeys = append(rowKeys, rkey)
+			cells[rkey] = map[string]string{}
+		}
+		cells[rkey][ckey] = row[_valCol]
+		colSeen[ckey] = true
+	}
+	colKeys := KeysOf(colSeen)
+	sort.SliceStable(colKeys, func(i, j int) bool { return NaturalLess(colKeys[i], colKeys[j]) })
+	header = append([]string{""}, colKeys...)
+	out = make([][]string, len(rowKeys))
+	for ii, rkey := range rowKeys {
+		out[ii] = make([]string, len(colKeys)+1)
+		out[ii][0] = rkey
+		for jj, ckey := range colKeys {
+			out[ii][jj+1] = cells[rkey][ckey]
+		}
+	}
+	return header, out
+}
+
+// PivotFile applies Pivot to a file (or available compression variant) with the separator (named as in SepMap),
+// and writes the matrix to _outfname (compressed if it ends in .gz) with fields quoted by CsvEscape where needed.
+// With _header the first input line is a header, and the name of its row key column heads the first output column.
+func PivotFile(_infname, _outfname, _sep string, _header bool, _rowKeyCol, _colKeyCol, _valCol int) error {
+	sep := StrAorB(SepMap(_sep, true), _sep)
+	rows := [][]string{}
+	corner, needHeader := "", _header
+	err := forEachLine(_infname, func(_line string) error {
+		if needHeader {
+			if parts := strings.Split(_line, sep); _rowKeyCol < len(parts) {
+				corner = parts[_rowKeyCol]
+			}
+			needHeader = false
+			return nil
+		}
+		if _line != "" {
+			rows = append(rows, strings.Split(_line, sep))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("genutil.PivotFile: %v", err)
+	}
+	header, out := Pivot(rows, _rowKeyCol, _colKeyCol, _valCol)
+	header[0] = corner
+	gzf := OpenGzFile(_outfname)
+	defer gzf.Close()
+	for _, row := range append([][]string{header}, out...) {
+		if _, err := gzf.WriteString(CsvJoin(row, sep) + "\n"); err != nil {
+			return fmt.Errorf("genutil.PivotFile: %v", err)
+		}
+	}
+	return nil
+}
+
+// TransposeFile writes the rows of _in as the columns of _out, with the separator (named as in SepMap).
+// The whole file is held in memory, ragged rows are padded with "". Either file may be compressed.
+func TransposeFile(_in, _out, _sep string) error {
+	sep := StrAorB(SepMap(_sep, true), _sep)
+	rows := [][]string{}
+	ncol := 0
+	err := forEachLine(_in, func(_line string) error {
+		parts := strings.Split(_line, sep)
+		ncol = MaxInt(ncol, len(parts))
+		rows = append(rows, parts)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("genutil.TransposeFile: %v", err)
+	}
+	gzf := OpenGzFile(_out)
+	defer gzf.Close()
+	outrow := make([]string, len(rows))
+	for col := 0; col < ncol; col++ {
+		for ii, row := range rows {
+			outrow[ii] = ""
+			if col < len(row) {
+				outrow[ii] = row[col]
+			}
+		}
+		if _, err := gzf.WriteString(strings.Join(outrow, sep) + "\n"); err != nil {
+			return fmt.Errorf("genutil.TransposeFile: %v", err)
+		}
+	}
+	return nil
+}
+
+// ReorderColumns copies a comma separated file with header, keeping only the named columns in the given order
+func ReorderColumns(_in, _out string, _newOrder []string) error {
+	return ReorderColumnsSep(_in, _out, ",", _newOrder)
+}
+
+// ReorderColumnsSep is ReorderColumns with a separator (named as in SepMap). It streams line by line,
+// a name missing from the header is an error, and short rows give "" for the missing columns.
+func ReorderColumnsSep(_in, _out, _sep string, _newOrder []string) error {
+	sep := StrAorB(SepMap(_sep, true), _sep)
+	var gzf GzFile
+	var positions []int
+	isOpen := false
+	defer func() {
+		if isOpen {
+			gzf.Close()
+		}
+	}()
+	outrow := make([]string, len(_newOrder))
+	err := forEachLine(_in, func(_line string) error {
+		parts := strings.Split(_line, sep)
+		if positions == nil {
+			header := HeaderIndex(parts)
+			positions = make([]int, len(_newOrder))
+			for ii, name := range _newOrder {
+				pos, ok := header[strings.TrimSpace(name)]
+				if !ok {
+					return fmt.Errorf("column(%s) not in header of %s", name, _in)
+				}
+				positions[ii] = pos
+			}
+			gzf, isOpen = OpenGzFile(_out), true
+		}
+		for ii, pos := range positions {
+			outrow[ii] = ""
+			if pos < len(parts) {
+				outrow[ii] = parts[pos]
+			}
+		}
+		_, err := gzf.WriteString(strings.Join(outrow, sep) + "\n")
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("genutil.ReorderColumns: %v", err)
+	}
+	return nil
+}
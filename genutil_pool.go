@@ -0,0 +1,42 @@
+package genutil
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// bufferPoolMaxCap keeps PutBuffer from pooling the odd huge buffer, which would then pin its memory
+const bufferPoolMaxCap = 1 << 20
+
+var bufferPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// GetBuffer returns an empty buffer from the package pool, give it back with PutBuffer when done
+func GetBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+// PutBuffer resets the buffer and returns it to the pool. The buffer, and any slice from its Bytes,
+// must not be used afterwards.
+func PutBuffer(_buf *bytes.Buffer) {
+	if _buf == nil || _buf.Cap() > bufferPoolMaxCap {
+		return
+	}
+	_buf.Reset()
+	bufferPool.Put(_buf)
+}
+
+// WriteJoinedLine writes the fields joined by the separator and a newline in one Write, formatting in a pooled buffer
+// instead of building the line as a string first
+func WriteJoinedLine(_ww io.Writer, _fields []string, _sep string) (int, error) {
+	buf := GetBuffer()
+	defer PutBuffer(buf)
+	for ii, field := range _fields {
+		if ii > 0 {
+			buf.WriteString(_sep)
+		}
+		buf.WriteString(field)
+	}
+	buf.WriteByte('\n')
+	return _ww.Write(buf.Bytes())
+}
@@ -0,0 +1,125 @@
+package genutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ProcessedSet remembers the files an ingestion script has handled, with their checksum, size and mtime,
+// in a JSON file rewritten atomically on every change. A file is new until marked done, and again if its content changes,
+// so re-delivered vendor files with identical content are skipped while corrected ones are picked up.
+type ProcessedSet struct {
+	fname   string
+	entries map[string]ProcessedFile
+	mu      sync.Mutex
+}
+
+// ProcessedFile is what a ProcessedSet stores per file
+type ProcessedFile struct {
+	SHA256    string    `json:"sha256"`
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"mtime"`
+	Processed time.Time `json:"processed"`
+}
+
+// OpenProcessedSet loads the state file if it exists, or starts empty
+func OpenProcessedSet(_fname string) (*ProcessedSet, error) {
+	ps := &ProcessedSet{fname: _fname, entries: map[string]ProcessedFile{}}
+	buf, err := os.ReadFile(_fname)
+	switch {
+	case os.IsNotExist(err):
+		return ps, nil
+	case err != nil:
+		return nil, fmt.Errorf("genutil.OpenProcessedSet: %v", err)
+	}
+	if err = json.Unmarshal(buf, &ps.entries); err != nil {
+		return nil, fmt.Errorf("genutil.OpenProcessedSet: fname(%s) : %v", _fname, err)
+	}
+	return ps, nil
+}
+
+// processedKey is the absolute path, so relative and absolute names of a file agree
+func processedKey(_fname string) string {
+	if abs, err := filepath.Abs(_fname); err == nil {
+		return abs
+	}
+	return _fname
+}
+
+// IsNew is true if the file has not been marked done, or its content has changed since.
+// An unchanged size and mtime is trusted without rehashing.
+func (us *ProcessedSet) IsNew(_fname string) (bool, error) {
+	key := processedKey(_fname)
+	us.mu.Lock()
+	old, ok := us.entries[key]
+	us.mu.Unlock()
+	if !ok {
+		return true, nil
+	}
+	stat, err := os.Stat(_fname)
+	if err != nil {
+		return false, fmt.Errorf("genutil.ProcessedSet.IsNew: %v", err)
+	}
+	if stat.Size() == old.Size && stat.ModTime().Equal(old.ModTime) {
+		return false, nil
+	}
+	mf := describeManifestFile(_fname, _fname)
+	if mf.Error != "" {
+		return false, fmt.Errorf("genutil.ProcessedSet.IsNew: %s", mf.Error)
+	}
+	return mf.SHA256 != old.SHA256, nil
+}
+
+// MarkDone records the file's current checksum, size and mtime, and saves the set
+func (us *ProcessedSet) MarkDone(_fname string) error {
+	mf := describeManifestFile(_fname, _fname)
+	if mf.Error != "" {
+		return fmt.Errorf("genutil.ProcessedSet.MarkDone: %s", mf.Error)
+	}
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	us.entries[processedKey(_fname)] = ProcessedFile{SHA256: mf.SHA256, Size: mf.Size, ModTime: mf.ModTime, Processed: time.Now()}
+	return us.save()
+}
+
+// PruneMissing forgets files that no longer exist, saves the set, and returns the names forgotten
+func (us *ProcessedSet) PruneMissing() ([]string, error) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	pruned := []string{}
+	for key := range us.entries {
+		if !PathOK(key) {
+			pruned = append(pruned, key)
+			delete(us.entries, key)
+		}
+	}
+	sort.Strings(pruned)
+	if len(pruned) == 0 {
+		return pruned, nil
+	}
+	return pruned, us.save()
+}
+
+// Files returns the sorted names in the set
+func (us *ProcessedSet) Files() []string {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	return SortedKeys(us.entries)
+}
+
+// save writes the entries with writeFileAtomic
+func (us *ProcessedSet) save() error {
+	buf, err := json.MarshalIndent(us.entries, "", "  ")
+	if err == nil {
+		err = writeFileAtomic(us.fname, buf)
+	}
+	if err != nil {
+		return fmt.Errorf("genutil.ProcessedSet: fname(%s) : %v", us.fname, err)
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package genutil
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// profileExamples is how many distinct example values ProfileFile keeps per column
+const profileExamples = 3
+
+// ColumnProfile is what ProfileFile found in one column
+type ColumnProfile struct {
+	Name     string
+	Type     string   // the narrowest of SchemaYYYYMMDD, SchemaInt, SchemaFloat, SchemaString fitting every non-null value
+	Values   int64    // rows with the column, null or not
+	Nulls    int64    // values null under DefaultNullPolicy, and rows too short to have the column
+	Min      string   // smallest non-null value in the column's type order, as written
+	Max      string   // largest
+	Distinct int64    // distinct non-null values, exact up to 1024 and then estimated within a few percent
+	Examples []string // the first few distinct non-null values
+}
+
+// NullRate is the fraction of rows in which the column is null or missing
+func (us ColumnProfile) NullRate() float64 {
+	if us.Values == 0 {
+		return 0
+	}
+	return float64(us.Nulls) / float64(us.Values)
+}
+
+// Profile is the outcome of ProfileFile
+type Profile struct {
+	Fname   string
+	Sep     string
+	Rows    int64 // data rows read
+	Ragged  int64 // rows whose field count differs from the header's
+	Columns []ColumnProfile
+}
+
+// Table gives one row per column, for Table.Render or RenderMarkdown
+func (us Profile) Table() *Table {
+	tbl := NewTable("column", "type", "null%", "min", "max", "distinct", "examples")
+	for _, col := range us.Columns {
+		tbl.AddRow(col.Name, col.Type, strconv.FormatFloat(100*col.NullRate(), 'f', 1, 64), col.Min, col.Max, col.Distinct, strings.Join(col.Examples, " | "))
+	}
+	return tbl
+}
+
+// Render writes a summary line and the column table
+func (us Profile) Render(_ww io.Writer) error {
+	if _, err := fmt.Fprintf(_ww, "%s: %d rows, %d columns, %d ragged rows\n", us.Fname, us.Rows, len(us.Columns), us.Ragged); err != nil {
+		return err
+	}
+	return us.Table().Render(_ww)
+}
+
+// columnProfiler accumulates one column
+type columnProfiler struct {
+	prof                   ColumnProfile
+	isDate, isInt, isFloat bool
+	minNum, maxNum         float64
+	minNumStr, maxNumStr   string
+	minStr, maxStr         string
+	minDate, maxDate       string
+	minDateStr, maxDateStr string
+	seenValue              bool
+	hll                    hyperLogLog
+}
+
+func (us *columnProfiler) add(_field string) {
+	us.prof.Values++
+	if IsNull(_field) {
+		us.prof.Nulls++
+		return
+	}
+	field := strings.TrimSpace(_field)
+	us.hll.add(HashString64(field))
+	if len(us.prof.Examples) < profileExamples && !slices.Contains(us.prof.Examples, field) {
+		us.prof.Examples = append(us.prof.Examples, field)
+	}
+	first := !us.seenValue
+	us.seenValue = true
+	if first || field < us.minStr {
+		us.minStr = field
+	}
+	if first || field > us.maxStr {
+		us.maxStr = field
+	}
+	if us.isDate {
+		if dt := filterDate(field); dt == "" {
+			us.isDate = false
+		} else {
+			if first || dt < us.minDate {
+				us.minDate, us.minDateStr = dt, field
+			}
+			if first || dt > us.maxDate {
+				us.maxDate, us.maxDateStr = dt, field
+			}
+		}
+	}
+	if us.isInt {
+		if _, err := strconv.ParseInt(field, 10, 64); err != nil {
+			us.isInt = false
+		}
+	}
+	if us.isFloat {
+		if num, err := strconv.ParseFloat(field, 64); err != nil || math.IsNaN(num) {
+			us.isFloat = false
+		} else {
+			if first || num < us.minNum {
+				us.minNum, us.minNumStr = num, field
+			}
+			if first || num > us.maxNum {
+				us.maxNum, us.maxNumStr = num, field
+			}
+		}
+	}
+}
+
+func (us *columnProfiler) finish() ColumnProfile {
+	prof := us.prof
+	prof.Distinct = us.hll.estimate()
+	switch {
+	case !us.seenValue:
+		prof.Type = SchemaString
+	case us.isDate:
+		prof.Type, prof.Min, prof.Max = SchemaYYYYMMDD, us.minDateStr, us.maxDateStr
+	case us.isInt:
+		prof.Type, prof.Min, prof.Max = SchemaInt, us.minNumStr, us.maxNumStr
+	case us.isFloat:
+		prof.Type, prof.Min, prof.Max = SchemaFloat, us.minNumStr, us.maxNumStr
+	default:
+		prof.Type, prof.Min, prof.Max = SchemaString, us.minStr, us.maxStr
+	}
+	return prof
+}
+
+// ProfileFile reads the first sampleRows data rows (all of them if 0) of a delimited file with a header line,
+// skipping blank and '#' lines, and reports per column its inferred type, null rate, min and max, distinct count
+// and example values: the first look at a new vendor file. The separator may be named as in SepMap, blank sniffs it
+// with SniffDelimiter.
+//
+//	prof, err := genutil.ProfileFile("vendor.csv.gz", "", 100000)
+//	prof.Render(os.Stdout)
+func ProfileFile(_fname, _sep string, _sampleRows int) (Profile, error) {
+	prof := Profile{Fname: _fname}
+	sep := StrAorB(SepMap(_sep, true), _sep)
+	if sep == "" {
+		var err error
+		if sep, _, err = SniffDelimiter(_fname, nil); err != nil {
+			return prof, fmt.Errorf("genutil.ProfileFile: %v", err)
+		}
+	}
+	var cols []*columnProfiler
+	err := forEachLine(_fname, func(_line string) error {
+		if strings.TrimSpace(_line) == "" || IsCommentLine([]byte(_line), []string{"WhitespaceHash"}) {
+			return nil
+		}
+		if cols == nil {
+			for _, name := range strings.Split(_line, sep) {
+				cols = append(cols, &columnProfiler{prof: ColumnProfile{Name: strings.TrimSpace(name)}, isDate: true, isInt: true, isFloat: true})
+			}
+			return nil
+		}
+		if _sampleRows > 0 && prof.Rows >= int64(_sampleRows) {
+			return errRangeDone
+		}
+		prof.Rows++
+		fields := strings.Split(_line, sep)
+		if len(fields) != len(cols) {
+			prof.Ragged++
+		}
+		for ii, col := range cols {
+			if ii < len(fields) {
+				col.add(fields[ii])
+			} else {
+				col.prof.Values++
+				col.prof.Nulls++
+			}
+		}
+		return nil
+	})
+	if err != nil && err != errRangeDone {
+		return prof, fmt.Errorf("genutil.ProfileFile: %s: %v", _fname, err)
+	}
+	if cols == nil {
+		return prof, fmt.Errorf("genutil.ProfileFile: %s: no header line", _fname)
+	}
+	prof.Sep = sep
+	for _, col := range cols {
+		prof.Columns = append(prof.Columns, col.finish())
+	}
+	return prof, nil
+}
+
+// hyperLogLogP sets 2^p registers, giving a standard error of about 1.6% in 4KB
+const hyperLogLogP = 12
+
+// hyperLogLogExact is how many distinct hashes are counted exactly before relying on the registers
+const hyperLogLogExact = 1024
+
+// hyperLogLog estimates the number of distinct 64 bit hashes added to it, exactly while they are few
+type hyperLogLog struct {
+	registers []uint8
+	exact     map[uint64]struct{} // nil once more than hyperLogLogExact
+}
+
+func (us *hyperLogLog) add(_hash uint64) {
+	if us.registers == nil {
+		us.registers = make([]uint8, 1<<hyperLogLogP)
+		us.exact = map[uint64]struct{}{}
+	}
+	if us.exact != nil {
+		us.exact[_hash] = struct{}{}
+		if len(us.exact) > hyperLogLogExact {
+			us.exact = nil
+		}
+	}
+	idx := _hash >> (64 - hyperLogLogP)
+	rank := uint8(bits.LeadingZeros64(_hash<<hyperLogLogP|1<<(hyperLogLogP-1))) + 1
+	if rank > us.registers[idx] {
+		us.registers[idx] = rank
+	}
+}
+
+func (us *hyperLogLog) estimate() int64 {
+	if us.registers == nil {
+		return 0
+	}
+	if us.exact != nil {
+		return int64(len(us.exact))
+	}
+	mm := float64(len(us.registers))
+	sum, zeros := 0.0, 0
+	for _, reg := range us.registers {
+		sum += math.Ldexp(1, -int(reg))
+		if reg == 0 {
+			zeros++
+		}
+	}
+	est := 0.7213 / (1 + 1.079/mm) * mm * mm / sum
+	if est <= 2.5*mm && zeros > 0 {
+		est = mm * math.Log(mm/float64(zeros)) // linear counting, better for small counts
+	}
+	return int64(math.Round(est))
+}
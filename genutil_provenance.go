@@ -0,0 +1,61 @@
+package genutil
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// WriteFileWithHeader writes the file (compressed if the name ends in .gz) as a commented provenance header followed by
+// whatever rows writes. The header gives the generating command, host and time, then the header lines:
+//
+//	# generator: pxload -date 20240105
+//	# host: box1
+//	# date: 2024-01-05T10:00:00Z
+//	# input: /data/px.20240105.csv.gz size=123456 sha256=9f86d0...
+//
+// Every header line starts with '#', so readers skip them with IsCommentLine and the "WhitespaceHash" tag.
+// If rows returns an error the partial file is removed and the error returned.
+func WriteFileWithHeader(_fname string, _headerLines []string, _rows func(_ww io.Writer) error) error {
+	host, _ := os.Hostname()
+	lines := []string{
+		"generator: " + strings.Join(append([]string{filepath.Base(os.Args[0])}, os.Args[1:]...), " "),
+		"host: " + host,
+		"date: " + time.Now().UTC().Format(time.RFC3339),
+	}
+	for _, line := range _headerLines {
+		lines = append(lines, strings.Split(strings.TrimRight(line, "\r\n"), "\n")...)
+	}
+	gzf := OpenGzFile(_fname)
+	abort := func(_err error) error {
+		gzf.Close()
+		if _fname != "-" {
+			auditedRemove(_fname)
+		}
+		return fmt.Errorf("genutil.WriteFileWithHeader: %s: %v", _fname, _err)
+	}
+	for _, line := range lines {
+		if _, err := gzf.WriteString("# " + strings.TrimRight(line, "\r") + "\n"); err != nil {
+			return abort(err)
+		}
+	}
+	if err := _rows(gzf); err != nil {
+		return abort(err)
+	}
+	gzf.Close()
+	return nil
+}
+
+// ProvenanceInput is a header line for WriteFileWithHeader describing an input by its size and checksum,
+// resolving compression variants as OpenAny does
+func ProvenanceInput(_fname string) string {
+	ofname, _, ofcode := ReadableFilename(_fname)
+	mf := describeManifestFile(_fname, StrTernary(ofcode == 0, _fname, ofname))
+	if mf.Error != "" {
+		return fmt.Sprintf("input: %s error=%s", mf.Path, mf.Error)
+	}
+	return fmt.Sprintf("input: %s size=%d sha256=%s", mf.Path, mf.Size, mf.SHA256)
+}
@@ -0,0 +1,68 @@
+package genutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxRangeSpan guards against a typo like 1-10000000000 allocating the world
+const maxRangeSpan = 10000000
+
+// ExpandRanges expands a list like "1-5,8,10-12" into [1 2 3 4 5 8 10 11 12].
+// Space around items is ignored, empty items are skipped, and duplicates keep their first position.
+func ExpandRanges(_spec string) ([]int, error) {
+	out := []int{}
+	for _, item := range strings.Split(_spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		begstr, endstr := SepSplit2(item, "-")
+		beg, err := strconv.Atoi(strings.TrimSpace(begstr))
+		if err != nil {
+			return nil, fmt.Errorf("ExpandRanges: bad item(%s) in spec(%s)", item, _spec)
+		}
+		end := beg
+		if strings.Contains(item, "-") {
+			if end, err = strconv.Atoi(strings.TrimSpace(endstr)); err != nil {
+				return nil, fmt.Errorf("ExpandRanges: bad item(%s) in spec(%s)", item, _spec)
+			}
+		}
+		switch {
+		case end < beg:
+			return nil, fmt.Errorf("ExpandRanges: descending range(%s) in spec(%s)", item, _spec)
+		case end-beg >= maxRangeSpan:
+			return nil, fmt.Errorf("ExpandRanges: range(%s) too large in spec(%s)", item, _spec)
+		}
+		for ii := beg; ii <= end; ii++ {
+			out = append(out, ii)
+		}
+	}
+	return UniqueSlice(out), nil
+}
+
+// ExpandDateRanges expands a list like "20240101-20240103,20240110" into calendar dates
+// [20240101 20240102 20240103 20240110], with the same rules as ExpandRanges
+func ExpandDateRanges(_spec string) ([]string, error) {
+	out := []string{}
+	for _, item := range strings.Split(_spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		beg, end := SepSplit2(item, "-")
+		beg, end = strings.TrimSpace(beg), strings.TrimSpace(end)
+		if !strings.Contains(item, "-") {
+			end = beg
+		}
+		if !IsYYYYMMDD(beg) || !IsYYYYMMDD(end) {
+			return nil, fmt.Errorf("ExpandDateRanges: bad item(%s) in spec(%s)", item, _spec)
+		}
+		if !StryyyymmddLTEQ(beg, end) {
+			return nil, fmt.Errorf("ExpandDateRanges: descending range(%s) in spec(%s)", item, _spec)
+		}
+		out = append(out, CalDatelist(beg, end, true, true)...)
+	}
+	return UniqueSlice(out), nil
+}
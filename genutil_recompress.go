@@ -0,0 +1,140 @@
+package genutil
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// recompressCodecs maps a target codec to its command and suffix
+var recompressCodecs = map[string][2]string{
+	"xz":  {"xz", ".xz"},
+	"zst": {"zstd", ".zst"},
+}
+
+// RecompressOld walks the dir and converts plain and .gz files last modified more than _olderThanDays ago
+// to the target codec ("xz" or "zst", using the command of that name) for archival. The compressed copy
+// keeps the original mtime, and is decompressed and checksummed against the original content before the
+// original is discarded through PathDiscardOrPanic. Files already compressed otherwise, or whose target exists, are skipped.
+// Note that OpenAny reads .xz but not .zst. It returns the files written.
+func RecompressOld(_dir string, _olderThanDays int, _targetCodec string) ([]string, error) {
+	codec, ok := recompressCodecs[_targetCodec]
+	if !ok {
+		return nil, fmt.Errorf("genutil.RecompressOld: unknown codec(%s)", _targetCodec)
+	}
+	cmdpath, err := exec.LookPath(codec[0])
+	if err != nil {
+		return nil, fmt.Errorf("genutil.RecompressOld: %v", err)
+	}
+	cutoff := time.Now().AddDate(0, 0, -_olderThanDays)
+	written := []string{}
+	err = filepath.WalkDir(_dir, func(_path string, _de fs.DirEntry, _err error) error {
+		if _err != nil {
+			return _err
+		}
+		if !_de.Type().IsRegular() || (IsCompressedName(_path) && !strings.HasSuffix(_path, ".gz")) {
+			return nil
+		}
+		fi, err := _de.Info()
+		if err != nil || !fi.ModTime().Before(cutoff) {
+			return err
+		}
+		target := strings.TrimSuffix(_path, ".gz") + codec[1]
+		if PathOK(target) {
+			return nil
+		}
+		if err = recompressFile(_path, target, cmdpath, fi.ModTime()); err != nil {
+			return err
+		}
+		written = append(written, target)
+		return nil
+	})
+	if err != nil {
+		return written, fmt.Errorf("genutil.RecompressOld: %v", err)
+	}
+	return written, nil
+}
+
+// IsCompressedName is true if the name ends in a compression suffix genutil knows
+func IsCompressedName(_fname string) bool {
+	for _, ext := range []string{".xz", ".gz", ".bz2", ".zip", ".ZIP", ".zst"} {
+		if strings.HasSuffix(_fname, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// recompressFile streams the (gunzipped) source through the command into target, verifies, and discards the source
+func recompressFile(_src, _target, _cmdpath string, _mtime time.Time) error {
+	fi, err := os.Open(_src)
+	if err != nil {
+		return err
+	}
+	defer fi.Close()
+	var rr io.Reader = fi
+	if strings.HasSuffix(_src, ".gz") {
+		gzr, err := gzip.NewReader(fi)
+		if err != nil {
+			return fmt.Errorf("%s: %v", _src, err)
+		}
+		defer gzr.Close()
+		rr = gzr
+	}
+	tmpname := _target + ".tmp"
+	fo, err := os.Create(tmpname)
+	if err != nil {
+		return err
+	}
+	hsrc := sha256.New()
+	cmd := exec.Command(_cmdpath, "-c", "-q")
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = io.TeeReader(rr, hsrc), fo, os.Stderr
+	err = cmd.Run()
+	if cerr := fo.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = recompressVerify(tmpname, _cmdpath, hex.EncodeToString(hsrc.Sum(nil)))
+	}
+	if err == nil {
+		err = os.Chtimes(tmpname, _mtime, _mtime)
+	}
+	if err == nil {
+		err = auditedRename(tmpname, _target)
+	}
+	if err != nil {
+		auditedRemove(tmpname)
+		return fmt.Errorf("%s: %v", _src, err)
+	}
+	PathDiscardOrPanic(_src)
+	return nil
+}
+
+// recompressVerify decompresses the file and compares the checksum of its content
+func recompressVerify(_fname, _cmdpath, _sum string) error {
+	cmd := exec.Command(_cmdpath, "-d", "-c", "-q", _fname)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+	hh := sha256.New()
+	_, cerr := io.Copy(hh, out)
+	if err = cmd.Wait(); err == nil {
+		err = cerr
+	}
+	if err == nil && hex.EncodeToString(hh.Sum(nil)) != _sum {
+		err = fmt.Errorf("checksum mismatch after recompression to %s", _fname)
+	}
+	return err
+}
@@ -0,0 +1,415 @@
+package genutil
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recordField describes one struct field bound to a column via the tags `col:"Price" fmt:"float"`
+type recordField struct {
+	index   []int
+	col     string
+	fmt     string
+	mask    bool // written through MaskAccount, see RecordWriter.MaskColumns
+	visible int
+}
+
+// recordFields returns the bindable fields of a struct type, in declaration order.
+// Untagged fields bind to the column of the same name, `col:"-"` skips the field.
+func recordFields(_typ reflect.Type) []recordField {
+	fields := []recordField{}
+	for ii := 0; ii < _typ.NumField(); ii++ {
+		sf := _typ.Field(ii)
+		if sf.PkgPath != "" {
+			continue // unexported
+		}
+		col := sf.Tag.Get("col")
+		if col == "-" {
+			continue
+		}
+		if col == "" {
+			col = sf.Name
+		}
+		fields = append(fields, recordField{index: sf.Index, col: col, fmt: sf.Tag.Get("fmt")})
+	}
+	return fields
+}
+
+// HeaderIndex maps each column name of a header line to its position
+func HeaderIndex(_fields []string) map[string]int {
+	header := make(map[string]int, len(_fields))
+	for ii, name := range _fields {
+		header[strings.TrimSpace(name)] = ii
+	}
+	return header
+}
+
+// UnmarshalRow sets the fields of the struct pointed to by _dst from the row, using the `col` and `fmt` tags.
+// If _header is nil, fields bind to columns by declaration order. Columns missing from the header are left untouched.
+// Supported fmt hints are float (for int fields), yyyymmdd, or a time layout for time.Time fields, printf verbs are ignored.
+// Pointer fields are left nil for a blank field, or a null token once SetRecordNullPolicy is in effect.
+func UnmarshalRow(_fields []string, _header map[string]int, _dst interface{}) error {
+	pv := reflect.ValueOf(_dst)
+	if pv.Kind() != reflect.Ptr || pv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("genutil.UnmarshalRow: dst must be pointer to struct, got %T", _dst)
+	}
+	return unmarshalRowFields(_fields, _header, recordFields(pv.Elem().Type()), pv.Elem())
+}
+
+// unmarshalRowFields is UnmarshalRow with the field list computed once by the caller
+func unmarshalRowFields(_fields []string, _header map[string]int, _rfs []recordField, _sv reflect.Value) error {
+	for ii, rf := range _rfs {
+		pos := ii
+		if _header != nil {
+			var ok bool
+			if pos, ok = _header[rf.col]; !ok {
+				continue
+			}
+		}
+		if pos >= len(_fields) {
+			continue
+		}
+		if err := setRecordValue(_sv.FieldByIndex(rf.index), strings.TrimSpace(_fields[pos]), rf.fmt); err != nil {
+			return fmt.Errorf("genutil.UnmarshalRow: col(%s) : %v", rf.col, err)
+		}
+	}
+	return nil
+}
+
+// setRecordValue parses the string into the field according to its kind and format hint
+func setRecordValue(_fv reflect.Value, _str, _fmt string) error {
+	if strings.HasPrefix(_fmt, "%") {
+		_fmt = ""
+	}
+	if recordNullPolicy != nil && recordNullPolicy.IsNull(_str) {
+		_str = ""
+	}
+	if _fv.Kind() == reflect.Ptr {
+		if _str == "" {
+			_fv.Set(reflect.Zero(_fv.Type()))
+			return nil
+		}
+		pv := reflect.New(_fv.Type().Elem())
+		if err := setRecordValue(pv.Elem(), _str, _fmt); err != nil {
+			return err
+		}
+		_fv.Set(pv)
+		return nil
+	}
+	if _fv.Type() == reflect.TypeOf(time.Time{}) {
+		if _str == "" {
+			return nil
+		}
+		layout := _fmt
+		switch layout {
+		case "", "yyyymmdd":
+			layout = "20060102"
+		}
+		tt, err := time.ParseInLocation(layout, _str, time.Local)
+		if err != nil {
+			return err
+		}
+		_fv.Set(reflect.ValueOf(tt))
+		return nil
+	}
+	if _fmt == "yyyymmdd" && _str != "" && !IsYYYYMMDD(_str) {
+		return fmt.Errorf("bad yyyymmdd(%s)", _str)
+	}
+	switch _fv.Kind() {
+	case reflect.String:
+		_fv.SetString(_str)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if _str == "" {
+			_fv.SetInt(0)
+			return nil
+		}
+		if _fmt == "float" {
+			ff, err := strconv.ParseFloat(_str, 64)
+			if err != nil {
+				return err
+			}
+			_fv.SetInt(int64(ff))
+			return nil
+		}
+		num, err := strconv.ParseInt(_str, 10, 64)
+		if err != nil {
+			return err
+		}
+		_fv.SetInt(num)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if _str == "" {
+			_fv.SetUint(0)
+			return nil
+		}
+		num, err := strconv.ParseUint(_str, 10, 64)
+		if err != nil {
+			return err
+		}
+		_fv.SetUint(num)
+	case reflect.Float32, reflect.Float64:
+		if _str == "" {
+			_fv.SetFloat(0.0)
+			return nil
+		}
+		ff, err := strconv.ParseFloat(_str, 64)
+		if err != nil {
+			return err
+		}
+		_fv.SetFloat(ff)
+	case reflect.Bool:
+		_fv.SetBool(Str2Bool(_str) || ToBool(_str, false))
+	default:
+		return fmt.Errorf("unsupported field type %s", _fv.Type())
+	}
+	return nil
+}
+
+// ReadRecords reads a comma separated file (or available compression variant) with a header line into a slice of T
+func ReadRecords[T any](_fname string) ([]T, error) {
+	return ReadRecordsSep[T](_fname, ",")
+}
+
+// ReadRecordsSep reads a delimited file with a header line into a slice of T, the separator may be named as in SepMap
+func ReadRecordsSep[T any](_fname, _sep string) ([]T, error) {
+	return ReadRecordsComments[T](_fname, _sep, CommentSpec{})
+}
+
+// ReadRecordsComments is ReadRecordsSep skipping comment lines, before the header too, and stripping inline comments
+// per the spec
+func ReadRecordsComments[T any](_fname, _sep string, _comments CommentSpec) ([]T, error) {
+	if sep := SepMap(_sep, true); sep != "" {
+		_sep = sep
+	}
+	var zero T
+	typ := reflect.TypeOf(zero)
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("genutil.ReadRecords: type %T is not a struct", zero)
+	}
+	rfs := recordFields(typ)
+	bio, err := OpenAnyReadCloser(_fname)
+	if err != nil {
+		return nil, err
+	}
+	defer bio.Close()
+	recs := []T{}
+	hasComments := _comments.Blank || len(_comments.Prefixes) > 0
+	var header map[string]int
+	lineno := 0
+	for {
+		line, err := bio.ReadString('\n')
+		if len(line) > 0 {
+			lineno++
+			line = strings.TrimRight(line, "\r\n")
+			if hasComments && _comments.IsComment([]byte(line)) {
+				line = ""
+			}
+			line = _comments.Strip(line)
+			switch {
+			case line == "":
+			case header == nil:
+				header = HeaderIndex(strings.Split(line, _sep))
+			default:
+				var rec T
+				if err := unmarshalRowFields(strings.Split(line, _sep), header, rfs, reflect.ValueOf(&rec).Elem()); err != nil {
+					return recs, fmt.Errorf("genutil.ReadRecords: fname(%s) line(%d) : %v", _fname, lineno, err)
+				}
+				recs = append(recs, rec)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return recs, err
+		}
+	}
+	return recs, nil
+}
+
+// MarshalHeader returns the column names of the struct's bindable fields joined by the separator
+func MarshalHeader(_src interface{}, _sep string) string {
+	sv := reflect.Indirect(reflect.ValueOf(_src))
+	if sv.Kind() != reflect.Struct {
+		return ""
+	}
+	rfs := recordFields(sv.Type())
+	cols := make([]string, len(rfs))
+	for ii, rf := range rfs {
+		cols[ii] = rf.col
+	}
+	return strings.Join(cols, _sep)
+}
+
+// MarshalRow formats the struct (or pointer to struct) as a delimited line without newline, in field declaration order.
+// The fmt tag may be yyyymmdd or a time layout for time.Time fields, or a printf verb such as %.2f for any field.
+// It is an error for a value to contain the separator or a newline, as the line could not be read back.
+func MarshalRow(_src interface{}, _sep string) (string, error) {
+	sv := reflect.Indirect(reflect.ValueOf(_src))
+	if sv.Kind() != reflect.Struct {
+		return "", fmt.Errorf("genutil.MarshalRow: src must be struct or pointer to struct, got %T", _src)
+	}
+	return marshalRowFields(sv, recordFields(sv.Type()), _sep)
+}
+
+// marshalRowFields is MarshalRow with the field list computed once by the caller
+func marshalRowFields(_sv reflect.Value, _rfs []recordField, _sep string) (string, error) {
+	buf := GetBuffer()
+	defer PutBuffer(buf)
+	if err := writeRowFields(buf, _sv, _rfs, _sep); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// writeRowFields appends the formatted line, without newline, to the buffer
+func writeRowFields(_buf *bytes.Buffer, _sv reflect.Value, _rfs []recordField, _sep string) error {
+	for ii, rf := range _rfs {
+		str, err := formatRecordValue(_sv.FieldByIndex(rf.index), rf.fmt)
+		if err != nil {
+			return fmt.Errorf("genutil.MarshalRow: col(%s) : %v", rf.col, err)
+		}
+		if strings.Contains(str, _sep) || strings.ContainsAny(str, "\r\n") {
+			return fmt.Errorf("genutil.MarshalRow: col(%s) value(%s) contains separator or newline", rf.col, str)
+		}
+		if rf.mask {
+			str = MaskAccount(str, rf.visible)
+		}
+		if ii > 0 {
+			_buf.WriteString(_sep)
+		}
+		_buf.WriteString(str)
+	}
+	return nil
+}
+
+// formatRecordValue is the inverse of setRecordValue
+func formatRecordValue(_fv reflect.Value, _fmt string) (string, error) {
+	if _fv.Kind() == reflect.Ptr {
+		if _fv.IsNil() {
+			if recordNullPolicy != nil {
+				return recordNullPolicy.Replacement, nil
+			}
+			return "", nil
+		}
+		_fv = _fv.Elem()
+	}
+	if strings.HasPrefix(_fmt, "%") {
+		if kind := _fv.Kind(); (kind == reflect.Float64 || kind == reflect.Float32) && len(_fmt) == 4 &&
+			_fmt[1] == '.' && _fmt[2] >= '0' && _fmt[2] <= '9' && _fmt[3] == 'f' {
+			return string(AppendFloat(nil, _fv.Float(), int(_fmt[2]-'0'))), nil // %.2f without fmt
+		}
+		return fmt.Sprintf(_fmt, _fv.Interface()), nil
+	}
+	if tt, ok := _fv.Interface().(time.Time); ok {
+		if tt.IsZero() {
+			return "", nil
+		}
+		switch _fmt {
+		case "", "yyyymmdd", "20060102":
+			return string(AppendYYYYMMDD(nil, tt)), nil
+		}
+		return tt.Format(_fmt), nil
+	}
+	switch _fv.Kind() {
+	case reflect.String:
+		return _fv.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(_fv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(_fv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(_fv.Float(), 'f', -1, 64), nil
+	case reflect.Bool:
+		return strconv.FormatBool(_fv.Bool()), nil
+	}
+	return "", fmt.Errorf("unsupported field type %s", _fv.Type())
+}
+
+// RecordWriter writes a header line and then one delimited line per record of type T, through GzFile
+type RecordWriter[T any] struct {
+	gzf GzFile
+	sep string
+	rfs []recordField
+	num int64
+
+	trailer  *TrailerSpec // set by SetTrailer, with the totals below
+	sumIndex []int
+	bytes    int64
+	sum      float64
+}
+
+// OpenRecordWriter opens the file for writing (gzipped if the name ends in .gz) and writes the header line.
+// The separator may be named as in SepMap.
+func OpenRecordWriter[T any](_fname, _sep string) (*RecordWriter[T], error) {
+	if sep := SepMap(_sep, true); sep != "" {
+		_sep = sep
+	}
+	var zero T
+	typ := reflect.TypeOf(zero)
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("genutil.OpenRecordWriter: type %T is not a struct", zero)
+	}
+	us := &RecordWriter[T]{sep: _sep, rfs: recordFields(typ)}
+	us.gzf = OpenGzFile(_fname)
+	if _, err := us.gzf.WriteString(MarshalHeader(zero, _sep) + "\n"); err != nil {
+		us.gzf.Close()
+		return nil, err
+	}
+	return us, nil
+}
+
+// Write appends one record
+func (us *RecordWriter[T]) Write(_rec T) error {
+	buf := GetBuffer()
+	defer PutBuffer(buf)
+	if err := writeRowFields(buf, reflect.ValueOf(_rec), us.rfs, us.sep); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	if _, err := us.gzf.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	us.num++
+	if us.trailer != nil {
+		us.addToTrailer(_rec, buf.Len())
+	}
+	return nil
+}
+
+// MaskColumns makes the writer pass the named columns through MaskAccount, keeping _visible characters,
+// so a report can be shared without the full account numbers. Call it before the first Write.
+func (us *RecordWriter[T]) MaskColumns(_visible int, _cols ...string) error {
+	for _, col := range _cols {
+		found := false
+		for ii := range us.rfs {
+			if us.rfs[ii].col == col {
+				us.rfs[ii].mask, us.rfs[ii].visible, found = true, _visible, true
+			}
+		}
+		if !found {
+			return fmt.Errorf("genutil.RecordWriter.MaskColumns: no column(%s)", col)
+		}
+	}
+	return nil
+}
+
+// Count returns the number of records written so far
+func (us *RecordWriter[T]) Count() int64 {
+	return us.num
+}
+
+// Close writes the trailer if one was set, then flushes and closes
+func (us *RecordWriter[T]) Close() {
+	if us.trailer != nil {
+		if _, err := us.gzf.WriteString(us.trailer.format(us.sep, us.num, us.bytes, us.sum, time.Now()) + "\n"); err != nil {
+			fmt.Fprintf(os.Stderr, "genutil.RecordWriter.Close: trailer: %v\n", err)
+		}
+	}
+	us.gzf.Close()
+}
@@ -0,0 +1,75 @@
+package genutil
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ReplacerSpec is an ordered list of replacements, built once with Build and then applied to many lines:
+//
+//	rep, err := (&genutil.ReplacerSpec{LongestFirst: true}).Add("NYSE", "N").Add("NYSE Arca", "P").Build()
+//	line = rep.Replace(line)
+//
+// Plain pairs are applied in a single pass: at each position the first pair in order whose old text matches wins
+// (the longest one with LongestFirst) and replaced text is not looked at again, so overlapping keys give the same
+// result every time, unlike StrReplaceWithMap's map order. With Regex each old is a regexp and each new may use $1
+// expansions; the pairs are then applied one after the other, each to the result of the one before.
+type ReplacerSpec struct {
+	Pairs        [][2]string // old, new
+	LongestFirst bool        // plain pairs only: prefer the longest match at a position over the earlier pair
+	Regex        bool
+}
+
+// Add appends a pair and returns the spec, for chaining
+func (us *ReplacerSpec) Add(_old, _new string) *ReplacerSpec {
+	us.Pairs = append(us.Pairs, [2]string{_old, _new})
+	return us
+}
+
+// Replacer applies a ReplacerSpec, and is safe for concurrent use
+type Replacer struct {
+	plain   *strings.Replacer
+	regexps []*regexp.Regexp
+	news    []string
+}
+
+// Build checks and compiles the spec. An empty old text, or with Regex a bad regexp, is an error.
+func (us *ReplacerSpec) Build() (*Replacer, error) {
+	rep := &Replacer{}
+	if us.Regex {
+		for _, pair := range us.Pairs {
+			re, err := regexp.Compile(pair[0])
+			if err != nil {
+				return nil, fmt.Errorf("genutil.ReplacerSpec.Build: regexp(%s): %v", pair[0], err)
+			}
+			rep.regexps, rep.news = append(rep.regexps, re), append(rep.news, pair[1])
+		}
+		return rep, nil
+	}
+	pairs := append([][2]string(nil), us.Pairs...)
+	if us.LongestFirst {
+		sort.SliceStable(pairs, func(ii, jj int) bool { return len(pairs[ii][0]) > len(pairs[jj][0]) })
+	}
+	oldnew := make([]string, 0, 2*len(pairs))
+	for _, pair := range pairs {
+		if pair[0] == "" {
+			return nil, fmt.Errorf("genutil.ReplacerSpec.Build: empty old text for new(%s)", pair[1])
+		}
+		oldnew = append(oldnew, pair[0], pair[1])
+	}
+	rep.plain = strings.NewReplacer(oldnew...)
+	return rep, nil
+}
+
+// Replace returns the string with the replacements made
+func (us *Replacer) Replace(_str string) string {
+	if us.plain != nil {
+		return us.plain.Replace(_str)
+	}
+	for ii, re := range us.regexps {
+		_str = re.ReplaceAllString(_str, us.news[ii])
+	}
+	return _str
+}
@@ -0,0 +1,78 @@
+package genutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// RetentionPolicy says which dated files PruneDatedFiles keeps, eg "keep the last 10 days, and month-ends for a year":
+//
+//	genutil.RetentionPolicy{KeepDays: 10, KeepMonthEnds: 12}
+type RetentionPolicy struct {
+	KeepDays      int    // keep files dated within this many calendar days of Today
+	KeepMonthEnds int    // keep the last dated file of each of this many calendar months, counting Today's month
+	Today         string // yyyymmdd the policy is applied as of, default today
+	DryRun        bool   // only report what would be removed
+}
+
+var retentionDateToken = regexp.MustCompile(`(19|20)\d{6}`)
+
+// retentionDate returns the first valid yyyymmdd token in the basename, or ""
+func retentionDate(_fname string) string {
+	for _, tok := range retentionDateToken.FindAllString(filepath.Base(_fname), -1) {
+		if IsYYYYMMDD(tok) {
+			return tok
+		}
+	}
+	return ""
+}
+
+// PruneDatedFiles applies the policy to the files in dir matching the glob pattern, dated by the first yyyymmdd
+// token in their names. Files without a date, dirs and symlinks are never touched, and all files sharing a date
+// share its fate. Removal goes through PathDiscardOrPanic, so SetRemoveMode can turn it into a move to backup or trash.
+// It returns the sorted list of files removed, or that would be with DryRun.
+func PruneDatedFiles(_dir, _pattern string, _policy RetentionPolicy) ([]string, error) {
+	today := StrAorB(_policy.Today, Today())
+	if !IsYYYYMMDD(today) {
+		return nil, fmt.Errorf("genutil.PruneDatedFiles: bad Today(%s)", today)
+	}
+	matches, err := filepath.Glob(filepath.Join(_dir, _pattern))
+	if err != nil {
+		return nil, fmt.Errorf("genutil.PruneDatedFiles: %v", err)
+	}
+	byDate := map[string][]string{}
+	monthEnd := map[string]string{}
+	for _, match := range matches {
+		fi, err := os.Lstat(match)
+		dt := retentionDate(match)
+		if err != nil || !fi.Mode().IsRegular() || dt == "" {
+			continue
+		}
+		byDate[dt] = append(byDate[dt], match)
+		if dt > monthEnd[dt[:6]] {
+			monthEnd[dt[:6]] = dt
+		}
+	}
+	dayCutoff := AddCalDate(today, -_policy.KeepDays)
+	monthNum := func(_dt string) int { return int(ToInt(_dt[:4], 0))*12 + int(ToInt(_dt[4:6], 0)) }
+	removed := []string{}
+	for dt, fnames := range byDate {
+		switch {
+		case dt > dayCutoff:
+			continue
+		case monthEnd[dt[:6]] == dt && monthNum(today)-monthNum(dt) < _policy.KeepMonthEnds:
+			continue
+		}
+		removed = append(removed, fnames...)
+	}
+	sort.Strings(removed)
+	if !_policy.DryRun {
+		for _, fname := range removed {
+			PathDiscardOrPanic(fname)
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,176 @@
+package genutil
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Rounding modes for RoundToMode and RoundToTickMode
+const (
+	RoundHalfAway = 0 // 2.5 -> 3, -2.5 -> -3
+	RoundHalfEven = 1 // banker's rounding, 2.5 -> 2, 3.5 -> 4
+	RoundFloor    = 2 // towards -inf
+	RoundCeil     = 3 // towards +inf
+)
+
+// roundClean drops binary noise past 15 significant digits, so 2.675*100 is 267.5 and not 267.49999999999997
+func roundClean(_val float64) float64 {
+	if math.IsInf(_val, 0) || math.IsNaN(_val) {
+		return _val
+	}
+	val, _ := strconv.ParseFloat(strconv.FormatFloat(_val, 'g', 15, 64), 64)
+	return val
+}
+
+// roundMode rounds to an integer in the mode
+func roundMode(_val float64, _mode int) float64 {
+	switch _mode {
+	case RoundHalfEven:
+		return math.RoundToEven(_val)
+	case RoundFloor:
+		return math.Floor(_val)
+	case RoundCeil:
+		return math.Ceil(_val)
+	}
+	return math.Round(_val)
+}
+
+// RoundToMode rounds to the number of decimals in the mode, negative decimals round to tens, hundreds, ...
+func RoundToMode(_val float64, _decimals, _mode int) float64 {
+	scale := math.Pow(10, float64(_decimals))
+	return roundClean(roundMode(roundClean(_val*scale), _mode) / scale)
+}
+
+// RoundTo rounds half away from zero to the number of decimals, so RoundTo(2.675, 2) is 2.68
+func RoundTo(_val float64, _decimals int) float64 {
+	return RoundToMode(_val, _decimals, RoundHalfAway)
+}
+
+// RoundToEven is banker's rounding to the number of decimals, so RoundToEven(2.665, 2) is 2.66
+func RoundToEven(_val float64, _decimals int) float64 {
+	return RoundToMode(_val, _decimals, RoundHalfEven)
+}
+
+// FloorTo rounds down to the number of decimals
+func FloorTo(_val float64, _decimals int) float64 {
+	return RoundToMode(_val, _decimals, RoundFloor)
+}
+
+// CeilTo rounds up to the number of decimals
+func CeilTo(_val float64, _decimals int) float64 {
+	return RoundToMode(_val, _decimals, RoundCeil)
+}
+
+// RoundToTickMode rounds to a multiple of the tick size in the mode, a tick <= 0 returns the value unchanged
+func RoundToTickMode(_val, _tick float64, _mode int) float64 {
+	if _tick <= 0 {
+		return _val
+	}
+	return roundClean(roundMode(roundClean(_val/_tick), _mode) * _tick)
+}
+
+// RoundToTick rounds half away from zero to a multiple of the tick size, so RoundToTick(10.13, 0.05) is 10.15
+func RoundToTick(_val, _tick float64) float64 {
+	return RoundToTickMode(_val, _tick, RoundHalfAway)
+}
+
+// tickDecimals is the number of decimals needed to print multiples of the tick
+func tickDecimals(_tick float64) int {
+	str := strconv.FormatFloat(_tick, 'f', -1, 64)
+	if idx := strings.Index(str, "."); idx >= 0 {
+		return len(str) - idx - 1
+	}
+	return 0
+}
+
+// StrRoundTo is RoundTo for strings, the result has exactly the number of decimals and an empty input gives ""
+func StrRoundTo(_bsl string, _decimals int) string {
+	return StrRoundToMode(_bsl, _decimals, RoundHalfAway)
+}
+
+// StrRoundToMode is RoundToMode for strings, the result has exactly the number of decimals and an empty input gives ""
+func StrRoundToMode(_bsl string, _decimals, _mode int) string {
+	if len(_bsl) <= 0 {
+		return ""
+	}
+	f1, _ := strconv.ParseFloat(_bsl, 64)
+	return fmt.Sprintf("%.*f", MaxInt(_decimals, 0), RoundToMode(f1, _decimals, _mode))
+}
+
+// StrRoundToTick is RoundToTick for strings, printed with the decimals of the tick, and an empty input gives ""
+func StrRoundToTick(_bsl string, _tick float64) string {
+	return StrRoundToTickMode(_bsl, _tick, RoundHalfAway)
+}
+
+// StrRoundToTickMode is RoundToTickMode for strings, printed with the decimals of the tick, and an empty input gives ""
+func StrRoundToTickMode(_bsl string, _tick float64, _mode int) string {
+	if len(_bsl) <= 0 {
+		return ""
+	}
+	f1, _ := strconv.ParseFloat(_bsl, 64)
+	return fmt.Sprintf("%.*f", tickDecimals(_tick), RoundToTickMode(f1, _tick, _mode))
+}
+
+// FormatPct formats a fraction as a percentage, so FormatPct(0.125, 1) is "12.5%"
+func FormatPct(_val float64, _decimals int) string {
+	return fmt.Sprintf("%.*f%%", MaxInt(_decimals, 0), RoundTo(_val*100, _decimals))
+}
+
+// FormatBps formats a fraction in basis points to at most 2 decimals, so FormatBps(0.0037) is "37bp"
+func FormatBps(_val float64) string {
+	return strconv.FormatFloat(RoundTo(_val*10000, 2), 'f', -1, 64) + "bp"
+}
+
+// ParsePct parses "12.5%" (or "12.5") into the fraction 0.125
+func ParsePct(_str string) (float64, error) {
+	num := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(_str), "%"))
+	val, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("ParsePct: bad percentage(%s)", _str)
+	}
+	return roundClean(val / 100), nil
+}
+
+// ParseBps parses "37bp", "37bps" or "37" into the fraction 0.0037
+func ParseBps(_str string) (float64, error) {
+	num := strings.TrimSpace(_str)
+	num = strings.TrimSpace(ChompStr(ChompStr(num, "bps"), "bp"))
+	val, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("ParseBps: bad basis points(%s)", _str)
+	}
+	return roundClean(val / 10000), nil
+}
+
+// SafeDiv returns a/b, or _def if b is zero or the result is not finite
+func SafeDiv(_aa, _bb, _def float64) float64 {
+	if _bb == 0 {
+		return _def
+	}
+	val := _aa / _bb
+	if math.IsInf(val, 0) || math.IsNaN(val) {
+		return _def
+	}
+	return val
+}
+
+// IsFiniteStr is true if the string parses as a finite number, so "NaN", "+Inf" and "" are false
+func IsFiniteStr(_str string) bool {
+	val, err := strconv.ParseFloat(strings.TrimSpace(_str), 64)
+	return err == nil && !math.IsInf(val, 0) && !math.IsNaN(val)
+}
+
+// ScrubNonFinite replaces in place fields that parse as NaN or ±Inf, as the Str* helpers can produce, and returns the slice.
+// Fields that are not numbers at all are left alone.
+func ScrubNonFinite(_fields []string, _replacement string) []string {
+	for ii, field := range _fields {
+		val, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if (err == nil || errors.Is(err, strconv.ErrRange)) && (math.IsInf(val, 0) || math.IsNaN(val)) {
+			_fields[ii] = _replacement
+		}
+	}
+	return _fields
+}
@@ -0,0 +1,268 @@
+package genutil
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Column types understood by ValidateFile
+const (
+	SchemaString   = "string"
+	SchemaInt      = "int"
+	SchemaFloat    = "float"
+	SchemaYYYYMMDD = "yyyymmdd"
+	SchemaEnum     = "enum"
+)
+
+// SchemaColumn declares one column of a delimited file
+type SchemaColumn struct {
+	Name     string
+	Type     string   // one of the Schema* types, blank means SchemaString
+	Enum     []string // allowed values when Type is SchemaEnum
+	Nullable bool     // whether a blank value is acceptable
+}
+
+// Schema declares the expected layout of a delimited file
+type Schema struct {
+	Sep        string // separator, may be named as in SepMap, blank means comma
+	Header     bool   // first non-comment line is a header which must match the column names
+	NumCols    int    // expected column count, 0 means len(Columns)
+	Columns    []SchemaColumn
+	UniqueKeys [][]string // each entry lists the column names that together must be unique
+	Comments   []string   // comment tags as in IsCommentLine, such lines are skipped
+	MaxIssues  int        // stop after this many issues, 0 means no limit
+}
+
+// SchemaIssue is one problem found by ValidateFile
+type SchemaIssue struct {
+	Line   int64
+	Column string
+	Reason string
+}
+
+// Report is the outcome of ValidateFile
+type Report struct {
+	Fname  string
+	Lines  int64 // data lines checked
+	Issues []SchemaIssue
+}
+
+// OK informs if no issues were found
+func (us Report) OK() bool {
+	return len(us.Issues) == 0
+}
+
+// String lists the issues, one per line
+func (us Report) String() string {
+	str := fmt.Sprintf("fname=%s lines=%d issues=%d\n", us.Fname, us.Lines, len(us.Issues))
+	for _, issue := range us.Issues {
+		str += fmt.Sprintf("line=%d column=%s reason=%s\n", issue.Line, issue.Column, issue.Reason)
+	}
+	return str
+}
+
+// ValidateFile checks every line of a delimited file (or available compression variant) against the schema.
+// The error is only for failures to read the file, content problems are listed in the Report.
+func ValidateFile(_fname string, _schema Schema) (Report, error) {
+	report := Report{Fname: _fname}
+	sep := StrAorB(SepMap(_schema.Sep, true), StrAorB(_schema.Sep, ","))
+	numcols := IntTernary(_schema.NumCols > 0, _schema.NumCols, len(_schema.Columns))
+	colpos := map[string]int{}
+	for ii, col := range _schema.Columns {
+		colpos[col.Name] = ii
+	}
+	for _, key := range _schema.UniqueKeys {
+		for _, name := range key {
+			if _, ok := colpos[name]; !ok {
+				return report, fmt.Errorf("genutil.ValidateFile: unique key column(%s) not in schema", name)
+			}
+		}
+	}
+	seen := make([]map[string]int64, len(_schema.UniqueKeys))
+	for ii := range seen {
+		seen[ii] = map[string]int64{}
+	}
+
+	bio, err := OpenAnyReadCloser(_fname)
+	if err != nil {
+		return report, err
+	}
+	defer bio.Close()
+	full := func() bool {
+		return _schema.MaxIssues > 0 && len(report.Issues) >= _schema.MaxIssues
+	}
+	addIssue := func(_lineno int64, _col, _reason string) {
+		if !full() {
+			report.Issues = append(report.Issues, SchemaIssue{Line: _lineno, Column: _col, Reason: _reason})
+		}
+	}
+	headerDone := !_schema.Header
+	checkLine := func(_lineno int64, _line string) {
+		if _line == "" || IsCommentLine([]byte(_line), _schema.Comments) {
+			return
+		}
+		fields := strings.Split(_line, sep)
+		if !headerDone {
+			headerDone = true
+			for ii, col := range _schema.Columns {
+				switch {
+				case ii >= len(fields):
+					addIssue(_lineno, col.Name, "missing from header")
+				case strings.TrimSpace(fields[ii]) != col.Name:
+					addIssue(_lineno, col.Name, fmt.Sprintf("header has (%s)", strings.TrimSpace(fields[ii])))
+				}
+			}
+			return
+		}
+		report.Lines++
+		if numcols > 0 && len(fields) != numcols {
+			addIssue(_lineno, "", fmt.Sprintf("expected %d columns, found %d", numcols, len(fields)))
+		}
+		for ii, col := range _schema.Columns {
+			if ii >= len(fields) {
+				break
+			}
+			if reason := schemaCheckValue(col, strings.TrimSpace(fields[ii])); reason != "" {
+				addIssue(_lineno, col.Name, reason)
+			}
+		}
+		for kk, key := range _schema.UniqueKeys {
+			vals := make([]string, len(key))
+			for ii, name := range key {
+				if pos := colpos[name]; pos < len(fields) {
+					vals[ii] = strings.TrimSpace(fields[pos])
+				}
+			}
+			kval := strings.Join(vals, "\x00")
+			if first, ok := seen[kk][kval]; ok {
+				addIssue(_lineno, strings.Join(key, "+"), fmt.Sprintf("duplicate key (%s) first seen on line %d", strings.Join(vals, ","), first))
+			} else {
+				seen[kk][kval] = _lineno
+			}
+		}
+	}
+	lineno := int64(0)
+	for !full() {
+		line, err := bio.ReadString('\n')
+		if len(line) > 0 {
+			lineno++
+			checkLine(lineno, strings.TrimRight(line, "\r\n"))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// schemaCheckValue returns the reason the value does not fit the column, or blank
+func schemaCheckValue(_col SchemaColumn, _val string) string {
+	if _val == "" {
+		return StrTernary(_col.Nullable, "", "blank value not allowed")
+	}
+	switch _col.Type {
+	case SchemaInt:
+		if _, err := strconv.ParseInt(_val, 10, 64); err != nil {
+			return fmt.Sprintf("not an int (%s)", _val)
+		}
+	case SchemaFloat:
+		if _, err := strconv.ParseFloat(_val, 64); err != nil {
+			return fmt.Sprintf("not a float (%s)", _val)
+		}
+	case SchemaYYYYMMDD:
+		if !IsYYYYMMDD(_val) {
+			return fmt.Sprintf("not a yyyymmdd date (%s)", _val)
+		}
+	case SchemaEnum:
+		if !SliceContainsStr(_col.Enum, _val) {
+			return fmt.Sprintf("value (%s) not in (%s)", _val, strings.Join(_col.Enum, "|"))
+		}
+	case SchemaString, "":
+	default:
+		return fmt.Sprintf("unknown schema type (%s)", _col.Type)
+	}
+	return ""
+}
+
+// ReadHeader returns the trimmed fields of the first non-blank line of a delimited file (or available compression variant).
+// The separator may be named as in SepMap.
+func ReadHeader(_fname, _sep string) ([]string, error) {
+	sep := StrAorB(SepMap(_sep, true), _sep)
+	bio, err := OpenAnyReadCloser(_fname)
+	if err != nil {
+		return nil, err
+	}
+	defer bio.Close()
+	for {
+		line, err := bio.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		if strings.TrimSpace(line) != "" {
+			fields := strings.Split(line, sep)
+			for ii := range fields {
+				fields[ii] = strings.TrimSpace(fields[ii])
+			}
+			return fields, nil
+		}
+		if err == io.EOF {
+			return []string{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// CompareHeaderFields compares two header lines.
+// added and removed are in the order of the new and old header respectively,
+// reordered lists (in new order) the common columns whose position among the common columns has changed.
+func CompareHeaderFields(_old, _new []string) (added, removed, reordered []string) {
+	added, removed, reordered = []string{}, []string{}, []string{}
+	oldset, newset := NewBoolMap(), NewBoolMap()
+	for _, col := range _old {
+		oldset[col] = true
+	}
+	for _, col := range _new {
+		newset[col] = true
+	}
+	oldcommon, newcommon := []string{}, []string{}
+	for _, col := range _old {
+		if newset[col] {
+			oldcommon = append(oldcommon, col)
+		} else {
+			removed = append(removed, col)
+		}
+	}
+	for _, col := range _new {
+		if oldset[col] {
+			newcommon = append(newcommon, col)
+		} else {
+			added = append(added, col)
+		}
+	}
+	for ii, col := range newcommon {
+		if ii < len(oldcommon) && oldcommon[ii] != col {
+			reordered = append(reordered, col)
+		}
+	}
+	return
+}
+
+// CompareHeaders compares the header lines of two generations of a delimited file, see CompareHeaderFields.
+// It panics if either file cannot be read.
+func CompareHeaders(_fnameOld, _fnameNew, _sep string) (added, removed, reordered []string) {
+	oldh, err := ReadHeader(_fnameOld, _sep)
+	if err != nil {
+		log.Panicf("genutil.CompareHeaders: err(%s) fname(%s)", err.Error(), _fnameOld)
+	}
+	newh, err := ReadHeader(_fnameNew, _sep)
+	if err != nil {
+		log.Panicf("genutil.CompareHeaders: err(%s) fname(%s)", err.Error(), _fnameNew)
+	}
+	return CompareHeaderFields(oldh, newh)
+}
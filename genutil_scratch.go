@@ -0,0 +1,90 @@
+package genutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ScratchDir is a temp dir scoped to a run, removed by Cleanup:
+//
+//	sd, err := genutil.NewScratchDir("myjob")
+//	if err != nil { ... }
+//	defer sd.Cleanup()
+//	sd.KeepOnFailure(true)
+//	fd, err := sd.TempFile("part.*.csv")
+//
+// Copies of a ScratchDir share their state, so it can be passed by value.
+type ScratchDir struct {
+	path  string
+	state *scratchState
+}
+
+type scratchState struct {
+	keepOnFail bool
+	failed     bool
+	cleaned    bool
+	mu         sync.Mutex
+}
+
+// NewScratchDir creates a fresh dir named <prefix>.<random> under os.TempDir()
+func NewScratchDir(_prefix string) (ScratchDir, error) {
+	path, err := os.MkdirTemp("", shardKeyName(_prefix)+".")
+	if err != nil {
+		return ScratchDir{}, fmt.Errorf("genutil.NewScratchDir: %v", err)
+	}
+	return ScratchDir{path: path, state: &scratchState{}}, nil
+}
+
+// Path returns the dir
+func (us ScratchDir) Path() string { return us.path }
+
+// Join returns the path of a name inside the dir
+func (us ScratchDir) Join(_name string) string { return filepath.Join(us.path, _name) }
+
+// TempFile creates a file in the dir as os.CreateTemp does, a * in the pattern is replaced by a random string
+func (us ScratchDir) TempFile(_pattern string) (*os.File, error) {
+	return os.CreateTemp(us.path, _pattern)
+}
+
+// KeepOnFailure makes Cleanup leave the dir in place, for post mortem, if the run failed
+func (us ScratchDir) KeepOnFailure(_keep bool) {
+	us.state.mu.Lock()
+	defer us.state.mu.Unlock()
+	us.state.keepOnFail = _keep
+}
+
+// SetFailed marks the run as failed, see KeepOnFailure. A panic through a deferred Cleanup also counts.
+func (us ScratchDir) SetFailed() {
+	us.state.mu.Lock()
+	defer us.state.mu.Unlock()
+	us.state.failed = true
+}
+
+// Cleanup removes the dir and everything in it, unless the run failed with KeepOnFailure set, in which case
+// the kept path is reported on stderr. It is safe to call more than once, and when deferred directly it
+// notices a panic in progress, counts it as a failure, and lets the panic continue.
+func (us ScratchDir) Cleanup() error {
+	if rr := recover(); rr != nil {
+		us.SetFailed()
+		defer panic(rr)
+	}
+	if us.state == nil {
+		return nil
+	}
+	us.state.mu.Lock()
+	defer us.state.mu.Unlock()
+	if us.state.cleaned {
+		return nil
+	}
+	us.state.cleaned = true
+	if us.state.failed && us.state.keepOnFail {
+		fmt.Fprintf(os.Stderr, "genutil.ScratchDir: keeping %s after failure\n", us.path)
+		return nil
+	}
+	if err := auditedRemoveAll(us.path); err != nil {
+		return fmt.Errorf("genutil.ScratchDir.Cleanup: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package genutil
+
+import (
+	"fmt"
+	"strings"
+)
+
+// secidValue is the check-digit value of an identifier character: 0-9 for digits, 10-35 for A-Z, -1 otherwise
+func secidValue(_ch byte) int {
+	switch {
+	case _ch >= '0' && _ch <= '9':
+		return int(_ch - '0')
+	case _ch >= 'A' && _ch <= 'Z':
+		return int(_ch-'A') + 10
+	}
+	return -1
+}
+
+// ValidateISIN checks the 12 character ISIN: a two letter country code, nine alphanumerics and a Luhn check digit.
+// The error says what is wrong, so it can go straight into a rejects file.
+func ValidateISIN(_isin string) (bool, error) {
+	if len(_isin) != 12 {
+		return false, fmt.Errorf("ISIN(%s) has length %d, want 12", _isin, len(_isin))
+	}
+	if secidValue(_isin[0]) < 10 || secidValue(_isin[1]) < 10 {
+		return false, fmt.Errorf("ISIN(%s) does not start with a country code", _isin)
+	}
+	for ii := 2; ii < 11; ii++ {
+		if secidValue(_isin[ii]) < 0 {
+			return false, fmt.Errorf("ISIN(%s) has bad character (%c)", _isin, _isin[ii])
+		}
+	}
+	if want := isinCheckDigit(_isin[:11]); _isin[11] != want {
+		return false, fmt.Errorf("ISIN(%s) has check digit %c, want %c", _isin, _isin[11], want)
+	}
+	return true, nil
+}
+
+// isinCheckDigit computes the Luhn check digit over the body with letters expanded to two digits
+func isinCheckDigit(_body string) byte {
+	digits := make([]int, 0, 2*len(_body))
+	for ii := 0; ii < len(_body); ii++ {
+		if val := secidValue(_body[ii]); val >= 10 {
+			digits = append(digits, val/10, val%10)
+		} else {
+			digits = append(digits, val)
+		}
+	}
+	sum := 0
+	for ii := len(digits) - 1; ii >= 0; ii-- {
+		val := digits[ii]
+		if (len(digits)-1-ii)%2 == 0 {
+			val *= 2
+		}
+		sum += val/10 + val%10
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// ValidateCUSIP checks the 9 character CUSIP: eight alphanumerics (or * @ #) and a check digit
+func ValidateCUSIP(_cusip string) (bool, error) {
+	if len(_cusip) != 9 {
+		return false, fmt.Errorf("CUSIP(%s) has length %d, want 9", _cusip, len(_cusip))
+	}
+	sum := 0
+	for ii := 0; ii < 8; ii++ {
+		val := secidValue(_cusip[ii])
+		switch _cusip[ii] {
+		case '*':
+			val = 36
+		case '@':
+			val = 37
+		case '#':
+			val = 38
+		}
+		if val < 0 {
+			return false, fmt.Errorf("CUSIP(%s) has bad character (%c)", _cusip, _cusip[ii])
+		}
+		if ii%2 == 1 {
+			val *= 2
+		}
+		sum += val/10 + val%10
+	}
+	if want := byte('0' + (10-sum%10)%10); _cusip[8] != want {
+		return false, fmt.Errorf("CUSIP(%s) has check digit %c, want %c", _cusip, _cusip[8], want)
+	}
+	return true, nil
+}
+
+// sedolWeights are the SEDOL check-digit weights of the first six characters
+var sedolWeights = [6]int{1, 3, 1, 7, 3, 9}
+
+// ValidateSEDOL checks the 7 character SEDOL: six digits or consonants and a check digit
+func ValidateSEDOL(_sedol string) (bool, error) {
+	if len(_sedol) != 7 {
+		return false, fmt.Errorf("SEDOL(%s) has length %d, want 7", _sedol, len(_sedol))
+	}
+	sum := 0
+	for ii := 0; ii < 6; ii++ {
+		val := secidValue(_sedol[ii])
+		if val < 0 || strings.IndexByte("AEIOU", _sedol[ii]) >= 0 {
+			return false, fmt.Errorf("SEDOL(%s) has bad character (%c)", _sedol, _sedol[ii])
+		}
+		sum += val * sedolWeights[ii]
+	}
+	if want := byte('0' + (10-sum%10)%10); _sedol[6] != want {
+		return false, fmt.Errorf("SEDOL(%s) has check digit %c, want %c", _sedol, _sedol[6], want)
+	}
+	return true, nil
+}
+
+// Cusip2Isin builds the ISIN of a valid CUSIP for the two letter country code, "US" if blank
+func Cusip2Isin(_cusip, _country string) (string, error) {
+	if ok, err := ValidateCUSIP(_cusip); !ok {
+		return "", fmt.Errorf("genutil.Cusip2Isin: %v", err)
+	}
+	country := strings.ToUpper(StrAorB(_country, "US"))
+	if len(country) != 2 || secidValue(country[0]) < 10 || secidValue(country[1]) < 10 {
+		return "", fmt.Errorf("genutil.Cusip2Isin: bad country code (%s)", _country)
+	}
+	body := country + _cusip
+	return body + string(isinCheckDigit(body)), nil
+}
@@ -0,0 +1,126 @@
+package genutil
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// SecretBackend is a source of secrets for GetSecret; ok is false when it has no secret by that name,
+// err is for a backend that has one but cannot give it out safely
+type SecretBackend interface {
+	Secret(name string) (val string, ok bool, err error)
+}
+
+// EnvSecrets reads the secret from the environment variable Prefix+NAME, the name uppercased with
+// characters other than letters and digits made '_', so "db.password" is DB_PASSWORD
+type EnvSecrets struct {
+	Prefix string
+}
+
+// FileSecrets reads the secret from the file Dir/name, less trailing newlines. The file must be owned by the
+// current user and not readable or writable by group or others (0600 or stricter), else it is an error.
+type FileSecrets struct {
+	Dir string
+}
+
+// CommandSecrets runs Command (split as by ShellSplit) with the name appended as last argument, as in
+// "pass show" or "vault kv get -field=value secret/app", and takes its stdout less trailing newlines.
+// Empty output is no secret, a non-zero exit an error.
+type CommandSecrets struct {
+	Command string
+	Timeout time.Duration // 0 for a minute
+}
+
+// secretBackends is set by SetSecretBackends
+var secretBackends = []SecretBackend{EnvSecrets{}}
+
+// SetSecretBackends sets the backends GetSecret asks in order, the default is just EnvSecrets{}:
+//
+//	genutil.SetSecretBackends(genutil.EnvSecrets{Prefix: "APP_"}, genutil.FileSecrets{Dir: os.Getenv("HOME") + "/.secrets"})
+func SetSecretBackends(_backends ...SecretBackend) {
+	secretBackends = _backends
+}
+
+// GetSecret returns the secret from the first backend that has it. An error from a backend, like a secrets file
+// with loose permissions, stops the search rather than falling through to the next backend.
+// Errors never include the secret itself.
+func GetSecret(_name string) (string, error) {
+	for _, backend := range secretBackends {
+		val, ok, err := backend.Secret(_name)
+		if err != nil {
+			return "", fmt.Errorf("genutil.GetSecret: %s: %v", _name, err)
+		}
+		if ok {
+			return val, nil
+		}
+	}
+	return "", fmt.Errorf("genutil.GetSecret: no secret(%s) in %d backends", _name, len(secretBackends))
+}
+
+// Secret implements SecretBackend
+func (us EnvSecrets) Secret(_name string) (string, bool, error) {
+	key := []byte(strings.ToUpper(_name))
+	for ii, ch := range key {
+		if !isASCIILetter(ch) && (ch < '0' || ch > '9') {
+			key[ii] = '_'
+		}
+	}
+	val, ok := os.LookupEnv(us.Prefix + string(key))
+	return val, ok, nil
+}
+
+// Secret implements SecretBackend
+func (us FileSecrets) Secret(_name string) (string, bool, error) {
+	path, err := CleanJoin(us.Dir, _name)
+	if err != nil {
+		return "", false, err
+	}
+	fi, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if perm := fi.Mode().Perm(); perm&0077 != 0 {
+		return "", false, fmt.Errorf("file(%s) has mode %04o, want 0600 or stricter", path, perm)
+	}
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok && int(st.Uid) != os.Getuid() {
+		return "", false, fmt.Errorf("file(%s) is owned by uid %d, not the current user", path, st.Uid)
+	}
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, err
+	}
+	return strings.TrimRight(string(buf), "\r\n"), true, nil
+}
+
+// commandSecretsMaxOutput caps the output CommandSecrets takes from its command
+const commandSecretsMaxOutput = 64 * 1024
+
+// Secret implements SecretBackend
+func (us CommandSecrets) Secret(_name string) (string, bool, error) {
+	argv, err := ShellSplit(us.Command)
+	if err != nil || len(argv) == 0 {
+		return "", false, fmt.Errorf("bad secrets command (%s)", us.Command)
+	}
+	timeout := us.Timeout
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	res, err := ExecWithOpts(argv[0], append(argv[1:], _name), ExecOpts{Timeout: timeout, MaxOutput: commandSecretsMaxOutput})
+	if stderr := strings.TrimSpace(res.Stderr); err != nil && stderr != "" {
+		return "", false, fmt.Errorf("%v: %s", err, stderr)
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if res.Truncated { // the value would be cut around a marker, not a secret
+		return "", false, fmt.Errorf("secrets command (%s) wrote more than %d bytes", us.Command, commandSecretsMaxOutput)
+	}
+	val := strings.TrimRight(res.Stdout, "\r\n")
+	return val, val != "", nil
+}
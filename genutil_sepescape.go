@@ -0,0 +1,88 @@
+package genutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseSeparator resolves a separator as written in a config or on a command line: a name known to SepMap
+// (in any case), else a string with backslash escapes such as `\x01`, `\001`, `\t` or `\x01\x02`, else the text
+// itself, so "||" stays "||". It is an error if the result is empty or the escapes are malformed.
+func ParseSeparator(_spec string) (string, error) {
+	if sep := SepMap(_spec, false); sep != "" {
+		return sep, nil
+	}
+	if sep := SepMap(_spec, true); sep != "" {
+		return sep, nil
+	}
+	if strings.Contains(_spec, `\`) {
+		sep, err := strconv.Unquote(`"` + strings.Replace(_spec, `"`, `\"`, -1) + `"`)
+		if err != nil {
+			return "", fmt.Errorf("genutil.ParseSeparator: bad escape in (%s)", _spec)
+		}
+		_spec = sep
+	}
+	if _spec == "" {
+		return "", fmt.Errorf("genutil.ParseSeparator: empty separator")
+	}
+	return _spec, nil
+}
+
+// resolveSep is ParseSeparator for the helpers without an error result, falling back to the text as given
+func resolveSep(_spec string) string {
+	if sep, err := ParseSeparator(_spec); err == nil {
+		return sep
+	}
+	return _spec
+}
+
+// SplitEscaped splits on the separator, which may be several characters, except where it is escaped. Reading left
+// to right, the escape character makes the byte after it literal and is dropped, so `\|` is "|" and `\\` is "\";
+// an escape at the very end is kept. It is the inverse of JoinEscaped, for Hive and legacy formats whose fields may
+// hold the separator. The separator is resolved by ParseSeparator.
+//
+//	genutil.SplitEscaped(`a\|b|c`, "|", '\\') // [a|b c]
+func SplitEscaped(_str, _sep string, _escape byte) []string {
+	sep := resolveSep(_sep)
+	if sep == "" {
+		return []string{_str}
+	}
+	fields := []string{}
+	var field strings.Builder
+	for ii := 0; ii < len(_str); {
+		switch {
+		case _str[ii] == _escape && ii+1 < len(_str):
+			field.WriteByte(_str[ii+1])
+			ii += 2
+		case strings.HasPrefix(_str[ii:], sep):
+			fields = append(fields, field.String())
+			field.Reset()
+			ii += len(sep)
+		default:
+			field.WriteByte(_str[ii])
+			ii++
+		}
+	}
+	return append(fields, field.String())
+}
+
+// JoinEscaped joins the fields with the separator, putting the escape character before each escape and each
+// occurrence of the separator's first byte within a field, so no separator can form across an escape or a field
+// boundary and SplitEscaped gives the fields back. The separator is resolved by ParseSeparator.
+func JoinEscaped(_fields []string, _sep string, _escape byte) string {
+	sep := resolveSep(_sep)
+	var out strings.Builder
+	for ii, field := range _fields {
+		if ii > 0 {
+			out.WriteString(sep)
+		}
+		for jj := 0; jj < len(field); jj++ {
+			if field[jj] == _escape || (sep != "" && field[jj] == sep[0]) {
+				out.WriteByte(_escape)
+			}
+			out.WriteByte(field[jj])
+		}
+	}
+	return out.String()
+}
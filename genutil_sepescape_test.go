@@ -0,0 +1,48 @@
+package genutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJoinSplitEscapedRoundTrip(t *testing.T) {
+	tests := []struct {
+		fields []string
+		sep    string
+	}{
+		{[]string{"a", "b", "c"}, "|"},
+		{[]string{"a|b", "c"}, "|"},
+		{[]string{`a\`, "b"}, "|"},
+		{[]string{"a|", "b"}, "||"},
+		{[]string{`a\|`, "b"}, "||"},
+		{[]string{"|a", "|", "||", "b|"}, "||"},
+		{[]string{`\\`, `\`, ""}, "||"},
+		{[]string{"x\x01y", "", "z"}, `\x01`},
+		{[]string{"", ""}, ","},
+	}
+	for _, tt := range tests {
+		joined := JoinEscaped(tt.fields, tt.sep, '\\')
+		if got := SplitEscaped(joined, tt.sep, '\\'); !reflect.DeepEqual(got, tt.fields) {
+			t.Errorf("SplitEscaped(JoinEscaped(%q, %q)) = %q via %q", tt.fields, tt.sep, got, joined)
+		}
+	}
+}
+
+func TestSplitEscaped(t *testing.T) {
+	tests := []struct {
+		str, sep string
+		want     []string
+	}{
+		{`a\|b|c`, "|", []string{"a|b", "c"}},
+		{`a\\|b`, "|", []string{`a\`, "b"}},
+		{`a|||b`, "||", []string{"a", "|b"}},
+		{`a\|||b`, "||", []string{"a|", "b"}},
+		{`a\`, "|", []string{`a\`}},
+		{"", "|", []string{""}},
+	}
+	for _, tt := range tests {
+		if got := SplitEscaped(tt.str, tt.sep, '\\'); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitEscaped(%q, %q) = %q, want %q", tt.str, tt.sep, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package genutil
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sha256SidecarSuffix is appended to the file name to name its sidecar
+const sha256SidecarSuffix = ".sha256"
+
+// Sha256Writer digests the bytes written through it and, on Close, writes the sidecar fname.sha256 in sha256sum
+// format, so `sha256sum -c fname.sha256` run in the file's directory verifies it, as does VerifySidecar
+type Sha256Writer struct {
+	ww    io.WriteCloser
+	fname string
+	hh    hash.Hash
+}
+
+// NewSha256Writer wraps the writer of the file named fname, which Close closes before writing the sidecar
+func NewSha256Writer(_ww io.WriteCloser, _fname string) *Sha256Writer {
+	return &Sha256Writer{ww: _ww, fname: _fname, hh: sha256.New()}
+}
+
+// Write writes to the file and adds what was written to the digest
+func (us *Sha256Writer) Write(pp []byte) (int, error) {
+	nn, err := us.ww.Write(pp)
+	us.hh.Write(pp[:nn])
+	return nn, err
+}
+
+// Sum is the hex digest of what has been written so far
+func (us *Sha256Writer) Sum() string {
+	return hex.EncodeToString(us.hh.Sum(nil))
+}
+
+// Close closes the file, then writes its sidecar unless the close failed
+func (us *Sha256Writer) Close() error {
+	if err := us.ww.Close(); err != nil {
+		return fmt.Errorf("genutil.Sha256Writer: %s: %v", us.fname, err)
+	}
+	line := us.Sum() + "  " + filepath.Base(us.fname) + "\n"
+	if err := writeFileAtomic(us.fname+sha256SidecarSuffix, []byte(line)); err != nil {
+		return fmt.Errorf("genutil.Sha256Writer: %v", err)
+	}
+	return nil
+}
+
+// OpenGzFileSha256 is OpenGzFile also writing the sidecar fname.sha256 on Close, digesting the bytes as they go
+// to disk (compressed, for a .gz) rather than reading the file again
+func OpenGzFileSha256(_fname string) GzFile {
+	if _fname == "-" {
+		panic(fmt.Errorf("genutil.OpenGzFileSha256: stdout has no sidecar"))
+	}
+	gzf := OpenGzFile(_fname)
+	auditedRemove(_fname + sha256SidecarSuffix) // a stale sidecar must not outlive the file it described
+	gzf.wwsum = NewSha256Writer(gzf.fo, _fname)
+	gzf.ww.Reset(gzf.wwsum)
+	if gzf.wwgz != nil {
+		gzf.wwgz.Reset(gzf.ww)
+	}
+	return gzf
+}
+
+// VerifySidecar checks the file against the digest in its sidecar fname.sha256, returning an error if the sidecar
+// is missing or malformed or the digest differs
+func VerifySidecar(_fname string) error {
+	buf, err := os.ReadFile(_fname + sha256SidecarSuffix)
+	if err != nil {
+		return fmt.Errorf("genutil.VerifySidecar: %v", err)
+	}
+	fields := strings.Fields(string(buf))
+	if len(fields) == 0 || len(fields[0]) != sha256.Size*2 {
+		return fmt.Errorf("genutil.VerifySidecar: %s%s has no sha256 digest", _fname, sha256SidecarSuffix)
+	}
+	want := strings.ToLower(fields[0])
+	fd, err := os.Open(_fname)
+	if err != nil {
+		return fmt.Errorf("genutil.VerifySidecar: %v", err)
+	}
+	defer fd.Close()
+	hh := sha256.New()
+	if _, err := io.Copy(hh, bufio.NewReaderSize(fd, 1<<20)); err != nil {
+		return fmt.Errorf("genutil.VerifySidecar: %s: %v", _fname, err)
+	}
+	if got := hex.EncodeToString(hh.Sum(nil)); got != want {
+		return fmt.Errorf("genutil.VerifySidecar: %s has sha256 %s, sidecar says %s", _fname, got, want)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package genutil
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// shardKeyName makes a key safe for use in a filename
+func shardKeyName(_key string) string {
+	_key = strings.TrimSpace(_key)
+	if _key == "" {
+		return "BLANK"
+	}
+	return strings.Map(func(_rr rune) rune {
+		switch _rr {
+		case '/', '\\', ' ', '\t', ':', '*', '?', '"', '<', '>', '|', 0:
+			return '_'
+		}
+		return _rr
+	}, _key)
+}
+
+// forEachLine calls _fn with every line of the file (or available compression variant), newline removed
+func forEachLine(_fname string, _fn func(_line string) error) error {
+	bio, err := OpenAnyReadCloser(_fname)
+	if err != nil {
+		return err
+	}
+	defer bio.Close()
+	for {
+		line, err := bio.ReadString('\n')
+		if len(line) > 0 {
+			if ferr := _fn(strings.TrimRight(line, "\r\n")); ferr != nil {
+				return ferr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// SplitFileByKey splits a comma separated file without header into one file per value of the (0-based) key column.
+// The output name is made by replacing $KEY in _outPattern, shards ending in .gz are compressed.
+// It returns the sorted list of files written.
+func SplitFileByKey(_fname string, _keyCol int, _outPattern string) ([]string, error) {
+	return SplitFileByKeySep(_fname, ",", _keyCol, false, _outPattern)
+}
+
+// SplitFileByKeySep is SplitFileByKey with a separator (named as in SepMap) and an optional header line repeated in every shard.
+// All shards are held open until the input is exhausted, so the number of distinct keys is bounded by the open file limit.
+func SplitFileByKeySep(_fname, _sep string, _keyCol int, _header bool, _outPattern string) ([]string, error) {
+	if !strings.Contains(_outPattern, "$KEY") {
+		return nil, fmt.Errorf("genutil.SplitFileByKey: outPattern(%s) lacks $KEY", _outPattern)
+	}
+	sep := StrAorB(SepMap(_sep, true), _sep)
+	shards := map[string]GzFile{}
+	defer func() {
+		for _, gzf := range shards {
+			gzf.Close()
+		}
+	}()
+	header, needHeader := "", _header
+	err := forEachLine(_fname, func(_line string) error {
+		if needHeader {
+			header, needHeader = _line, false
+			return nil
+		}
+		if _line == "" {
+			return nil
+		}
+		parts := strings.Split(_line, sep)
+		key := ""
+		if _keyCol < len(parts) {
+			key = parts[_keyCol]
+		}
+		ofname := strings.Replace(_outPattern, "$KEY", shardKeyName(key), -1)
+		gzf, ok := shards[ofname]
+		if !ok {
+			gzf = OpenGzFile(ofname)
+			shards[ofname] = gzf
+			if _header {
+				if _, err := gzf.WriteString(header + "\n"); err != nil {
+					return err
+				}
+			}
+		}
+		_, err := gzf.WriteString(_line + "\n")
+		return err
+	})
+	fnames := make([]string, 0, len(shards))
+	for ofname := range shards {
+		fnames = append(fnames, ofname)
+	}
+	sort.Strings(fnames)
+	return fnames, err
+}
+
+// SplitFileBySize splits a file into shards of at most _maxBytes uncompressed bytes, never breaking a line.
+// The output name is made by replacing $NUM in _outPattern with the 4-digit shard number starting at 0000,
+// shards ending in .gz are compressed. It returns the list of files written, in order.
+func SplitFileBySize(_fname string, _maxBytes int64, _outPattern string) ([]string, error) {
+	return SplitFileBySizeHeader(_fname, _maxBytes, false, _outPattern)
+}
+
+// SplitFileBySizeHeader is SplitFileBySize with an optional header line repeated in every shard
+func SplitFileBySizeHeader(_fname string, _maxBytes int64, _header bool, _outPattern string) ([]string, error) {
+	if !strings.Contains(_outPattern, "$NUM") {
+		return nil, fmt.Errorf("genutil.SplitFileBySize: outPattern(%s) lacks $NUM", _outPattern)
+	}
+	if _maxBytes <= 0 {
+		return nil, fmt.Errorf("genutil.SplitFileBySize: bad maxBytes(%d)", _maxBytes)
+	}
+	fnames := []string{}
+	var gzf GzFile
+	isOpen, size := false, int64(0)
+	header, needHeader := "", _header
+	err := forEachLine(_fname, func(_line string) error {
+		if needHeader {
+			header, needHeader = _line, false
+			return nil
+		}
+		nbytes := int64(len(_line) + 1)
+		if isOpen && size+nbytes > _maxBytes && size > int64(len(header)+1) {
+			gzf.Close()
+			isOpen = false
+		}
+		if !isOpen {
+			ofname := strings.Replace(_outPattern, "$NUM", fmt.Sprintf("%04d", len(fnames)), -1)
+			gzf, isOpen, size = OpenGzFile(ofname), true, 0
+			fnames = append(fnames, ofname)
+			if _header {
+				if _, err := gzf.WriteString(header + "\n"); err != nil {
+					return err
+				}
+				size += int64(len(header) + 1)
+			}
+		}
+		size += nbytes
+		_, err := gzf.WriteString(_line + "\n")
+		return err
+	})
+	if isOpen {
+		gzf.Close()
+	}
+	return fnames, err
+}
+
+// ConcatFiles streams the inputs (any mix of compression variants) into one output, compressed if it ends in .gz.
+// A missing final newline in an input is supplied so lines of consecutive inputs are not joined.
+func ConcatFiles(_outFname string, _inFnames ...string) error {
+	return concatFiles(_outFname, false, _inFnames)
+}
+
+// ConcatFilesSkipHeaders is ConcatFiles keeping the first line of the first input only, dropping the header of the others
+func ConcatFilesSkipHeaders(_outFname string, _inFnames ...string) error {
+	return concatFiles(_outFname, true, _inFnames)
+}
+
+// concatFiles does the work for ConcatFiles and ConcatFilesSkipHeaders
+func concatFiles(_outFname string, _skipHeaders bool, _inFnames []string) error {
+	outbase := CompressionBasename(_outFname)
+	for _, fname := range _inFnames {
+		if CompressionBasename(fname) == outbase {
+			return fmt.Errorf("genutil.ConcatFiles: input(%s) is a compression variant of output(%s)", fname, _outFname)
+		}
+		if !AnyPathOK(fname) {
+			return fmt.Errorf("genutil.ConcatFiles: input(%s) not found", fname)
+		}
+	}
+	gzf := OpenGzFile(_outFname)
+	defer gzf.Close()
+	for ii, fname := range _inFnames {
+		first := true
+		err := forEachLine(fname, func(_line string) error {
+			if first {
+				first = false
+				if _skipHeaders && ii > 0 {
+					return nil
+				}
+			}
+			_, err := gzf.WriteString(_line + "\n")
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("genutil.ConcatFiles: input(%s) : %v", fname, err)
+		}
+	}
+	return nil
+}
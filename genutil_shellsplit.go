@@ -0,0 +1,71 @@
+package genutil
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ShellSplit splits a command line into words the way sh does, without expanding anything:
+// single quotes are literal, double quotes allow \" \\ \$ \` escapes, and outside quotes a backslash escapes the next char.
+//
+//	ShellSplit(`grep -F "a b" 'it''s' c\ d`) // [grep -F "a b" its "c d"]
+//
+// An unterminated quote or a trailing backslash is an error.
+func ShellSplit(_cmdline string) ([]string, error) {
+	words := []string{}
+	var word strings.Builder
+	inWord := false
+	rr := []rune(_cmdline)
+	for ii := 0; ii < len(rr); ii++ {
+		ch := rr[ii]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case ch == '\\':
+			ii++
+			if ii >= len(rr) {
+				return nil, fmt.Errorf("genutil.ShellSplit: trailing backslash in (%s)", _cmdline)
+			}
+			if rr[ii] != '\n' { // backslash-newline is a line continuation
+				word.WriteRune(rr[ii])
+				inWord = true
+			}
+		case ch == '\'':
+			end := ii + 1
+			for end < len(rr) && rr[end] != '\'' {
+				end++
+			}
+			if end >= len(rr) {
+				return nil, fmt.Errorf("genutil.ShellSplit: unterminated single quote in (%s)", _cmdline)
+			}
+			word.WriteString(string(rr[ii+1 : end]))
+			ii, inWord = end, true
+		case ch == '"':
+			ii++
+			for ; ii < len(rr) && rr[ii] != '"'; ii++ {
+				if rr[ii] == '\\' && ii+1 < len(rr) && strings.ContainsRune("\"\\$`\n", rr[ii+1]) {
+					ii++
+					if rr[ii] == '\n' {
+						continue
+					}
+				}
+				word.WriteRune(rr[ii])
+			}
+			if ii >= len(rr) {
+				return nil, fmt.Errorf("genutil.ShellSplit: unterminated double quote in (%s)", _cmdline)
+			}
+			inWord = true
+		default:
+			word.WriteRune(ch)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
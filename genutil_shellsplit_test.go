@@ -0,0 +1,48 @@
+package genutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestShellSplit(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{``, []string{}},
+		{"  \t ", []string{}},
+		{`ls -l  /tmp`, []string{"ls", "-l", "/tmp"}},
+		{`"x\"y"`, []string{`x"y`}},
+		{`'a'"b"`, []string{"ab"}},
+		{`c\ d`, []string{"c d"}},
+		{`a '' b`, []string{"a", "", "b"}},
+		{`''`, []string{""}},
+		{`""`, []string{""}},
+		{`'it''s'`, []string{"its"}},
+		{`'a\b'`, []string{`a\b`}},
+		{`"a\b \\ \$ \` + "`" + `"`, []string{`a\b \ $ ` + "`"}},
+		{"a\\\nb", []string{"ab"}},
+		{"a \\\n b", []string{"a", "b"}},
+		{"\"a\\\nb\"", []string{"ab"}},
+		{`grep -F "a b" 'it''s' c\ d`, []string{"grep", "-F", "a b", "its", "c d"}},
+	}
+	for _, tt := range tests {
+		got, err := ShellSplit(tt.in)
+		if err != nil {
+			t.Errorf("ShellSplit(%q): %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ShellSplit(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestShellSplitErrors(t *testing.T) {
+	for _, in := range []string{`"abc`, `'abc`, `a "b\"`, `abc\`, `"a" \`} {
+		if got, err := ShellSplit(in); err == nil {
+			t.Errorf("ShellSplit(%q) = %q, want an error", in, got)
+		}
+	}
+}
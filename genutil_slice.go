@@ -0,0 +1,137 @@
+package genutil
+
+import (
+	"fmt"
+	"io"
+)
+
+// MapSlice returns a new slice with _fn applied to each element
+func MapSlice[T, U any](_sl []T, _fn func(T) U) []U {
+	out := make([]U, len(_sl))
+	for ii, elt := range _sl {
+		out[ii] = _fn(elt)
+	}
+	return out
+}
+
+// FilterSlice returns a new slice of the elements for which _keep is true, in order
+func FilterSlice[T any](_sl []T, _keep func(T) bool) []T {
+	out := []T{}
+	for _, elt := range _sl {
+		if _keep(elt) {
+			out = append(out, elt)
+		}
+	}
+	return out
+}
+
+// UniqueSlice returns a new slice without duplicates, keeping the first occurrence of each element in order
+func UniqueSlice[T comparable](_sl []T) []T {
+	seen := make(map[T]bool, len(_sl))
+	out := []T{}
+	for _, elt := range _sl {
+		if seen[elt] {
+			continue
+		}
+		seen[elt] = true
+		out = append(out, elt)
+	}
+	return out
+}
+
+// ChunkSlice splits the slice into consecutive chunks of at most _nn elements, the chunks share storage with the input
+func ChunkSlice[T any](_sl []T, _nn int) [][]T {
+	if _nn < 1 {
+		panic("genutil.ChunkSlice: chunk size must be positive")
+	}
+	chunks := [][]T{}
+	for len(_sl) > _nn {
+		chunks = append(chunks, _sl[:_nn:_nn])
+		_sl = _sl[_nn:]
+	}
+	if len(_sl) > 0 {
+		chunks = append(chunks, _sl)
+	}
+	return chunks
+}
+
+// ReverseSlice returns a new slice with the elements in reverse order
+func ReverseSlice[T any](_sl []T) []T {
+	nn := len(_sl)
+	out := make([]T, nn)
+	for ii, elt := range _sl {
+		out[nn-1-ii] = elt
+	}
+	return out
+}
+
+// Pair holds corresponding elements of two slices, see ZipSlices
+type Pair[A, B any] struct {
+	First  A
+	Second B
+}
+
+// ZipSlices pairs up elements at the same index, stopping at the end of the shorter slice
+func ZipSlices[A, B any](_aa []A, _bb []B) []Pair[A, B] {
+	nn := MinInt(len(_aa), len(_bb))
+	out := make([]Pair[A, B], nn)
+	for ii := 0; ii < nn; ii++ {
+		out[ii] = Pair[A, B]{First: _aa[ii], Second: _bb[ii]}
+	}
+	return out
+}
+
+// IntersectSlices returns the unique elements of _aa that are also in _bb, in the order of _aa
+func IntersectSlices[T comparable](_aa, _bb []T) []T {
+	inb := NewSet(_bb...)
+	return FilterSlice(UniqueSlice(_aa), func(_elt T) bool { return inb[_elt] })
+}
+
+// UnionSlices returns the unique elements of _aa in order, followed by those of _bb not in _aa
+func UnionSlices[T comparable](_aa, _bb []T) []T {
+	return UniqueSlice(append(append([]T{}, _aa...), _bb...))
+}
+
+// DifferenceSlices returns the unique elements of _aa that are not in _bb, in the order of _aa
+func DifferenceSlices[T comparable](_aa, _bb []T) []T {
+	inb := NewSet(_bb...)
+	return FilterSlice(UniqueSlice(_aa), func(_elt T) bool { return !inb[_elt] })
+}
+
+// SymmetricDifferenceSlices returns DifferenceSlices(_aa, _bb) followed by DifferenceSlices(_bb, _aa)
+func SymmetricDifferenceSlices[T comparable](_aa, _bb []T) []T {
+	return append(DifferenceSlices(_aa, _bb), DifferenceSlices(_bb, _aa)...)
+}
+
+// Intersect is IntersectSlices for strings
+func Intersect(_aa, _bb []string) []string {
+	return IntersectSlices(_aa, _bb)
+}
+
+// Union is UnionSlices for strings
+func Union(_aa, _bb []string) []string {
+	return UnionSlices(_aa, _bb)
+}
+
+// Difference is DifferenceSlices for strings
+func Difference(_aa, _bb []string) []string {
+	return DifferenceSlices(_aa, _bb)
+}
+
+// SymmetricDifference is SymmetricDifferenceSlices for strings
+func SymmetricDifference(_aa, _bb []string) []string {
+	return SymmetricDifferenceSlices(_aa, _bb)
+}
+
+// DiffReport prints the keys added in _new and removed from _old, one per line prefixed by + or -, and returns them
+func DiffReport(_ww io.Writer, _old, _new []string) (added, removed []string) {
+	added, removed = Difference(_new, _old), Difference(_old, _new)
+	fmt.Fprintf(_ww, "added=%d removed=%d\n", len(added), len(removed))
+	for _, key := range added {
+		fmt.Fprintf(_ww, "+%s\n", key)
+	}
+	for _, key := range removed {
+		fmt.Fprintf(_ww, "-%s\n", key)
+	}
+	return
+}
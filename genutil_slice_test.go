@@ -0,0 +1,133 @@
+package genutil
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestMapSlice(t *testing.T) {
+	tests := []struct {
+		in   []int
+		want []string
+	}{
+		{nil, []string{}},
+		{[]int{}, []string{}},
+		{[]int{3, 1, 2}, []string{"3", "1", "2"}},
+	}
+	for _, tt := range tests {
+		if got := MapSlice(tt.in, strconv.Itoa); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("MapSlice(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFilterSlice(t *testing.T) {
+	even := func(_nn int) bool { return _nn%2 == 0 }
+	tests := []struct {
+		in, want []int
+	}{
+		{nil, []int{}},
+		{[]int{1, 3}, []int{}},
+		{[]int{4, 1, 2, 6, 3}, []int{4, 2, 6}},
+	}
+	for _, tt := range tests {
+		if got := FilterSlice(tt.in, even); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FilterSlice(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestUniqueSlice(t *testing.T) {
+	tests := []struct {
+		in, want []string
+	}{
+		{nil, []string{}},
+		{[]string{"a"}, []string{"a"}},
+		{[]string{"c", "a", "c", "b", "a", "c"}, []string{"c", "a", "b"}},
+		{[]string{"", "x", ""}, []string{"", "x"}},
+	}
+	for _, tt := range tests {
+		if got := UniqueSlice(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("UniqueSlice(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestChunkSlice(t *testing.T) {
+	tests := []struct {
+		in   []int
+		nn   int
+		want [][]int
+	}{
+		{nil, 2, [][]int{}},
+		{[]int{}, 3, [][]int{}},
+		{[]int{1, 2, 3, 4}, 2, [][]int{{1, 2}, {3, 4}}},
+		{[]int{1, 2, 3, 4, 5}, 2, [][]int{{1, 2}, {3, 4}, {5}}},
+		{[]int{1, 2, 3}, 1, [][]int{{1}, {2}, {3}}},
+		{[]int{1, 2, 3}, 3, [][]int{{1, 2, 3}}},
+		{[]int{1, 2, 3}, 10, [][]int{{1, 2, 3}}},
+	}
+	for _, tt := range tests {
+		if got := ChunkSlice(tt.in, tt.nn); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ChunkSlice(%v, %d) = %v, want %v", tt.in, tt.nn, got, tt.want)
+		}
+	}
+	for _, nn := range []int{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("ChunkSlice(_, %d) did not panic", nn)
+				}
+			}()
+			ChunkSlice([]int{1, 2}, nn)
+		}()
+	}
+}
+
+func TestChunkSliceAppendDoesNotClobber(t *testing.T) {
+	in := []int{1, 2, 3, 4}
+	chunks := ChunkSlice(in, 2)
+	_ = append(chunks[0], 99)
+	if !reflect.DeepEqual(in, []int{1, 2, 3, 4}) {
+		t.Errorf("append to a chunk changed the input to %v", in)
+	}
+}
+
+func TestReverseSlice(t *testing.T) {
+	tests := []struct {
+		in, want []int
+	}{
+		{nil, []int{}},
+		{[]int{1}, []int{1}},
+		{[]int{1, 2, 3}, []int{3, 2, 1}},
+	}
+	for _, tt := range tests {
+		in := append([]int(nil), tt.in...)
+		if got := ReverseSlice(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ReverseSlice(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+		if !reflect.DeepEqual(tt.in, in) {
+			t.Errorf("ReverseSlice changed its input to %v", tt.in)
+		}
+	}
+}
+
+func TestZipSlices(t *testing.T) {
+	tests := []struct {
+		aa   []int
+		bb   []string
+		want []Pair[int, string]
+	}{
+		{nil, nil, []Pair[int, string]{}},
+		{[]int{1, 2}, nil, []Pair[int, string]{}},
+		{[]int{1, 2}, []string{"a", "b"}, []Pair[int, string]{{1, "a"}, {2, "b"}}},
+		{[]int{1, 2, 3}, []string{"a"}, []Pair[int, string]{{1, "a"}}},
+		{[]int{1}, []string{"a", "b", "c"}, []Pair[int, string]{{1, "a"}}},
+	}
+	for _, tt := range tests {
+		if got := ZipSlices(tt.aa, tt.bb); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ZipSlices(%v, %q) = %v, want %v", tt.aa, tt.bb, got, tt.want)
+		}
+	}
+}
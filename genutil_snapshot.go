@@ -0,0 +1,81 @@
+package genutil
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+// DirSnapshot records the regular files under a root by relative path, with size and checksum.
+// It can be kept with WriteJSONFile and reloaded with ReadJSONFile to compare against a later run.
+type DirSnapshot struct {
+	Root  string                      `json:"root"`
+	Taken time.Time                   `json:"taken"`
+	Files map[string]DirSnapshotEntry `json:"files"`
+}
+
+// DirSnapshotEntry is what a DirSnapshot keeps per file
+type DirSnapshotEntry struct {
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// SnapshotDiff lists the relative paths that differ between two snapshots, each sorted
+type SnapshotDiff struct {
+	Added    []string
+	Removed  []string
+	Modified []string
+}
+
+// Same is true if nothing was added, removed or modified
+func (us SnapshotDiff) Same() bool {
+	return len(us.Added)+len(us.Removed)+len(us.Modified) == 0
+}
+
+// SnapshotDir walks the root and checksums every regular file, symlinks are not followed
+func SnapshotDir(_root string) (DirSnapshot, error) {
+	snap := DirSnapshot{Root: _root, Taken: time.Now(), Files: map[string]DirSnapshotEntry{}}
+	err := filepath.WalkDir(_root, func(_path string, _de fs.DirEntry, _err error) error {
+		if _err != nil {
+			return _err
+		}
+		if !_de.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(_root, _path)
+		if err != nil {
+			return err
+		}
+		mf := describeManifestFile(_path, _path)
+		if mf.Error != "" {
+			return fmt.Errorf("%s", mf.Error)
+		}
+		snap.Files[filepath.ToSlash(rel)] = DirSnapshotEntry{Size: mf.Size, SHA256: mf.SHA256}
+		return nil
+	})
+	if err != nil {
+		return snap, fmt.Errorf("genutil.SnapshotDir: %v", err)
+	}
+	return snap, nil
+}
+
+// CompareSnapshots lists the files added in _bb, removed from _aa, and modified (size or checksum) between them
+func CompareSnapshots(_aa, _bb DirSnapshot) SnapshotDiff {
+	diff := SnapshotDiff{Added: []string{}, Removed: []string{}, Modified: []string{}}
+	for _, rel := range SortedKeys(_aa.Files) {
+		entb, ok := _bb.Files[rel]
+		switch {
+		case !ok:
+			diff.Removed = append(diff.Removed, rel)
+		case entb != _aa.Files[rel]:
+			diff.Modified = append(diff.Modified, rel)
+		}
+	}
+	for _, rel := range SortedKeys(_bb.Files) {
+		if _, ok := _aa.Files[rel]; !ok {
+			diff.Added = append(diff.Added, rel)
+		}
+	}
+	return diff
+}
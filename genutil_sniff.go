@@ -0,0 +1,100 @@
+package genutil
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SniffLines is how many lines SniffDelimiter samples from the start of the file, blank and '#' lines not counted
+var SniffLines = 200
+
+// sniffCandidates are tried when SniffDelimiter is given none
+var sniffCandidates = []string{",", "\t", "|", ";"}
+
+// SniffDelimiter guesses the separator of a delimited file by how consistently each candidate splits the sampled
+// lines into the same number of fields, separators inside double quotes not counting. Candidates may be named as in
+// SepMap, nil tries comma, tab, pipe and semicolon. It returns the separator itself, and as confidence the fraction of
+// lines having its usual field count, halved when another candidate fits as well with as many fields.
+// It is an error if no candidate occurs in the sample.
+//
+//	sep, conf, err := genutil.SniffDelimiter("vendor.txt.gz", nil)
+//	if err != nil || conf < 0.9 { ... ask a human ... }
+func SniffDelimiter(_fname string, _candidates []string) (sep string, confidence float64, err error) {
+	if len(_candidates) == 0 {
+		_candidates = sniffCandidates
+	}
+	cands := make([]string, len(_candidates))
+	for ii, cand := range _candidates {
+		if cands[ii] = StrAorB(SepMap(cand, true), cand); cands[ii] == "" {
+			return "", 0, fmt.Errorf("genutil.SniffDelimiter: empty candidate")
+		}
+	}
+	lines := []string{}
+	err = forEachLine(_fname, func(_line string) error {
+		if strings.TrimSpace(_line) == "" || IsCommentLine([]byte(_line), []string{"WhitespaceHash"}) {
+			return nil
+		}
+		lines = append(lines, _line)
+		if len(lines) >= SniffLines {
+			return errRangeDone
+		}
+		return nil
+	})
+	if err != nil && err != errRangeDone {
+		return "", 0, fmt.Errorf("genutil.SniffDelimiter: %s: %v", _fname, err)
+	}
+	if len(lines) == 0 {
+		return "", 0, fmt.Errorf("genutil.SniffDelimiter: %s: no lines to sample", _fname)
+	}
+	bestFit, bestFields, secondFit, secondFields := 0.0, 0, 0.0, 0
+	for _, cand := range cands {
+		fit, fields := sniffFit(lines, cand)
+		switch {
+		case fields < 2:
+			continue
+		case fit > bestFit || (fit == bestFit && fields > bestFields):
+			secondFit, secondFields = bestFit, bestFields
+			sep, bestFit, bestFields = cand, fit, fields
+		case fit > secondFit || (fit == secondFit && fields > secondFields):
+			secondFit, secondFields = fit, fields
+		}
+	}
+	if sep == "" {
+		return "", 0, fmt.Errorf("genutil.SniffDelimiter: %s: no candidate separator occurs", _fname)
+	}
+	confidence = bestFit
+	if secondFit == bestFit && secondFields >= bestFields {
+		confidence /= 2
+	}
+	return sep, confidence, nil
+}
+
+// sniffFit returns the most common field count of the lines split on the separator, and the fraction of lines having it
+func sniffFit(_lines []string, _sep string) (float64, int) {
+	counts := map[int]int{}
+	for _, line := range _lines {
+		counts[sniffFieldCount(line, _sep)]++
+	}
+	modal, modalLines := 0, 0
+	for fields, nlines := range counts {
+		if nlines > modalLines || (nlines == modalLines && fields > modal) {
+			modal, modalLines = fields, nlines
+		}
+	}
+	return float64(modalLines) / float64(len(_lines)), modal
+}
+
+// sniffFieldCount counts the fields of the line, ignoring separators inside double quotes
+func sniffFieldCount(_line, _sep string) int {
+	count, inQuote := 1, false
+	for ii := 0; ii < len(_line); ii++ {
+		switch {
+		case _line[ii] == '"':
+			inQuote = !inQuote
+		case !inQuote && strings.HasPrefix(_line[ii:], _sep):
+			count++
+			ii += len(_sep) - 1
+		}
+	}
+	return count
+}
@@ -1,6 +1,7 @@
 package genutil
 
 import (
+	"cmp"
 	"sort"
 )
 
@@ -75,3 +76,73 @@ func SortedKeys_Int2Int(_mp *map[int]int) []int {
 	sort.Ints(keys)
 	return keys
 }
+
+// SortedKeys_Int642Int returns sorted keys of that type
+func SortedKeys_Int642Int(_mp *map[int64]int) []int64 {
+	keys := make([]int64, len(*_mp))
+	ii := 0
+	for kk := range *_mp {
+		keys[ii] = kk
+		ii++
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// SortedKeys_Int642Int64 returns sorted keys of that type
+func SortedKeys_Int642Int64(_mp *map[int64]int64) []int64 {
+	keys := make([]int64, len(*_mp))
+	ii := 0
+	for kk := range *_mp {
+		keys[ii] = kk
+		ii++
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// SortedKeys_Int642Float64 returns sorted keys of that type
+func SortedKeys_Int642Float64(_mp *map[int64]float64) []int64 {
+	keys := make([]int64, len(*_mp))
+	ii := 0
+	for kk := range *_mp {
+		keys[ii] = kk
+		ii++
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// SortedKeys_Int642Bool returns sorted keys of that type
+func SortedKeys_Int642Bool(_mp *map[int64]bool) []int64 {
+	keys := make([]int64, len(*_mp))
+	ii := 0
+	for kk := range *_mp {
+		keys[ii] = kk
+		ii++
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// SortedKeys_Int642String returns sorted keys of that type
+func SortedKeys_Int642String(_mp *map[int64]string) []int64 {
+	keys := make([]int64, len(*_mp))
+	ii := 0
+	for kk := range *_mp {
+		keys[ii] = kk
+		ii++
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// SortedKeys returns the sorted keys of any map with ordered keys
+func SortedKeys[K cmp.Ordered, V any](_mp map[K]V) []K {
+	keys := make([]K, 0, len(_mp))
+	for kk := range _mp {
+		keys = append(keys, kk)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
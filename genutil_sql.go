@@ -0,0 +1,237 @@
+package genutil
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RowsToDelimited writes a header line of column names and then each row, joined by the separator (named as in SepMap)
+// and quoted by CsvJoin where needed. NULLs are written as _nullRepr, columns of type DATE as YYYYMMDD, other times
+// as "YYYYMMDD HHMMSS", and floats in plain decimal without an exponent. It returns the number of rows written.
+// The rows are not closed.
+func RowsToDelimited(_rows *sql.Rows, _ww io.Writer, _sep, _nullRepr string) (int64, error) {
+	sep := StrAorB(SepMap(_sep, true), _sep)
+	cols, err := _rows.ColumnTypes()
+	if err != nil {
+		return 0, fmt.Errorf("genutil.RowsToDelimited: %v", err)
+	}
+	names := make([]string, len(cols))
+	for ii, col := range cols {
+		names[ii] = col.Name()
+	}
+	if _, err = io.WriteString(_ww, CsvJoin(names, sep)+"\n"); err != nil {
+		return 0, fmt.Errorf("genutil.RowsToDelimited: %v", err)
+	}
+	vals := make([]interface{}, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for ii := range vals {
+		ptrs[ii] = &vals[ii]
+	}
+	fields := make([]string, len(cols))
+	nrows := int64(0)
+	for _rows.Next() {
+		if err = _rows.Scan(ptrs...); err != nil {
+			return nrows, fmt.Errorf("genutil.RowsToDelimited: row %d: %v", nrows+1, err)
+		}
+		for ii, val := range vals {
+			fields[ii] = sqlValueString(val, cols[ii].DatabaseTypeName(), _nullRepr)
+		}
+		if _, err = io.WriteString(_ww, CsvJoin(fields, sep)+"\n"); err != nil {
+			return nrows, fmt.Errorf("genutil.RowsToDelimited: %v", err)
+		}
+		nrows++
+	}
+	if err = _rows.Err(); err != nil {
+		return nrows, fmt.Errorf("genutil.RowsToDelimited: %v", err)
+	}
+	return nrows, nil
+}
+
+// sqlValueString formats one scanned value for a flat file
+func sqlValueString(_val interface{}, _dbType, _nullRepr string) string {
+	switch vv := _val.(type) {
+	case nil:
+		return _nullRepr
+	case []byte:
+		return string(vv)
+	case string:
+		return vv
+	case float64:
+		return strconv.FormatFloat(vv, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(vv), 'f', -1, 32)
+	case int64:
+		return strconv.FormatInt(vv, 10)
+	case bool:
+		return strconv.FormatBool(vv)
+	case time.Time:
+		if strings.ToUpper(_dbType) == "DATE" { // by the column's type, so a midnight timestamp keeps its time
+			return vv.Format("20060102")
+		}
+		return vv.Format("20060102 150405")
+	}
+	return fmt.Sprint(_val)
+}
+
+// QueryToFile runs the query and streams the result through RowsToDelimited into the file, compressed if the
+// name ends in .gz, with NULLs written as empty fields. It returns the number of rows written.
+//
+//	nn, err := genutil.QueryToFile(db, "select * from trades where tradedate = $1", "trades.csv.gz", "comma", 20240102)
+func QueryToFile(_db *sql.DB, _query, _outfname, _sep string, _args ...interface{}) (int64, error) {
+	rows, err := _db.Query(_query, _args...)
+	if err != nil {
+		return 0, fmt.Errorf("genutil.QueryToFile: %v", err)
+	}
+	defer rows.Close()
+	gzf := OpenGzFile(_outfname)
+	defer gzf.Close()
+	nn, err := RowsToDelimited(rows, gzf, _sep, "")
+	if err != nil {
+		return nn, fmt.Errorf("genutil.QueryToFile: %s: %v", _outfname, err)
+	}
+	return nn, nil
+}
+
+// SQLNull is the field value WriteCopyFile writes as a NULL, which is also how both dialects spell it in the file
+const SQLNull = `\N`
+
+// copyEscapers escape a field for the text formats of PostgreSQL COPY and MySQL LOAD DATA with default options
+var copyEscapers = map[string]*strings.Replacer{
+	"postgres": strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`),
+	"mysql":    strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`, "\x00", `\0`),
+}
+
+// WriteCopyFile writes the rows as a tab separated file that loads with PostgreSQL COPY (dialect "postgres" or "pg")
+// or MySQL LOAD DATA INFILE (dialect "mysql") with no extra options, escaping backslashes, tabs and newlines in the
+// values. A field equal to SQLNull is left as the NULL marker. A non-empty header is written first,
+// which needs COPY ... HEADER or LOAD DATA ... IGNORE 1 LINES. It returns the number of rows written.
+func WriteCopyFile(_fname string, _header []string, _rows <-chan []string, _dialect string) (int64, error) {
+	esc, ok := copyEscapers[sqlDialect(_dialect)]
+	if !ok {
+		for range _rows {
+		}
+		return 0, fmt.Errorf("genutil.WriteCopyFile: unknown dialect %s", _dialect)
+	}
+	line := func(_fields []string) string {
+		parts := make([]string, len(_fields))
+		for ii, field := range _fields {
+			parts[ii] = field
+			if field != SQLNull {
+				parts[ii] = esc.Replace(field)
+			}
+		}
+		return strings.Join(parts, "\t") + "\n"
+	}
+	gzf := OpenGzFile(_fname)
+	defer gzf.Close()
+	if len(_header) > 0 {
+		if _, err := gzf.WriteString(line(_header)); err != nil {
+			for range _rows {
+			}
+			return 0, fmt.Errorf("genutil.WriteCopyFile: %s: %v", _fname, err)
+		}
+	}
+	nrows := int64(0)
+	for row := range _rows {
+		// on failure drain the rest so the producer is not left blocked
+		if _, err := gzf.WriteString(line(row)); err != nil {
+			for range _rows {
+			}
+			return nrows, fmt.Errorf("genutil.WriteCopyFile: %s: %v", _fname, err)
+		}
+		nrows++
+	}
+	return nrows, nil
+}
+
+// sqlDialect normalizes the dialect names, anything unknown comes back as is
+func sqlDialect(_dialect string) string {
+	dialect := strings.ToLower(_dialect)
+	switch dialect {
+	case "pg", "postgresql", "psql":
+		return "postgres"
+	case "mariadb":
+		return "mysql"
+	}
+	return dialect
+}
+
+// sqlStringEscapers escape the inside of a quoted string literal; postgres assumes standard_conforming_strings
+var sqlStringEscapers = map[string]*strings.Replacer{
+	"postgres": strings.NewReplacer(`'`, `''`),
+	"mysql":    strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\x00", `\0`, "\n", `\n`, "\r", `\r`, "\x1a", `\Z`),
+}
+
+// QuoteSQLString returns the value as a quoted string literal for the dialect ("postgres" or "mysql"),
+// doubling embedded quotes for postgres and backslash escaping for mysql.
+// SQLNull becomes NULL. An unknown dialect is treated as postgres, which is standard SQL.
+func QuoteSQLString(_ss, _dialect string) string {
+	if _ss == SQLNull {
+		return "NULL"
+	}
+	esc, ok := sqlStringEscapers[sqlDialect(_dialect)]
+	if !ok {
+		esc = sqlStringEscapers["postgres"]
+	}
+	return "'" + esc.Replace(_ss) + "'"
+}
+
+// QuoteSQLIdent returns the table or column name quoted for the dialect, "name" or `name`, doubling embedded quotes.
+// A dotted name like schema.table is quoted part by part.
+func QuoteSQLIdent(_name, _dialect string) string {
+	quote := `"`
+	if sqlDialect(_dialect) == "mysql" {
+		quote = "`"
+	}
+	parts := strings.Split(_name, ".")
+	for ii, part := range parts {
+		parts[ii] = quote + strings.ReplaceAll(part, quote, quote+quote) + quote
+	}
+	return strings.Join(parts, ".")
+}
+
+// SQLInsertBatch is the number of rows BuildInsert puts in each statement
+var SQLInsertBatch = 500
+
+// BuildInsert returns INSERT statements for the rows, SQLInsertBatch rows per statement, with every name quoted
+// by QuoteSQLIdent and every value by QuoteSQLString. A row whose length differs from the columns is an error.
+//
+//	stmts, err := genutil.BuildInsert("ref.sector", []string{"ticker", "sector"}, rows, "postgres")
+func BuildInsert(_table string, _cols []string, _rows [][]string, _dialect string) ([]string, error) {
+	if len(_cols) == 0 {
+		return nil, fmt.Errorf("genutil.BuildInsert: no columns")
+	}
+	cols := make([]string, len(_cols))
+	for ii, col := range _cols {
+		cols[ii] = QuoteSQLIdent(col, _dialect)
+	}
+	prefix := "INSERT INTO " + QuoteSQLIdent(_table, _dialect) + " (" + strings.Join(cols, ", ") + ") VALUES\n"
+	batch := MaxInt(SQLInsertBatch, 1)
+	stmts := []string{}
+	vals := make([]string, len(_cols))
+	var sb strings.Builder
+	for ii, row := range _rows {
+		if len(row) != len(_cols) {
+			return nil, fmt.Errorf("genutil.BuildInsert: row %d has %d fields, want %d", ii+1, len(row), len(_cols))
+		}
+		if ii%batch == 0 {
+			sb.WriteString(prefix)
+		} else {
+			sb.WriteString(",\n")
+		}
+		for jj, field := range row {
+			vals[jj] = QuoteSQLString(field, _dialect)
+		}
+		sb.WriteString("(" + strings.Join(vals, ", ") + ")")
+		if ii%batch == batch-1 || ii == len(_rows)-1 {
+			sb.WriteString(";")
+			stmts = append(stmts, sb.String())
+			sb.Reset()
+		}
+	}
+	return stmts, nil
+}
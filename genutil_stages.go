@@ -0,0 +1,120 @@
+package genutil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// StageResult is the outcome of one Stages.Run
+type StageResult struct {
+	Name     string
+	Duration time.Duration
+	Err      error
+	Skipped  bool
+}
+
+// Stages runs the steps of a linear script in order, timing each, and summarizes at the end:
+//
+//	st := genutil.NewStages(logger)
+//	st.Run("load", load)
+//	st.Run("transform", transform)
+//	if err := st.Summary(); err != nil {
+//		os.Exit(1)
+//	}
+//
+// Once a stage fails the later ones are skipped. A panic in a stage is recovered as its error.
+type Stages struct {
+	logger  *log.Logger
+	results []StageResult
+	failed  bool
+}
+
+// NewStages returns a runner logging stage start and end to the logger, which may be nil
+func NewStages(_logger *log.Logger) *Stages {
+	return &Stages{logger: _logger}
+}
+
+// Run runs the stage unless an earlier one failed, sets it as the heartbeat stage, and returns its error
+func (us *Stages) Run(_name string, _fn func() error) error {
+	if us.failed {
+		us.results = append(us.results, StageResult{Name: _name, Skipped: true})
+		us.logf("stage %s skipped", _name)
+		return nil
+	}
+	SetHeartbeatStage(_name)
+	us.logf("stage %s starting", _name)
+	start := time.Now()
+	err := stageCall(_fn)
+	res := StageResult{Name: _name, Duration: time.Since(start), Err: err}
+	us.results = append(us.results, res)
+	if err != nil {
+		us.failed = true
+		us.logf("stage %s failed after %s: %v", _name, res.Duration.Round(time.Millisecond), err)
+	} else {
+		us.logf("stage %s done in %s", _name, res.Duration.Round(time.Millisecond))
+	}
+	return err
+}
+
+// stageCall turns a panic in the stage into an error with the short stack
+func stageCall(_fn func() error) (err error) {
+	defer func() {
+		if rr := recover(); rr != nil {
+			err = fmt.Errorf("panic: %v : stack=%s", rr, shortStackFrom(4, 8))
+		}
+	}()
+	return _fn()
+}
+
+func (us *Stages) logf(_format string, _args ...interface{}) {
+	if us.logger != nil {
+		us.logger.Printf(_format, _args...)
+	}
+}
+
+// Results returns the outcome of each stage run so far
+func (us *Stages) Results() []StageResult {
+	return us.results
+}
+
+// Summary prints the stage table to stdout, see SummaryTo
+func (us *Stages) Summary() error {
+	return us.SummaryTo(os.Stdout)
+}
+
+// SummaryTo prints a table of stage, status, duration and error, colored when ColorEnabled,
+// and returns the stage errors joined, each prefixed by its stage name, or nil if all succeeded
+func (us *Stages) SummaryTo(_ww io.Writer) error {
+	return summarizeStages(_ww, us.results)
+}
+
+// summarizeStages is SummaryTo for any list of results, the total is the sum of the durations
+func summarizeStages(_ww io.Writer, _results []StageResult) error {
+	tbl := NewTable("stage", "status", "secs", "error").SetAlign(2, TableAlignRight)
+	errs := []error{}
+	total := time.Duration(0)
+	for _, res := range _results {
+		status, errstr := "ok", ""
+		switch {
+		case res.Skipped:
+			status = "skipped"
+		case res.Err != nil:
+			status, errstr = "FAILED", StrCappedRunes(res.Err.Error(), 80, true)
+			errs = append(errs, fmt.Errorf("stage %s: %w", res.Name, res.Err))
+		}
+		if ColorEnabled() && !res.Skipped {
+			status = Colorize(res.Err == nil, status)
+		}
+		tbl.AddRow(res.Name, status, fmt.Sprintf("%.3f", res.Duration.Seconds()), errstr)
+		total += res.Duration
+	}
+	tbl.AddRow("total", "", fmt.Sprintf("%.3f", total.Seconds()), "")
+	if err := tbl.Render(_ww); err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
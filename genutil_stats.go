@@ -0,0 +1,95 @@
+package genutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// WeightedMean returns sum(v*w)/sum(w), or an error if the lengths differ or the weights sum to zero
+func WeightedMean(_values, _weights []float64) (float64, error) {
+	if len(_values) != len(_weights) {
+		return 0, fmt.Errorf("WeightedMean: %d values but %d weights", len(_values), len(_weights))
+	}
+	var vw VWAP
+	for ii, val := range _values {
+		vw.Add(val, _weights[ii])
+	}
+	mean, ok := vw.Mean()
+	if !ok {
+		return 0, fmt.Errorf("WeightedMean: weights sum to zero")
+	}
+	return mean, nil
+}
+
+// VWAP accumulates value*weight pairs, typically price and quantity, and finalizes to the weighted mean.
+// The zero value is ready to use. Signed quantities should be passed as their absolute value.
+type VWAP struct {
+	sumVW float64
+	sumW  float64
+	count int
+}
+
+// Add accumulates one value with its weight
+func (us *VWAP) Add(_value, _weight float64) {
+	us.sumVW += _value * _weight
+	us.sumW += _weight
+	us.count++
+}
+
+// AddStr accumulates a price/qty string pair, empty strings are skipped as a zero weight
+func (us *VWAP) AddStr(_value, _weight string) error {
+	_value, _weight = strings.TrimSpace(_value), strings.TrimSpace(_weight)
+	if len(_value) == 0 || len(_weight) == 0 {
+		return nil
+	}
+	val, err := strconv.ParseFloat(_value, 64)
+	if err != nil {
+		return fmt.Errorf("VWAP.AddStr: bad value(%s)", _value)
+	}
+	wt, err := strconv.ParseFloat(_weight, 64)
+	if err != nil {
+		return fmt.Errorf("VWAP.AddStr: bad weight(%s)", _weight)
+	}
+	us.Add(val, wt)
+	return nil
+}
+
+// Mean returns the weighted mean, false if nothing has been added or the weights sum to zero
+func (us *VWAP) Mean() (float64, bool) {
+	if us.sumW == 0 {
+		return 0, false
+	}
+	return us.sumVW / us.sumW, true
+}
+
+// SumWeights returns the total weight, eg the traded quantity
+func (us *VWAP) SumWeights() float64 { return us.sumW }
+
+// SumProducts returns the total value*weight, eg the traded notional
+func (us *VWAP) SumProducts() float64 { return us.sumVW }
+
+// Count returns the number of pairs added
+func (us *VWAP) Count() int { return us.count }
+
+// Reset clears the accumulator
+func (us *VWAP) Reset() { *us = VWAP{} }
+
+// StrWeightedMean is WeightedMean over price/qty string pairs in the "%f" format of the Str* helpers,
+// returning _def if a string is bad or the weights sum to zero. Pairs with an empty member are skipped.
+func StrWeightedMean(_values, _weights []string, _def string) string {
+	if len(_values) != len(_weights) {
+		return _def
+	}
+	var vw VWAP
+	for ii, val := range _values {
+		if vw.AddStr(val, _weights[ii]) != nil {
+			return _def
+		}
+	}
+	mean, ok := vw.Mean()
+	if !ok {
+		return _def
+	}
+	return sprintfF(mean)
+}
@@ -0,0 +1,169 @@
+package genutil
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Basic 16 colors for Style.Fg and Style.Bg, values 16-255 select from the 256-color palette
+const (
+	ColorBlack = iota
+	ColorRed
+	ColorGreen
+	ColorYellow
+	ColorBlue
+	ColorMagenta
+	ColorCyan
+	ColorWhite
+	ColorBrightBlack
+	ColorBrightRed
+	ColorBrightGreen
+	ColorBrightYellow
+	ColorBrightBlue
+	ColorBrightMagenta
+	ColorBrightCyan
+	ColorBrightWhite
+)
+
+// Color modes for SetColorMode
+const (
+	ColorModeAuto   = 0 // color only if stdout is a terminal and NO_COLOR is unset
+	ColorModeAlways = 1
+	ColorModeNever  = 2
+)
+
+var colorMode = ColorModeAuto
+
+// SetColorMode overrides the automatic detection used by Style and Colorize
+func SetColorMode(_mode int) {
+	colorMode = _mode
+}
+
+// ColorEnabled informs if Style will emit escape codes
+func ColorEnabled() bool {
+	switch colorMode {
+	case ColorModeAlways:
+		return true
+	case ColorModeNever:
+		return false
+	}
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	stat, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return (stat.Mode() & os.ModeCharDevice) != 0
+}
+
+// Style accumulates ANSI attributes, for example NewStyle().Fg(ColorYellow).Bold().Sprint("warn")
+type Style struct {
+	codes []string
+}
+
+// NewStyle returns a style without attributes
+func NewStyle() *Style {
+	return &Style{}
+}
+
+// Fg sets the foreground color, 0-15 for the basic colors and 16-255 for the extended palette
+func (us *Style) Fg(_color int) *Style {
+	us.codes = append(us.codes, styleColorCode(_color, 30, 90, "38"))
+	return us
+}
+
+// Bg sets the background color, 0-15 for the basic colors and 16-255 for the extended palette
+func (us *Style) Bg(_color int) *Style {
+	us.codes = append(us.codes, styleColorCode(_color, 40, 100, "48"))
+	return us
+}
+
+// Bold adds bold
+func (us *Style) Bold() *Style {
+	us.codes = append(us.codes, "1")
+	return us
+}
+
+// Dim adds faint intensity
+func (us *Style) Dim() *Style {
+	us.codes = append(us.codes, "2")
+	return us
+}
+
+// Underline adds underline
+func (us *Style) Underline() *Style {
+	us.codes = append(us.codes, "4")
+	return us
+}
+
+// Reverse swaps foreground and background
+func (us *Style) Reverse() *Style {
+	us.codes = append(us.codes, "7")
+	return us
+}
+
+// Sprint wraps the string in the style's escape codes, or returns it unchanged if color is disabled
+func (us *Style) Sprint(_str string) string {
+	if len(us.codes) == 0 || !ColorEnabled() {
+		return _str
+	}
+	return "\033[" + strings.Join(us.codes, ";") + "m" + _str + "\033[0m"
+}
+
+// Sprintf formats and then styles
+func (us *Style) Sprintf(_format string, _args ...interface{}) string {
+	return us.Sprint(fmt.Sprintf(_format, _args...))
+}
+
+// styleColorCode returns the SGR parameter for a color
+func styleColorCode(_color, _base, _brightBase int, _extended string) string {
+	switch {
+	case _color >= 0 && _color < 8:
+		return fmt.Sprintf("%d", _base+_color)
+	case _color >= 8 && _color < 16:
+		return fmt.Sprintf("%d", _brightBase+_color-8)
+	case _color >= 16 && _color < 256:
+		return fmt.Sprintf("%s;5;%d", _extended, _color)
+	}
+	panic(fmt.Sprintf("genutil.Style: color(%d) out of range 0-255", _color))
+}
+
+// Colorize renders the string in green if the condition holds, else in red
+func Colorize(_ok bool, _str string) string {
+	if _ok {
+		return NewStyle().Fg(ColorGreen).Sprint(_str)
+	}
+	return NewStyle().Fg(ColorRed).Sprint(_str)
+}
+
+// ColorizeBold is the bold variant of Colorize
+func ColorizeBold(_ok bool, _str string) string {
+	if _ok {
+		return NewStyle().Bold().Fg(ColorGreen).Sprint(_str)
+	}
+	return NewStyle().Bold().Fg(ColorRed).Sprint(_str)
+}
+
+// ColorizeIf applies the style only if the condition holds
+func ColorizeIf(_cond bool, _style *Style, _str string) string {
+	if _cond {
+		return _style.Sprint(_str)
+	}
+	return _str
+}
+
+var ansiEscape = regexp.MustCompile("\033\\[[0-9;]*m")
+
+// StripANSI removes the color escapes added by Style, Colorize, Red, Green and friends
+func StripANSI(_str string) string {
+	return ansiEscape.ReplaceAllString(_str, "")
+}
+
+// DisplayWidth is the number of runes shown on a terminal, not counting color escapes
+func DisplayWidth(_str string) int {
+	return utf8.RuneCountInString(StripANSI(_str))
+}
@@ -0,0 +1,67 @@
+package genutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AtomicSymlinkSwap points linkName at target by creating a new symlink and renaming it over the old one,
+// so readers never see a missing link. An existing linkName that is a real dir is refused.
+func AtomicSymlinkSwap(_target, _linkName string) error {
+	if fi, err := os.Lstat(_linkName); err == nil && fi.IsDir() {
+		return fmt.Errorf("genutil.AtomicSymlinkSwap: linkName(%s) is a dir", _linkName)
+	}
+	tmpname := fmt.Sprintf("%s.tmp%d", _linkName, os.Getpid())
+	auditedRemove(tmpname)
+	if err := auditedSymlink(_target, tmpname); err != nil {
+		return fmt.Errorf("genutil.AtomicSymlinkSwap: %v", err)
+	}
+	if err := auditedRename(tmpname, _linkName); err != nil {
+		auditedRemove(tmpname)
+		return fmt.Errorf("genutil.AtomicSymlinkSwap: %v", err)
+	}
+	return nil
+}
+
+// ResolveSymlink returns the absolute path with all symlinks followed, a path without symlinks is returned cleaned
+func ResolveSymlink(_path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(_path)
+	if err != nil {
+		return "", fmt.Errorf("genutil.ResolveSymlink: %v", err)
+	}
+	return filepath.Abs(resolved)
+}
+
+// LatestLinkUpdater repoints a "latest" symlink at the newest dated file in dir matching the glob pattern,
+// where newest means last in NaturalLess order of the names, so positions.20240105.csv beats positions.20240104.csv.
+// The link is named by replacing the first * in the pattern with "latest" (positions.latest.csv), and points
+// at the file by its relative name. It returns the file linked to.
+func LatestLinkUpdater(_dir, _pattern string) (string, error) {
+	if !strings.Contains(_pattern, "*") {
+		return "", fmt.Errorf("genutil.LatestLinkUpdater: pattern(%s) lacks *", _pattern)
+	}
+	linkName := strings.Replace(_pattern, "*", "latest", 1)
+	matches, err := filepath.Glob(filepath.Join(_dir, _pattern))
+	if err != nil {
+		return "", fmt.Errorf("genutil.LatestLinkUpdater: %v", err)
+	}
+	newest := ""
+	for _, match := range matches {
+		base := filepath.Base(match)
+		if fi, err := os.Lstat(match); err != nil || !fi.Mode().IsRegular() || base == linkName {
+			continue
+		}
+		if newest == "" || NaturalLess(newest, base) {
+			newest = base
+		}
+	}
+	if newest == "" {
+		return "", fmt.Errorf("genutil.LatestLinkUpdater: no files match %s in %s", _pattern, _dir)
+	}
+	if err = AtomicSymlinkSwap(newest, filepath.Join(_dir, linkName)); err != nil {
+		return "", err
+	}
+	return filepath.Join(_dir, newest), nil
+}
@@ -0,0 +1,255 @@
+package genutil
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Column alignments understood by Table.SetAlign
+const (
+	TableAlignAuto   = 0
+	TableAlignLeft   = 1
+	TableAlignRight  = 2
+	TableAlignCenter = 3
+)
+
+// Table accumulates rows for column-aligned rendering in script reports
+type Table struct {
+	header    []string
+	rows      [][]string
+	align     map[int]int
+	thousands map[int]bool
+	redNeg    bool
+	colsep    string
+}
+
+// NewTable returns an empty table with the given column headers
+func NewTable(_header ...string) *Table {
+	return &Table{
+		header:    append([]string(nil), _header...),
+		align:     map[int]int{},
+		thousands: map[int]bool{},
+		colsep:    "  ",
+	}
+}
+
+// AddRow appends a row, formatting non-string cells with fmt.Sprint
+func (us *Table) AddRow(_cells ...interface{}) *Table {
+	row := make([]string, len(_cells))
+	for ii, cell := range _cells {
+		switch vv := cell.(type) {
+		case string:
+			row[ii] = vv
+		case float64:
+			row[ii] = fmt.Sprintf("%f", vv)
+		default:
+			row[ii] = fmt.Sprint(vv)
+		}
+	}
+	us.rows = append(us.rows, row)
+	return us
+}
+
+// NumRows returns the number of rows added so far
+func (us *Table) NumRows() int {
+	return len(us.rows)
+}
+
+// SetAlign sets the alignment of a column, TableAlignAuto right-aligns columns that are entirely numeric
+func (us *Table) SetAlign(_col, _align int) *Table {
+	us.align[_col] = _align
+	return us
+}
+
+// SetThousands formats the numeric cells of the listed columns with Thousands
+func (us *Table) SetThousands(_cols ...int) *Table {
+	for _, col := range _cols {
+		us.thousands[col] = true
+	}
+	return us
+}
+
+// SetRedNegatives colors negative numeric cells with Red in Render if ColorEnabled, never in RenderCSV or RenderMarkdown
+func (us *Table) SetRedNegatives(_on bool) *Table {
+	us.redNeg = _on
+	return us
+}
+
+// SetColSep sets the separator placed between columns by Render, default is two spaces
+func (us *Table) SetColSep(_sep string) *Table {
+	us.colsep = _sep
+	return us
+}
+
+// numCols returns the widest of header and rows
+func (us *Table) numCols() int {
+	ncol := len(us.header)
+	for _, row := range us.rows {
+		ncol = MaxInt(ncol, len(row))
+	}
+	return ncol
+}
+
+// cell returns the formatted content of a cell, blank if the row is short
+func (us *Table) cell(_row []string, _col int) string {
+	if _col >= len(_row) {
+		return ""
+	}
+	str := _row[_col]
+	if us.thousands[_col] && tableIsNumeric(str) {
+		return Thousands(StrToFloat(str))
+	}
+	return str
+}
+
+// tableIsNumeric checks if the cell parses as a number
+func tableIsNumeric(_str string) bool {
+	_, err := strconv.ParseFloat(strings.TrimSpace(_str), 64)
+	return err == nil
+}
+
+// rightAligned decides the alignment of a column
+func (us *Table) rightAligned(_col int) bool {
+	switch us.align[_col] {
+	case TableAlignLeft:
+		return false
+	case TableAlignRight:
+		return true
+	}
+	seen := false
+	for _, row := range us.rows {
+		str := us.cell(row, _col)
+		if str == "" {
+			continue
+		}
+		if !tableIsNumeric(strings.Replace(str, ",", "", -1)) {
+			return false
+		}
+		seen = true
+	}
+	return seen
+}
+
+// Render writes the table with padded columns, a header underline, and optional coloring.
+// Widths are counted in runes without color escapes, so non-ASCII and colored text line up.
+func (us *Table) Render(_ww io.Writer) error {
+	ncol := us.numCols()
+	widths := make([]int, ncol)
+	right := make([]bool, ncol)
+	for col := 0; col < ncol; col++ {
+		if col < len(us.header) {
+			widths[col] = DisplayWidth(us.header[col])
+		}
+		for _, row := range us.rows {
+			widths[col] = MaxInt(widths[col], DisplayWidth(us.cell(row, col)))
+		}
+		right[col] = us.rightAligned(col)
+	}
+	line := func(_row []string, _isHeader bool) string {
+		parts := make([]string, ncol)
+		for col := 0; col < ncol; col++ {
+			str := _row[col]
+			if !_isHeader {
+				str = us.cell(_row, col)
+			}
+			pad := strings.Repeat(" ", widths[col]-DisplayWidth(str))
+			if !_isHeader && us.redNeg && ColorEnabled() && strings.HasPrefix(strings.TrimSpace(str), "-") && tableIsNumeric(strings.Replace(str, ",", "", -1)) {
+				str = Red(str)
+			}
+			switch {
+			case us.align[col] == TableAlignCenter:
+				left := len(pad) / 2
+				parts[col] = pad[:left] + str + pad[left:]
+			case right[col]:
+				parts[col] = pad + str
+			default:
+				parts[col] = str + pad
+			}
+		}
+		return strings.TrimRight(strings.Join(parts, us.colsep), " ") + "\n"
+	}
+	if len(us.header) > 0 {
+		header := append([]string(nil), us.header...)
+		for len(header) < ncol {
+			header = append(header, "")
+		}
+		if _, err := io.WriteString(_ww, line(header, true)); err != nil {
+			return err
+		}
+		dashes := make([]string, ncol)
+		for col := range dashes {
+			dashes[col] = strings.Repeat("-", widths[col])
+		}
+		if _, err := io.WriteString(_ww, strings.Join(dashes, us.colsep)+"\n"); err != nil {
+			return err
+		}
+	}
+	for _, row := range us.rows {
+		full := append([]string(nil), row...)
+		for len(full) < ncol {
+			full = append(full, "")
+		}
+		if _, err := io.WriteString(_ww, line(full, false)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenderCSV writes the table as csv, without Thousands formatting so the output stays machine readable
+func (us *Table) RenderCSV(_ww io.Writer) error {
+	cw := csv.NewWriter(_ww)
+	if len(us.header) > 0 {
+		if err := cw.Write(us.header); err != nil {
+			return err
+		}
+	}
+	for _, row := range us.rows {
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// RenderMarkdown writes the table as a github-flavored markdown table
+func (us *Table) RenderMarkdown(_ww io.Writer) error {
+	ncol := us.numCols()
+	escape := func(_str string) string {
+		return strings.Replace(strings.Replace(_str, "|", "\\|", -1), "\n", " ", -1)
+	}
+	row2md := func(_row []string, _isHeader bool) string {
+		parts := make([]string, ncol)
+		for col := 0; col < ncol; col++ {
+			str := ""
+			switch {
+			case _isHeader && col < len(_row):
+				str = _row[col]
+			case !_isHeader:
+				str = us.cell(_row, col)
+			}
+			parts[col] = escape(str)
+		}
+		return "| " + strings.Join(parts, " | ") + " |\n"
+	}
+	if _, err := io.WriteString(_ww, row2md(us.header, true)); err != nil {
+		return err
+	}
+	seps := make([]string, ncol)
+	for col := range seps {
+		seps[col] = StrTernary(us.rightAligned(col), "---:", "---")
+	}
+	if _, err := io.WriteString(_ww, "| "+strings.Join(seps, " | ")+" |\n"); err != nil {
+		return err
+	}
+	for _, row := range us.rows {
+		if _, err := io.WriteString(_ww, row2md(row, false)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
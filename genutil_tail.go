@@ -0,0 +1,148 @@
+package genutil
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TailPollInterval is how often TailFollow checks the file for new lines, rotation and truncation.
+// It polls rather than using inotify, which does not see writes made on other hosts to NFS files.
+var TailPollInterval = time.Second
+
+// tailState is the file TailFollow has open
+type tailState struct {
+	fo      *os.File
+	bio     *bufio.Reader
+	info    os.FileInfo
+	offset  int64  // bytes consumed, including the partial line
+	partial string // text after the last newline, held until the line is complete
+}
+
+// TailFollow calls fn with each line appended to the file, without the newline, like `tail -F`: from the end of the file
+// if fromEnd is set, else from the start. When the file is replaced (rotated) the rest of the old one is read and
+// the new one followed from its start; when it is truncated it is followed from the start again. A missing file is
+// waited for and read from its start. fn is called on the follower's goroutine, one line at a time. It returns the stop func, which is
+// safe to call more than once; fn is not called after the stop is seen, at the latest one TailPollInterval later.
+func TailFollow(_fname string, _fromEnd bool, _fn func(_line string)) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		var ts *tailState
+		lastErr := ""
+		report := func(err error) {
+			if msg := err.Error(); msg != lastErr {
+				fmt.Fprintf(os.Stderr, "genutil.TailFollow: %s\n", msg)
+				lastErr = msg
+			}
+		}
+		defer func() {
+			if ts != nil {
+				ts.fo.Close()
+			}
+		}()
+		seekEnd := _fromEnd
+		for {
+			if ts == nil {
+				var err error
+				if ts, err = tailOpen(_fname, seekEnd); err != nil {
+					if !os.IsNotExist(err) {
+						report(err)
+					}
+				} else {
+					lastErr = ""
+				}
+				seekEnd = false // later files are new, so read from their start
+			}
+			if ts != nil {
+				if err := ts.readLines(done, _fn); err != nil {
+					report(err)
+				}
+				if cur, err := os.Stat(_fname); err == nil {
+					switch {
+					case !os.SameFile(ts.info, cur):
+						// rotated: finish the old file, including an unterminated last line, then open the new one
+						if err := ts.readLines(done, _fn); err != nil {
+							report(err)
+						}
+						select {
+						case <-done: // readLines may have stopped early for it, and fn must not be called now
+							return
+						default:
+						}
+						if ts.partial != "" {
+							_fn(strings.TrimRight(ts.partial, "\r"))
+						}
+						ts.fo.Close()
+						ts = nil
+						continue
+					case cur.Size() < ts.offset:
+						if _, err := ts.fo.Seek(0, io.SeekStart); err != nil {
+							report(err)
+						}
+						ts.bio.Reset(ts.fo)
+						ts.offset, ts.partial = 0, ""
+						continue
+					}
+				}
+			}
+			select {
+			case <-done:
+				return
+			case <-time.After(TailPollInterval):
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// tailOpen opens the file, at its end if asked
+func tailOpen(_fname string, _seekEnd bool) (*tailState, error) {
+	fo, err := os.Open(_fname)
+	if err != nil {
+		return nil, err
+	}
+	info, err := fo.Stat()
+	if err != nil {
+		fo.Close()
+		return nil, err
+	}
+	ts := &tailState{fo: fo, info: info}
+	if _seekEnd {
+		if ts.offset, err = fo.Seek(0, io.SeekEnd); err != nil {
+			fo.Close()
+			return nil, err
+		}
+	}
+	ts.bio = bufio.NewReaderSize(fo, 64*1024)
+	return ts, nil
+}
+
+// readLines passes every complete line now in the file to fn, keeping a final partial line for the next call
+func (us *tailState) readLines(_done <-chan struct{}, _fn func(_line string)) error {
+	for {
+		select {
+		case <-_done:
+			return nil
+		default:
+		}
+		chunk, err := us.bio.ReadString('\n')
+		us.offset += int64(len(chunk))
+		if strings.HasSuffix(chunk, "\n") {
+			_fn(strings.TrimRight(us.partial+chunk, "\r\n"))
+			us.partial = ""
+		} else {
+			us.partial += chunk
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
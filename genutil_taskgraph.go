@@ -0,0 +1,190 @@
+package genutil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// TaskGraph runs named tasks in dependency order with bounded parallelism:
+//
+//	tg := genutil.NewTaskGraph(4, logger)
+//	tg.Add("prices", loadPrices)
+//	tg.Add("positions", loadPositions)
+//	tg.Add("pnl", computePnl, "prices", "positions")
+//	err := tg.Run()
+//
+// A task runs once all its dependencies have succeeded. When a task fails its dependents are skipped,
+// while independent tasks carry on. A panic in a task is recovered as its error.
+type TaskGraph struct {
+	parallel int
+	logger   *log.Logger
+	order    []string
+	tasks    map[string]*graphTask
+	results  []StageResult
+}
+
+type graphTask struct {
+	name string
+	fn   func() error
+	deps []string
+}
+
+// NewTaskGraph returns an empty graph running at most _parallel tasks at once, logging to the logger, which may be nil
+func NewTaskGraph(_parallel int, _logger *log.Logger) *TaskGraph {
+	return &TaskGraph{parallel: MaxInt(_parallel, 1), logger: _logger, tasks: map[string]*graphTask{}}
+}
+
+// Add adds a task depending on the named tasks, which may be added later. A duplicate name is an error.
+func (us *TaskGraph) Add(_name string, _fn func() error, _deps ...string) error {
+	if _, ok := us.tasks[_name]; ok {
+		return fmt.Errorf("genutil.TaskGraph.Add: duplicate task(%s)", _name)
+	}
+	us.tasks[_name] = &graphTask{name: _name, fn: _fn, deps: _deps}
+	us.order = append(us.order, _name)
+	return nil
+}
+
+// validate checks that dependencies exist and there is no cycle
+func (us *TaskGraph) validate() error {
+	pending := map[string]int{}
+	for _, name := range us.order {
+		for _, dep := range us.tasks[name].deps {
+			if _, ok := us.tasks[dep]; !ok {
+				return fmt.Errorf("genutil.TaskGraph: task(%s) depends on unknown task(%s)", name, dep)
+			}
+		}
+		pending[name] = len(us.tasks[name].deps)
+	}
+	done := 0
+	for changed := true; changed; {
+		changed = false
+		for _, name := range us.order {
+			if pending[name] != 0 {
+				continue
+			}
+			pending[name], changed = -1, true
+			done++
+			for _, other := range us.order {
+				for _, dep := range us.tasks[other].deps {
+					if dep == name {
+						pending[other]--
+					}
+				}
+			}
+		}
+	}
+	if done < len(us.order) {
+		cyclic := FilterSlice(us.order, func(_name string) bool { return pending[_name] > 0 })
+		return fmt.Errorf("genutil.TaskGraph: dependency cycle among tasks %v", cyclic)
+	}
+	return nil
+}
+
+// Run executes the graph and returns the task errors joined, each prefixed by its task name, or nil if all succeeded.
+// A graph with unknown dependencies or a cycle is rejected before anything runs.
+func (us *TaskGraph) Run() error {
+	if err := us.validate(); err != nil {
+		return err
+	}
+	dependents := map[string][]string{}
+	waiting := map[string]int{}
+	for _, name := range us.order {
+		waiting[name] = len(us.tasks[name].deps)
+		for _, dep := range us.tasks[name].deps {
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+	results := map[string]StageResult{}
+	finished := make(chan StageResult)
+	sem := make(chan struct{}, us.parallel)
+	var wg sync.WaitGroup
+	launch := func(_task *graphTask) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			us.logf("task %s starting", _task.name)
+			start := time.Now()
+			err := stageCall(_task.fn)
+			finished <- StageResult{Name: _task.name, Duration: time.Since(start), Err: err}
+		}()
+	}
+	// skip marks the task and, transitively, its dependents as skipped
+	var skip func(_name string)
+	skip = func(_name string) {
+		if _, ok := results[_name]; ok {
+			return
+		}
+		results[_name] = StageResult{Name: _name, Skipped: true}
+		us.logf("task %s skipped", _name)
+		for _, dependent := range dependents[_name] {
+			skip(dependent)
+		}
+	}
+	running := 0
+	for _, name := range us.order {
+		if waiting[name] == 0 {
+			launch(us.tasks[name])
+			running++
+		}
+	}
+	for running > 0 {
+		res := <-finished
+		running--
+		results[res.Name] = res
+		if res.Err != nil {
+			us.logf("task %s failed after %s: %v", res.Name, res.Duration.Round(time.Millisecond), res.Err)
+			for _, dependent := range dependents[res.Name] {
+				skip(dependent)
+			}
+			continue
+		}
+		us.logf("task %s done in %s", res.Name, res.Duration.Round(time.Millisecond))
+		for _, dependent := range dependents[res.Name] {
+			if waiting[dependent]--; waiting[dependent] == 0 {
+				if _, skipped := results[dependent]; !skipped {
+					launch(us.tasks[dependent])
+					running++
+				}
+			}
+		}
+	}
+	wg.Wait()
+	us.results = make([]StageResult, 0, len(us.order))
+	errs := []error{}
+	for _, name := range us.order {
+		res := results[name]
+		us.results = append(us.results, res)
+		if res.Err != nil {
+			errs = append(errs, fmt.Errorf("task %s: %w", name, res.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (us *TaskGraph) logf(_format string, _args ...interface{}) {
+	if us.logger != nil {
+		us.logger.Printf(_format, _args...)
+	}
+}
+
+// Results returns the outcome of each task in the order added, after Run
+func (us *TaskGraph) Results() []StageResult {
+	return us.results
+}
+
+// Summary prints the task table to stdout, see Stages.SummaryTo
+func (us *TaskGraph) Summary() error {
+	return us.SummaryTo(os.Stdout)
+}
+
+// SummaryTo prints the task table as Stages.SummaryTo does, the total is the sum of task times not the elapsed time
+func (us *TaskGraph) SummaryTo(_ww io.Writer) error {
+	return summarizeStages(_ww, us.results)
+}
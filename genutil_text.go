@@ -0,0 +1,306 @@
+package genutil
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// EditDistance returns the Levenshtein distance between the strings, counted in runes
+func EditDistance(_aa, _bb string) int {
+	ra, rb := []rune(_aa), []rune(_bb)
+	if len(ra) < len(rb) {
+		ra, rb = rb, ra
+	}
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for jj := range prev {
+		prev[jj] = jj
+	}
+	for ii := 1; ii <= len(ra); ii++ {
+		curr[0] = ii
+		for jj := 1; jj <= len(rb); jj++ {
+			cost := 1
+			if ra[ii-1] == rb[jj-1] {
+				cost = 0
+			}
+			curr[jj] = MinInt(MinInt(prev[jj]+1, curr[jj-1]+1), prev[jj-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// ClosestMatch returns the candidate with the smallest EditDistance to the target, provided it is within _maxDist.
+// Ties go to the earlier candidate. An exact match is returned immediately.
+func ClosestMatch(_target string, _candidates []string, _maxDist int) (string, bool) {
+	best, bestDist := "", _maxDist+1
+	for _, cand := range _candidates {
+		if cand == _target {
+			return cand, true
+		}
+		if AbsInt(utf8.RuneCountInString(cand)-utf8.RuneCountInString(_target)) >= bestDist {
+			continue // the length difference alone is too far
+		}
+		if dist := EditDistance(_target, cand); dist < bestDist {
+			best, bestDist = cand, dist
+		}
+	}
+	return best, bestDist <= _maxDist
+}
+
+// caseWords splits an identifier or header into words at non-alphanumerics and at case changes,
+// so "PxLast", "px_last", "px-last" and "PX Last" all give [Px Last] in their original case.
+// An uppercase run followed by lowercase keeps its last letter for the next word, as in HTTPServer -> [HTTP Server].
+func caseWords(_str string) []string {
+	words := []string{}
+	rr := []rune(_str)
+	start := -1
+	flush := func(_end int) {
+		if start >= 0 && _end > start {
+			words = append(words, string(rr[start:_end]))
+		}
+		start = -1
+	}
+	for ii, ch := range rr {
+		if !unicode.IsLetter(ch) && !unicode.IsDigit(ch) {
+			flush(ii)
+			continue
+		}
+		if start < 0 {
+			start = ii
+			continue
+		}
+		prev := rr[ii-1]
+		switch {
+		case unicode.IsUpper(ch) && (unicode.IsLower(prev) || unicode.IsDigit(prev)):
+			flush(ii)
+			start = ii
+		case unicode.IsUpper(prev) && unicode.IsUpper(ch) && ii+1 < len(rr) && unicode.IsLower(rr[ii+1]):
+			flush(ii)
+			start = ii
+		}
+	}
+	flush(len(rr))
+	return words
+}
+
+// capitalizeWord uppercases the first rune and lowercases the rest
+func capitalizeWord(_word string) string {
+	rr := []rune(strings.ToLower(_word))
+	if len(rr) > 0 {
+		rr[0] = unicode.ToUpper(rr[0])
+	}
+	return string(rr)
+}
+
+// ToSnakeCase converts "PxLast" or "Px Last" to "px_last"
+func ToSnakeCase(_str string) string {
+	return strings.ToLower(strings.Join(caseWords(_str), "_"))
+}
+
+// ToKebabCase converts "PxLast" or "Px Last" to "px-last"
+func ToKebabCase(_str string) string {
+	return strings.ToLower(strings.Join(caseWords(_str), "-"))
+}
+
+// ToCamelCase converts "px_last" or "Px Last" to "pxLast"
+func ToCamelCase(_str string) string {
+	words := caseWords(_str)
+	for ii := range words {
+		if ii == 0 {
+			words[ii] = strings.ToLower(words[ii])
+		} else {
+			words[ii] = capitalizeWord(words[ii])
+		}
+	}
+	return strings.Join(words, "")
+}
+
+// ToPascalCase converts "px_last" or "Px Last" to "PxLast"
+func ToPascalCase(_str string) string {
+	return strings.Join(MapSlice(caseWords(_str), capitalizeWord), "")
+}
+
+// ToTitleCaseASCII capitalizes each space separated word and lowercases the rest, touching only ASCII letters
+func ToTitleCaseASCII(_str string) string {
+	bb := []byte(_str)
+	atStart := true
+	for ii, ch := range bb {
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n':
+			atStart = true
+			continue
+		case atStart && 'a' <= ch && ch <= 'z':
+			bb[ii] = ch - 'a' + 'A'
+		case !atStart && 'A' <= ch && ch <= 'Z':
+			bb[ii] = ch - 'A' + 'a'
+		}
+		atStart = false
+	}
+	return string(bb)
+}
+
+// StrCappedRunes is StrCapped counting runes rather than bytes, so UTF-8 is never split.
+// With _ellipsis a truncated string ends in "…" and is still at most _cap runes.
+func StrCappedRunes(_str string, _cap int, _ellipsis bool) string {
+	if _cap < 0 {
+		_cap = 0
+	}
+	if utf8.RuneCountInString(_str) <= _cap {
+		return _str
+	}
+	rr := []rune(_str)
+	if _ellipsis && _cap > 0 {
+		return string(rr[:_cap-1]) + "…"
+	}
+	return string(rr[:_cap])
+}
+
+// PadLeft right-justifies the string in _width runes, longer strings are returned unchanged
+func PadLeft(_str string, _width int) string {
+	if nn := utf8.RuneCountInString(_str); nn < _width {
+		return strings.Repeat(" ", _width-nn) + _str
+	}
+	return _str
+}
+
+// PadRight left-justifies the string in _width runes, longer strings are returned unchanged
+func PadRight(_str string, _width int) string {
+	if nn := utf8.RuneCountInString(_str); nn < _width {
+		return _str + strings.Repeat(" ", _width-nn)
+	}
+	return _str
+}
+
+// Center centers the string in _width runes, any odd space goes on the right
+func Center(_str string, _width int) string {
+	nn := utf8.RuneCountInString(_str)
+	if nn >= _width {
+		return _str
+	}
+	left := (_width - nn) / 2
+	return strings.Repeat(" ", left) + _str + strings.Repeat(" ", _width-nn-left)
+}
+
+// FitColumn truncates (with ellipsis) or pads the string to exactly _width runes,
+// _align is one of TableAlignLeft, TableAlignRight or TableAlignCenter
+func FitColumn(_str string, _width, _align int) string {
+	_str = StrCappedRunes(_str, _width, true)
+	switch _align {
+	case TableAlignRight:
+		return PadLeft(_str, _width)
+	case TableAlignCenter:
+		return Center(_str, _width)
+	}
+	return PadRight(_str, _width)
+}
+
+// NormalizeWhitespace collapses each run of unicode whitespace to a single space and trims both ends
+func NormalizeWhitespace(_str string) string {
+	return strings.Join(strings.Fields(_str), " ")
+}
+
+// StripControlChars removes control characters, including tab and newline, and invalid UTF-8
+func StripControlChars(_str string) string {
+	return strings.Map(func(_ch rune) rune {
+		if unicode.IsControl(_ch) || _ch == utf8.RuneError {
+			return -1
+		}
+		return _ch
+	}, _str)
+}
+
+// Sanitizer cleans strings for output to CSV or a DB in one pass, configured by chaining:
+//
+//	san := NewSanitizer().Deny(unicode.IsControl).Map(',', ";").Normalize()
+//	val := san.Clean(val)
+//
+// A rune is rejected if any Deny class matches it, or if Allow classes are set and none match it.
+// Rejected runes are replaced by the Replacement string, which defaults to "" (drop).
+type Sanitizer struct {
+	allow    []func(rune) bool
+	deny     []func(rune) bool
+	mapping  map[rune]string
+	repl     string
+	normWhSp bool
+}
+
+// NewSanitizer returns a Sanitizer that passes everything through
+func NewSanitizer() *Sanitizer {
+	return &Sanitizer{mapping: map[rune]string{}}
+}
+
+// Allow restricts output to runes in at least one of the classes, eg unicode.IsLetter, unicode.IsDigit
+func (us *Sanitizer) Allow(_classes ...func(rune) bool) *Sanitizer {
+	us.allow = append(us.allow, _classes...)
+	return us
+}
+
+// Deny rejects runes in any of the classes, eg unicode.IsControl
+func (us *Sanitizer) Deny(_classes ...func(rune) bool) *Sanitizer {
+	us.deny = append(us.deny, _classes...)
+	return us
+}
+
+// DenyRunes rejects each rune in the string
+func (us *Sanitizer) DenyRunes(_chars string) *Sanitizer {
+	return us.Deny(func(_ch rune) bool { return strings.ContainsRune(_chars, _ch) })
+}
+
+// Map replaces the rune by the string, mapped runes are not checked against Allow and Deny
+func (us *Sanitizer) Map(_from rune, _to string) *Sanitizer {
+	us.mapping[_from] = _to
+	return us
+}
+
+// Replacement sets the string that replaces rejected runes
+func (us *Sanitizer) Replacement(_repl string) *Sanitizer {
+	us.repl = _repl
+	return us
+}
+
+// Normalize applies NormalizeWhitespace after the runes are cleaned
+func (us *Sanitizer) Normalize() *Sanitizer {
+	us.normWhSp = true
+	return us
+}
+
+// rejects is true if the rune fails the Allow or Deny classes
+func (us *Sanitizer) rejects(_ch rune) bool {
+	for _, class := range us.deny {
+		if class(_ch) {
+			return true
+		}
+	}
+	if len(us.allow) == 0 {
+		return false
+	}
+	for _, class := range us.allow {
+		if class(_ch) {
+			return false
+		}
+	}
+	return true
+}
+
+// Clean returns the sanitized string, invalid UTF-8 is treated as utf8.RuneError
+func (us *Sanitizer) Clean(_str string) string {
+	var sb strings.Builder
+	sb.Grow(len(_str))
+	for _, ch := range _str {
+		if to, ok := us.mapping[ch]; ok {
+			sb.WriteString(to)
+			continue
+		}
+		if us.rejects(ch) {
+			sb.WriteString(us.repl)
+			continue
+		}
+		sb.WriteRune(ch)
+	}
+	if us.normWhSp {
+		return NormalizeWhitespace(sb.String())
+	}
+	return sb.String()
+}
@@ -0,0 +1,109 @@
+package genutil
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Ticker share-class conventions for NormalizeTicker, so BRK.B is written BRK.B, BRK/B, BRK-B or BRKB
+const (
+	TickerDot   = "dot"
+	TickerSlash = "slash"
+	TickerDash  = "dash"
+	TickerNone  = "none"
+)
+
+// tickerClassSep is the share-class separator for each convention
+var tickerClassSep = map[string]string{TickerDot: ".", TickerSlash: "/", TickerDash: "-", TickerNone: ""}
+
+// TickerExchangeSuffixes are the Reuters style exchange suffixes NormalizeTicker strips, longest first.
+// One letter share classes like .A are deliberately absent, add to it at startup for other feeds.
+var TickerExchangeSuffixes = []string{".US", ".OQ", ".PK", ".UN", ".UW", ".UQ", ".N", ".O", ".K"}
+
+// NormalizeTicker uppercases the ticker and strips the exchange: a Bloomberg " US Equity" or " UN" style suffix,
+// a "NYSE:" style prefix, or one of TickerExchangeSuffixes. A trailing share class of one or two letters after
+// '.', '/', '-' or ' ' is then written with the convention's separator (TickerDot, TickerSlash, TickerDash or
+// TickerNone); an empty or unknown convention leaves the separator as found.
+// So NormalizeTicker("brk/b us equity", TickerDot) is "BRK.B" and NormalizeTicker("IBM.N", "") is "IBM".
+func NormalizeTicker(_ticker, _convention string) string {
+	fields := strings.Fields(strings.ToUpper(_ticker))
+	if len(fields) == 0 {
+		return ""
+	}
+	if last := fields[len(fields)-1]; len(fields) > 1 && (last == "EQUITY" || last == "PFD") {
+		fields = fields[:len(fields)-1]
+	}
+	if len(fields) > 1 && len(fields[len(fields)-1]) == 2 { // bloomberg exchange code
+		fields = fields[:len(fields)-1]
+	}
+	ticker := strings.Join(fields, " ")
+	if _, after, found := strings.Cut(ticker, ":"); found && after != "" {
+		ticker = after
+	}
+	for _, suffix := range TickerExchangeSuffixes {
+		if strings.HasSuffix(ticker, suffix) && len(ticker) > len(suffix) {
+			ticker = ticker[:len(ticker)-len(suffix)]
+			break
+		}
+	}
+	sep, ok := tickerClassSep[strings.ToLower(_convention)]
+	if !ok {
+		return ticker
+	}
+	idx := strings.LastIndexAny(ticker, "./- ")
+	if idx <= 0 || len(ticker)-idx-1 < 1 || len(ticker)-idx-1 > 2 {
+		return ticker
+	}
+	for _, ch := range ticker[idx+1:] {
+		if ch < 'A' || ch > 'Z' {
+			return ticker
+		}
+	}
+	return ticker[:idx] + sep + ticker[idx+1:]
+}
+
+// SymbolMapper maps symbols through a table of renames (corporate actions, vendor to house symbols),
+// normalizing both sides with NormalizeTicker
+type SymbolMapper struct {
+	convention string
+	table      map[string]string
+}
+
+// NewSymbolMapper loads a comma separated file of from,to lines (blank and # comment lines skipped, via LoadLookup)
+// into a SymbolMapper whose symbols are normalized with the convention
+func NewSymbolMapper(_fname, _convention string) (*SymbolMapper, error) {
+	lookup, err := LoadLookup(_fname, 0, 1)
+	if err != nil {
+		return nil, fmt.Errorf("genutil.NewSymbolMapper: %v", err)
+	}
+	mapper := &SymbolMapper{convention: _convention, table: make(map[string]string, len(lookup))}
+	for from, to := range lookup {
+		mapper.Add(from, to)
+	}
+	return mapper, nil
+}
+
+// Add adds or replaces one mapping
+func (us *SymbolMapper) Add(_from, _to string) {
+	us.table[NormalizeTicker(_from, us.convention)] = NormalizeTicker(_to, us.convention)
+}
+
+// Lookup returns the normalized symbol's mapping, and whether the table had one
+func (us *SymbolMapper) Lookup(_symbol string) (string, bool) {
+	to, ok := us.table[NormalizeTicker(_symbol, us.convention)]
+	return to, ok
+}
+
+// Map returns the symbol's mapping, or the normalized symbol when the table has none
+func (us *SymbolMapper) Map(_symbol string) string {
+	symbol := NormalizeTicker(_symbol, us.convention)
+	if to, ok := us.table[symbol]; ok {
+		return to
+	}
+	return symbol
+}
+
+// Len returns the number of mappings
+func (us *SymbolMapper) Len() int {
+	return len(us.table)
+}
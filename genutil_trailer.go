@@ -0,0 +1,194 @@
+package genutil
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// trailerTimeLayout is the generation timestamp of a trailer, always UTC
+const trailerTimeLayout = "20060102150405"
+
+// TrailerSpec describes a trailer line closing a delimited file, as many exchange and vendor formats have:
+//
+//	TRAILER|1234|56789|1234567.89|20240105103000
+//
+// holding the prefix, the record count, then as enabled the byte count of the records, the sum of a numeric
+// column and the UTC generation time
+type TrailerSpec struct {
+	Prefix    string // first field, marking the line as the trailer, default "TRAILER"
+	Sep       string // separator named as in SepMap, default comma; RecordWriter uses its own
+	Header    bool   // the file has a header line, which is not a record; RecordWriter always writes one
+	ByteCount bool   // record the bytes of the record lines, newlines included
+	SumCol    string // record the sum of this column, named in the header or else a 0-based index
+	Decimals  int    // decimals the sum is written with
+	Timestamp bool   // record the generation time
+}
+
+func (us TrailerSpec) prefix() string {
+	return StrAorB(us.Prefix, "TRAILER")
+}
+
+// format builds the trailer line, without the newline
+func (us TrailerSpec) format(_sep string, _count, _bytes int64, _sum float64, _now time.Time) string {
+	fields := []string{us.prefix(), strconv.FormatInt(_count, 10)}
+	if us.ByteCount {
+		fields = append(fields, strconv.FormatInt(_bytes, 10))
+	}
+	if us.SumCol != "" {
+		fields = append(fields, string(AppendFloat(nil, _sum, us.Decimals)))
+	}
+	if us.Timestamp {
+		fields = append(fields, _now.UTC().Format(trailerTimeLayout))
+	}
+	return strings.Join(fields, _sep)
+}
+
+// SetTrailer makes Close write a trailer line per the spec after the records; its Sep and Header are ignored.
+// The SumCol must be a numeric column. Call it before the first Write.
+func (us *RecordWriter[T]) SetTrailer(_spec TrailerSpec) error {
+	if _spec.SumCol != "" {
+		found := false
+		for _, rf := range us.rfs {
+			if rf.col == _spec.SumCol {
+				var zero T
+				switch reflect.ValueOf(zero).FieldByIndex(rf.index).Kind() {
+				case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+					reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+					reflect.Float32, reflect.Float64:
+				default:
+					return fmt.Errorf("genutil.RecordWriter.SetTrailer: column(%s) is not numeric", _spec.SumCol)
+				}
+				us.sumIndex, found = rf.index, true
+			}
+		}
+		if !found {
+			return fmt.Errorf("genutil.RecordWriter.SetTrailer: no column(%s)", _spec.SumCol)
+		}
+	}
+	us.trailer = &_spec
+	return nil
+}
+
+// addToTrailer counts the record just written into the trailer totals
+func (us *RecordWriter[T]) addToTrailer(_rec T, _nbytes int) {
+	us.bytes += int64(_nbytes)
+	if us.sumIndex == nil {
+		return
+	}
+	switch fv := reflect.ValueOf(_rec).FieldByIndex(us.sumIndex); fv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		us.sum += float64(fv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		us.sum += float64(fv.Uint())
+	case reflect.Float32, reflect.Float64:
+		us.sum += fv.Float()
+	}
+}
+
+// ValidateTrailer checks that the last non-empty line of the file (or available compression variant) is a trailer
+// per the spec and agrees with the records before it: their count, byte count and column sum, the sum within
+// rounding to spec.Decimals. Empty lines are not records.
+func ValidateTrailer(_fname string, _spec TrailerSpec) error {
+	sep := StrAorB(SepMap(StrAorB(_spec.Sep, ","), true), _spec.Sep)
+	bio, err := OpenAnyReadCloser(_fname)
+	if err != nil {
+		return fmt.Errorf("genutil.ValidateTrailer: %v", err)
+	}
+	defer bio.Close()
+	sumCol, needHeader := -1, _spec.Header
+	if _spec.SumCol != "" && !_spec.Header {
+		if sumCol, err = strconv.Atoi(_spec.SumCol); err != nil || sumCol < 0 {
+			return fmt.Errorf("genutil.ValidateTrailer: SumCol(%s) needs a header or is a 0-based index", _spec.SumCol)
+		}
+	}
+	count, nbytes, sum, last, lineno := int64(0), int64(0), 0.0, "", 0
+	pending := "" // the latest line, a record unless it turns out to be the trailer
+	for {
+		line, rerr := bio.ReadString('\n')
+		if len(line) > 0 {
+			lineno++
+			text := strings.TrimRight(line, "\r\n")
+			switch {
+			case text == "":
+			case needHeader:
+				needHeader = false
+				if _spec.SumCol != "" {
+					idx, ok := HeaderIndex(strings.Split(text, sep))[_spec.SumCol]
+					if !ok {
+						return fmt.Errorf("genutil.ValidateTrailer: %s: no column(%s) in header", _fname, _spec.SumCol)
+					}
+					sumCol = idx
+				}
+			default:
+				if pending != "" {
+					count++
+					nbytes += int64(len(pending))
+					if sumCol >= 0 {
+						parts := strings.Split(strings.TrimRight(pending, "\r\n"), sep)
+						val, perr := 0.0, error(nil)
+						if sumCol < len(parts) {
+							val, perr = strconv.ParseFloat(strings.TrimSpace(parts[sumCol]), 64)
+						}
+						if sumCol >= len(parts) || perr != nil {
+							return fmt.Errorf("genutil.ValidateTrailer: %s: line(%d) column(%s) is not a number", _fname, lineno-1, _spec.SumCol)
+						}
+						sum += val
+					}
+				}
+				pending, last = line, text
+				if !strings.HasSuffix(pending, "\n") {
+					pending += "\n"
+				}
+			}
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return fmt.Errorf("genutil.ValidateTrailer: %s: %v", _fname, rerr)
+		}
+	}
+	fields := strings.Split(last, sep)
+	if fields[0] != _spec.prefix() {
+		return fmt.Errorf("genutil.ValidateTrailer: %s: last line does not start with %s", _fname, _spec.prefix())
+	}
+	want := 2
+	for _, enabled := range []bool{_spec.ByteCount, _spec.SumCol != "", _spec.Timestamp} {
+		if enabled {
+			want++
+		}
+	}
+	if len(fields) != want {
+		return fmt.Errorf("genutil.ValidateTrailer: %s: trailer has %d fields, want %d", _fname, len(fields), want)
+	}
+	pos := 1
+	next := func() string {
+		pos++
+		return strings.TrimSpace(fields[pos-1])
+	}
+	if got, err := strconv.ParseInt(next(), 10, 64); err != nil || got != count {
+		return fmt.Errorf("genutil.ValidateTrailer: %s: trailer count(%s) but %d records", _fname, fields[1], count)
+	}
+	if _spec.ByteCount {
+		if got, err := strconv.ParseInt(next(), 10, 64); err != nil || got != nbytes {
+			return fmt.Errorf("genutil.ValidateTrailer: %s: trailer bytes(%s) but %d in records", _fname, fields[pos-1], nbytes)
+		}
+	}
+	if _spec.SumCol != "" {
+		got, err := strconv.ParseFloat(next(), 64)
+		if err != nil || math.Abs(got-sum) > 0.5/math.Pow(10, float64(_spec.Decimals))+1e-9*math.Abs(sum) {
+			return fmt.Errorf("genutil.ValidateTrailer: %s: trailer sum(%s) but records sum to %s", _fname, fields[pos-1], string(AppendFloat(nil, sum, _spec.Decimals)))
+		}
+	}
+	if _spec.Timestamp {
+		if _, err := time.Parse(trailerTimeLayout, next()); err != nil {
+			return fmt.Errorf("genutil.ValidateTrailer: %s: trailer time(%s) is not %s", _fname, fields[pos-1], trailerTimeLayout)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package genutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Transform is one operation on one column, so a reformatting job can be a config file read with ReadConfigFile:
+//
+//	transforms:
+//	  - {col: Ticker, op: upper}
+//	  - {col: TradeDate, op: date, arg: "01/02/2006->20060102"}
+//	  - {col: Price, op: scale, arg: "0.01"}
+//	  - {col: Exch, op: map, map: {N: NYSE, Q: NASDAQ}}
+//	  - {col: Exch, op: default, arg: OTC}
+//
+// Ops are trim, upper, lower, date (Arg is "fromLayout->toLayout" in time.Format terms), scale (Arg is the factor),
+// map (values found in Map are replaced, others kept) and default (Arg replaces a blank value).
+// Blank values pass through date and scale untouched.
+type Transform struct {
+	Col string
+	Op  string
+	Arg string
+	Map map[string]string
+}
+
+// compiledTransform is a Transform bound to a column position with its argument parsed
+type compiledTransform struct {
+	Transform
+	pos      int
+	from, to string
+	factor   float64
+}
+
+// ApplyTransformFile copies a comma separated file with header, applying the transforms in order to each data line
+func ApplyTransformFile(_in, _out string, _spec []Transform) error {
+	return ApplyTransformFileSep(_in, _out, ",", _spec)
+}
+
+// ApplyTransformFileSep is ApplyTransformFile with a separator (named as in SepMap). It streams line by line;
+// an unknown op or column is an error before anything is written, and a value that fails to parse is an error
+// naming the line.
+func ApplyTransformFileSep(_in, _out, _sep string, _spec []Transform) error {
+	sep := StrAorB(SepMap(_sep, true), _sep)
+	var gzf GzFile
+	var compiled []compiledTransform
+	isOpen := false
+	defer func() {
+		if isOpen {
+			gzf.Close()
+		}
+	}()
+	lineno := 0
+	err := forEachLine(_in, func(_line string) error {
+		lineno++
+		parts := strings.Split(_line, sep)
+		if compiled == nil {
+			var err error
+			if compiled, err = compileTransforms(_spec, HeaderIndex(parts)); err != nil {
+				return err
+			}
+			gzf, isOpen = OpenGzFile(_out), true
+			_, err = gzf.WriteString(_line + "\n")
+			return err
+		}
+		for _, ct := range compiled {
+			for len(parts) <= ct.pos {
+				parts = append(parts, "")
+			}
+			val, err := ct.apply(parts[ct.pos])
+			if err != nil {
+				return fmt.Errorf("line(%d) col(%s) op(%s) : %v", lineno, ct.Col, ct.Op, err)
+			}
+			parts[ct.pos] = val
+		}
+		_, err := WriteJoinedLine(gzf, parts, sep)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("genutil.ApplyTransformFile: %s: %v", _in, err)
+	}
+	return nil
+}
+
+// compileTransforms checks the spec against the header
+func compileTransforms(_spec []Transform, _header map[string]int) ([]compiledTransform, error) {
+	compiled := make([]compiledTransform, 0, len(_spec))
+	for _, tr := range _spec {
+		ct := compiledTransform{Transform: tr}
+		ct.Op = strings.ToLower(strings.TrimSpace(tr.Op))
+		pos, ok := _header[strings.TrimSpace(tr.Col)]
+		if !ok {
+			return nil, fmt.Errorf("transform column(%s) not in header", tr.Col)
+		}
+		ct.pos = pos
+		switch ct.Op {
+		case "trim", "upper", "lower", "map", "default":
+		case "date":
+			from, to, found := strings.Cut(tr.Arg, "->")
+			if !found || from == "" || to == "" {
+				return nil, fmt.Errorf("transform col(%s) op(date) wants arg fromLayout->toLayout, got (%s)", tr.Col, tr.Arg)
+			}
+			ct.from, ct.to = from, to
+		case "scale":
+			factor, err := strconv.ParseFloat(strings.TrimSpace(tr.Arg), 64)
+			if err != nil {
+				return nil, fmt.Errorf("transform col(%s) op(scale) bad factor (%s)", tr.Col, tr.Arg)
+			}
+			ct.factor = factor
+		default:
+			return nil, fmt.Errorf("transform col(%s) unknown op(%s)", tr.Col, tr.Op)
+		}
+		compiled = append(compiled, ct)
+	}
+	return compiled, nil
+}
+
+// apply runs the transform on one value
+func (us compiledTransform) apply(_val string) (string, error) {
+	switch us.Op {
+	case "trim":
+		return strings.TrimSpace(_val), nil
+	case "upper":
+		return strings.ToUpper(_val), nil
+	case "lower":
+		return strings.ToLower(_val), nil
+	case "map":
+		if mapped, ok := us.Map[_val]; ok {
+			return mapped, nil
+		}
+		return _val, nil
+	case "default":
+		if strings.TrimSpace(_val) == "" {
+			return us.Arg, nil
+		}
+		return _val, nil
+	}
+	val := strings.TrimSpace(_val)
+	if val == "" {
+		return _val, nil
+	}
+	switch us.Op {
+	case "date":
+		tt, err := time.Parse(us.from, val)
+		if err != nil {
+			return "", fmt.Errorf("bad date (%s) for layout (%s)", val, us.from)
+		}
+		return tt.Format(us.to), nil
+	case "scale":
+		ff, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return "", fmt.Errorf("bad number (%s)", val)
+		}
+		return strconv.FormatFloat(roundClean(ff*us.factor), 'f', -1, 64), nil
+	}
+	return _val, nil
+}
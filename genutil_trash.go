@@ -0,0 +1,115 @@
+package genutil
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Removal modes for SetRemoveMode, which govern how WritableFilename and RemoveCompressionVariants discard existing files
+const (
+	RemoveDelete = 0 // remove permanently (default)
+	RemoveBackup = 1 // rename to <fname>.<timestamp>.bak in the same dir
+	RemoveTrash  = 2 // move to <trashdir>/<basename>.<timestamp>.bak
+)
+
+const trashStampLayout = "20060102_150405.000000000"
+
+var (
+	removeMode = RemoveDelete
+	trashDir   = ""
+)
+
+// SetRemoveMode selects how existing files are discarded, the trash dir is only used (and created) for RemoveTrash
+func SetRemoveMode(_mode int, _trashDir string) {
+	switch _mode {
+	case RemoveDelete, RemoveBackup:
+	case RemoveTrash:
+		if _trashDir == "" {
+			panic("genutil.SetRemoveMode: RemoveTrash needs a trash dir")
+		}
+		if err := os.MkdirAll(_trashDir, 0775); err != nil {
+			panic("genutil.SetRemoveMode: cannot create trash dir: " + _trashDir)
+		}
+	default:
+		panic(fmt.Sprintf("genutil.SetRemoveMode: unknown mode(%d)", _mode))
+	}
+	removeMode, trashDir = _mode, _trashDir
+}
+
+// PathDiscardOrPanic removes, backs up or trashes the file according to SetRemoveMode, and panics on failure
+func PathDiscardOrPanic(_fname string) bool {
+	stamp := time.Now().Format(trashStampLayout)
+	switch removeMode {
+	case RemoveBackup:
+		return pathMoveOrPanic(_fname, _fname+"."+stamp+".bak")
+	case RemoveTrash:
+		return pathMoveOrPanic(_fname, filepath.Join(trashDir, filepath.Base(_fname)+"."+stamp+".bak"))
+	}
+	return PathRemoveOrPanic(_fname)
+}
+
+// pathMoveOrPanic renames, falling back to copy and remove across filesystems
+func pathMoveOrPanic(_from, _to string) bool {
+	if err := auditedRename(_from, _to); err == nil {
+		return true
+	}
+	fi, err := os.Open(_from)
+	if err != nil {
+		panic(err)
+	}
+	defer fi.Close()
+	fo, err := os.Create(_to)
+	if err != nil {
+		panic(err)
+	}
+	if _, err = io.Copy(fo, fi); err != nil {
+		fo.Close()
+		panic(err)
+	}
+	if err = fo.Close(); err != nil {
+		panic(err)
+	}
+	auditFile("create", _to, -1)
+	return PathRemoveOrPanic(_from)
+}
+
+// PurgeTrash permanently removes the files in the trash dir that were trashed more than _olderThan ago
+func PurgeTrash(_olderThan time.Duration) (int, error) {
+	if trashDir == "" {
+		return 0, fmt.Errorf("genutil.PurgeTrash: no trash dir, see SetRemoveMode")
+	}
+	return PurgeBackups(trashDir, _olderThan)
+}
+
+// PurgeBackups permanently removes the .bak files in the dir made more than _olderThan ago by PathDiscardOrPanic
+func PurgeBackups(_dir string, _olderThan time.Duration) (int, error) {
+	entries, err := os.ReadDir(_dir)
+	if err != nil {
+		return 0, err
+	}
+	cutoff, count := time.Now().Add(-_olderThan), 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".bak") {
+			continue
+		}
+		stem := strings.TrimSuffix(name, ".bak")
+		if len(stem) < len(trashStampLayout)+1 {
+			continue
+		}
+		stamp := stem[len(stem)-len(trashStampLayout):]
+		tt, err := time.ParseInLocation(trashStampLayout, stamp, time.Local)
+		if err != nil || !tt.Before(cutoff) {
+			continue
+		}
+		if err = auditedRemove(filepath.Join(_dir, name)); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
@@ -0,0 +1,268 @@
+package genutil
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// xlsx parts, only the fields needed to pull cell values out
+type (
+	xlsxWorkbook struct {
+		Pr struct {
+			Date1904 bool `xml:"date1904,attr"`
+		} `xml:"workbookPr"`
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	xlsxRels struct {
+		Rels []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	xlsxRichText struct {
+		T string         `xml:"t"`
+		R []xlsxRichText `xml:"r"`
+	}
+	xlsxStyles struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellXfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	xlsxSheet struct {
+		Rows []struct {
+			R     int `xml:"r,attr"`
+			Cells []struct {
+				R  string       `xml:"r,attr"`
+				T  string       `xml:"t,attr"`
+				S  int          `xml:"s,attr"`
+				V  string       `xml:"v"`
+				Is xlsxRichText `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+)
+
+// String joins plain and rich text runs
+func (us xlsxRichText) String() string {
+	ss := us.T
+	for _, run := range us.R {
+		ss += run.T
+	}
+	return ss
+}
+
+// ReadXlsxSheet returns the cells of the named sheet (the first one if _sheet is "") as rows of strings,
+// with gaps filled by "". Cells formatted as dates come back as YYYYMMDD, or "YYYYMMDD HHMMSS" when they
+// carry a time of day; other numbers as Excel stored them, booleans as TRUE/FALSE, formulas as their cached value.
+func ReadXlsxSheet(_fname, _sheet string) ([][]string, error) {
+	zr, err := zip.OpenReader(_fname)
+	if err != nil {
+		return nil, fmt.Errorf("genutil.ReadXlsxSheet: %v", err)
+	}
+	defer zr.Close()
+	parts := map[string]*zip.File{}
+	for _, zf := range zr.File {
+		parts[zf.Name] = zf
+	}
+	load := func(_name string, _into interface{}, _optional bool) error {
+		zf, ok := parts[_name]
+		if !ok {
+			if _optional {
+				return nil
+			}
+			return fmt.Errorf("%s missing from %s", _name, _fname)
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		if err = xml.NewDecoder(rc).Decode(_into); err != nil && err != io.EOF {
+			return fmt.Errorf("%s: %v", _name, err)
+		}
+		return nil
+	}
+
+	var wb xlsxWorkbook
+	var rels xlsxRels
+	var styles xlsxStyles
+	var sst struct {
+		SI []xlsxRichText `xml:"si"`
+	}
+	for _, step := range []struct {
+		name     string
+		into     interface{}
+		optional bool
+	}{{"xl/workbook.xml", &wb, false}, {"xl/_rels/workbook.xml.rels", &rels, false}, {"xl/styles.xml", &styles, true}, {"xl/sharedStrings.xml", &sst, true}} {
+		if err = load(step.name, step.into, step.optional); err != nil {
+			return nil, fmt.Errorf("genutil.ReadXlsxSheet: %v", err)
+		}
+	}
+
+	rid := ""
+	for _, sh := range wb.Sheets {
+		if _sheet == "" || sh.Name == _sheet {
+			rid = sh.RID
+			break
+		}
+	}
+	if rid == "" {
+		return nil, fmt.Errorf("genutil.ReadXlsxSheet: no sheet (%s) in %s", _sheet, _fname)
+	}
+	target := ""
+	for _, rel := range rels.Rels {
+		if rel.ID == rid {
+			target = rel.Target
+		}
+	}
+	if strings.HasPrefix(target, "/") {
+		target = strings.TrimPrefix(target, "/")
+	} else {
+		target = path.Join("xl", target)
+	}
+	var sheet xlsxSheet
+	if err = load(target, &sheet, false); err != nil {
+		return nil, fmt.Errorf("genutil.ReadXlsxSheet: %v", err)
+	}
+
+	dateStyles := xlsxDateStyles(styles)
+	out := [][]string{}
+	for _, row := range sheet.Rows {
+		rownum := row.R
+		if rownum <= 0 {
+			rownum = len(out) + 1
+		}
+		for len(out) < rownum {
+			out = append(out, []string{})
+		}
+		cells := out[rownum-1]
+		for _, cell := range row.Cells {
+			col := len(cells)
+			if cell.R != "" {
+				if col, err = xlsxColumn(cell.R); err != nil {
+					return nil, fmt.Errorf("genutil.ReadXlsxSheet: %v", err)
+				}
+			}
+			for len(cells) <= col {
+				cells = append(cells, "")
+			}
+			val := cell.V
+			switch cell.T {
+			case "s":
+				idx, err := strconv.Atoi(cell.V)
+				if err != nil || idx < 0 || idx >= len(sst.SI) {
+					return nil, fmt.Errorf("genutil.ReadXlsxSheet: cell %s: bad shared string index %s", cell.R, cell.V)
+				}
+				val = sst.SI[idx].String()
+			case "inlineStr":
+				val = cell.Is.String()
+			case "b":
+				val = map[bool]string{true: "TRUE", false: "FALSE"}[cell.V == "1"]
+			case "", "n":
+				if cell.S < len(dateStyles) && dateStyles[cell.S] && cell.V != "" {
+					if serial, err := strconv.ParseFloat(cell.V, 64); err == nil {
+						val = xlsxSerialDate(serial, wb.Pr.Date1904)
+					}
+				}
+			}
+			cells[col] = val
+		}
+		out[rownum-1] = cells
+	}
+	return out, nil
+}
+
+// XlsxToCsv writes the sheet read by ReadXlsxSheet to the file as comma separated CsvJoin lines, compressed if the name ends in .gz
+func XlsxToCsv(_fname, _sheet, _outfname string) error {
+	rows, err := ReadXlsxSheet(_fname, _sheet)
+	if err != nil {
+		return err
+	}
+	gzf := OpenGzFile(_outfname)
+	defer gzf.Close()
+	for _, row := range rows {
+		if _, err = gzf.WriteString(CsvJoin(row, ",") + "\n"); err != nil {
+			return fmt.Errorf("genutil.XlsxToCsv: %s: %v", _outfname, err)
+		}
+	}
+	return nil
+}
+
+// xlsxColumn turns a cell reference like AB12 into the 0-based column 27
+func xlsxColumn(_ref string) (int, error) {
+	col, nn := 0, 0
+	for ; nn < len(_ref) && _ref[nn] >= 'A' && _ref[nn] <= 'Z'; nn++ {
+		col = col*26 + int(_ref[nn]-'A'+1)
+	}
+	if nn == 0 {
+		return 0, fmt.Errorf("bad cell reference %s", _ref)
+	}
+	return col - 1, nil
+}
+
+// xlsxDateStyles flags the cellXfs entries whose number format shows a date
+func xlsxDateStyles(_styles xlsxStyles) []bool {
+	custom := map[int]bool{}
+	for _, nf := range _styles.NumFmts {
+		custom[nf.ID] = xlsxIsDateFormat(nf.Code)
+	}
+	flags := make([]bool, len(_styles.CellXfs))
+	for ii, xf := range _styles.CellXfs {
+		id := xf.NumFmtID
+		isDate, ok := custom[id]
+		flags[ii] = (ok && isDate) || (!ok && ((id >= 14 && id <= 22) || (id >= 27 && id <= 36) || (id >= 45 && id <= 47) || (id >= 50 && id <= 58)))
+	}
+	return flags
+}
+
+// xlsxIsDateFormat looks for date letters in a format code outside quoted text, [colors] and \escapes
+func xlsxIsDateFormat(_code string) bool {
+	inQuote, inBracket := false, false
+	for ii := 0; ii < len(_code); ii++ {
+		ch := _code[ii]
+		switch {
+		case ch == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case ch == '[':
+			inBracket = true
+		case ch == ']':
+			inBracket = false
+		case inBracket:
+		case ch == '\\' || ch == '_' || ch == '*':
+			ii++
+		case strings.IndexByte("dDyY", ch) >= 0:
+			return true
+		}
+	}
+	return false
+}
+
+// xlsxSerialDate converts an Excel day number to YYYYMMDD, or "YYYYMMDD HHMMSS" if it has a time of day
+func xlsxSerialDate(_serial float64, _date1904 bool) string {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if _date1904 {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	days := math.Floor(_serial)
+	secs := math.Round((_serial - days) * 86400)
+	tt := epoch.AddDate(0, 0, int(days)).Add(time.Duration(secs) * time.Second)
+	if secs == 0 {
+		return tt.Format("20060102")
+	}
+	return tt.Format("20060102 150405")
+}
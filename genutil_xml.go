@@ -0,0 +1,112 @@
+package genutil
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// StreamXMLElements reads the (possibly compressed) file with OpenAnyReadCloser and calls _fn for each element named
+// _elementName, wherever it sits in the document, without holding more than that element in memory.
+// The element is flattened into a map: its attributes as "@attr", its own text as ".", and the text of
+// nested elements by their path below it, like "price" or "issuer/name" (attributes likewise "issuer/@id").
+// A repeated path gets "#2", "#3" etc. appended to the later keys. Namespaces are ignored.
+// A non-nil error from _fn stops the stream and is returned.
+func StreamXMLElements(_fname, _elementName string, _fn func(_decoded map[string]string) error) error {
+	bio, err := OpenAnyReadCloser(_fname)
+	if err != nil {
+		return fmt.Errorf("genutil.StreamXMLElements: %s: %v", _fname, err)
+	}
+	defer bio.Close()
+	if err = streamXMLElements(bio, _elementName, _fn); err != nil {
+		return fmt.Errorf("genutil.StreamXMLElements: %s: %v", _fname, err)
+	}
+	return nil
+}
+
+// streamXMLElements is StreamXMLElements on a reader
+func streamXMLElements(_rr io.Reader, _elementName string, _fn func(_decoded map[string]string) error) error {
+	dec := xml.NewDecoder(_rr)
+	dec.Strict = false
+	var (
+		rec  map[string]string
+		path []string          // below the matched element
+		open []*xmlOpenElement // from the matched element down
+	)
+	put := func(_key, _val string) {
+		key := _key
+		for nn := 2; ; nn++ {
+			if _, ok := rec[key]; !ok {
+				break
+			}
+			key = _key + "#" + strconv.Itoa(nn)
+		}
+		rec[key] = _val
+	}
+	join := func(_leaf string) string {
+		return strings.Join(append(append([]string{}, path...), _leaf), "/")
+	}
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			if rec != nil {
+				return fmt.Errorf("unexpected end of file inside <%s>", _elementName)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch tt := tok.(type) {
+		case xml.StartElement:
+			switch {
+			case rec == nil && tt.Name.Local == _elementName:
+				rec, path, open = map[string]string{}, []string{}, []*xmlOpenElement{{}}
+				for _, attr := range tt.Attr {
+					put("@"+attr.Name.Local, attr.Value)
+				}
+			case rec != nil:
+				path = append(path, tt.Name.Local)
+				open[len(open)-1].hasChild = true
+				open = append(open, &xmlOpenElement{})
+				for _, attr := range tt.Attr {
+					put(join("@"+attr.Name.Local), attr.Value)
+				}
+			}
+		case xml.CharData:
+			if rec != nil {
+				open[len(open)-1].text.Write(tt)
+			}
+		case xml.EndElement:
+			if rec == nil {
+				continue
+			}
+			elem := open[len(open)-1]
+			val := strings.TrimSpace(elem.text.String())
+			open = open[:len(open)-1]
+			if len(path) == 0 {
+				if val != "" {
+					put(".", val)
+				}
+				if err = _fn(rec); err != nil {
+					return err
+				}
+				rec = nil
+				continue
+			}
+			key := strings.Join(path, "/")
+			path = path[:len(path)-1]
+			if val != "" || !elem.hasChild { // a wrapper element adds no empty key
+				put(key, val)
+			}
+		}
+	}
+}
+
+// xmlOpenElement collects the text of an element until its end tag
+type xmlOpenElement struct {
+	text     strings.Builder
+	hasChild bool
+}
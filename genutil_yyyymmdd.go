@@ -0,0 +1,85 @@
+package genutil
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// yyyymmddClass ranks a date string for CompareYYYYMMDD: 0 short (a missing date), 1 eight chars, 2 longer
+func yyyymmddClass(_dt string) int {
+	switch {
+	case len(_dt) < 8:
+		return 0
+	case len(_dt) == 8:
+		return 1
+	}
+	return 2
+}
+
+// CompareYYYYMMDD returns -1, 0 or +1 as the first date is before, the same as or after the second, ordering as
+// StryyyymmddLTEQ does: a date shorter than 8 chars (a missing date) sorts before every 8 char date.
+// Unlike StryyyymmddLTEQ it never prints: strings too long or both too short, which StryyyymmddLTEQ reports,
+// are given a consistent order (short ones first, long ones last) so sorting stays well defined.
+// Use ValidateYYYYMMDD or ValidateYYYYMMDDs to find the bad ones.
+func CompareYYYYMMDD(_dt1, _dt2 string) int {
+	if c1, c2 := yyyymmddClass(_dt1), yyyymmddClass(_dt2); c1 != c2 {
+		if c1 < c2 {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(_dt1, _dt2)
+}
+
+// SortYYYYMMDD sorts the date strings in place in CompareYYYYMMDD order
+func SortYYYYMMDD(_dates []string) {
+	slices.SortFunc(_dates, CompareYYYYMMDD)
+}
+
+// yyyymmddMonthDays is the longest month length, February allowing for leap years
+var yyyymmddMonthDays = [13]int{0, 31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+// ValidateYYYYMMDD returns an error saying what is wrong with the date, nil for a real calendar date
+func ValidateYYYYMMDD(_dt string) error {
+	if len(_dt) != 8 {
+		return fmt.Errorf("date(%s) has length %d, want 8", _dt, len(_dt))
+	}
+	for ii := 0; ii < 8; ii++ {
+		if !IsDigit(_dt[ii]) {
+			return fmt.Errorf("date(%s) has non-digit (%c)", _dt, _dt[ii])
+		}
+	}
+	yyyy := int(_dt[0]-'0')*1000 + int(_dt[1]-'0')*100 + int(_dt[2]-'0')*10 + int(_dt[3]-'0')
+	mm := int(_dt[4]-'0')*10 + int(_dt[5]-'0')
+	dd := int(_dt[6]-'0')*10 + int(_dt[7]-'0')
+	if mm < 1 || mm > 12 {
+		return fmt.Errorf("date(%s) has month %d", _dt, mm)
+	}
+	leap := yyyy%4 == 0 && (yyyy%100 != 0 || yyyy%400 == 0)
+	if dd < 1 || dd > yyyymmddMonthDays[mm] || (mm == 2 && dd == 29 && !leap) {
+		return fmt.Errorf("date(%s) has day %d", _dt, dd)
+	}
+	return nil
+}
+
+// ValidateYYYYMMDDs checks every date with ValidateYYYYMMDD, returning an error with the number of bad dates and
+// the first one, nil if all are good. Blank dates are skipped when _allowBlank is set.
+func ValidateYYYYMMDDs(_dates []string, _allowBlank bool) error {
+	nbad, first := 0, error(nil)
+	for ii, dt := range _dates {
+		if dt == "" && _allowBlank {
+			continue
+		}
+		if err := ValidateYYYYMMDD(dt); err != nil {
+			if nbad == 0 {
+				first = fmt.Errorf("index(%d): %v", ii, err)
+			}
+			nbad++
+		}
+	}
+	if nbad > 0 {
+		return fmt.Errorf("genutil.ValidateYYYYMMDDs: %d of %d bad, first at %v", nbad, len(_dates), first)
+	}
+	return nil
+}